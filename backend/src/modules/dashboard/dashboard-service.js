@@ -1,3 +1,4 @@
+const { ApiError } = require("../../utils");
 const { getUserDashboardData } = require("./dashboard-repository");
 
 const fetchDashboardData = async (id) => {
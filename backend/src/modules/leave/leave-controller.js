@@ -84,8 +84,14 @@ const handleUpdateLeaveRequest = asyncHandler(async (req, res) => {
 });
 
 const handleGetUserLeaveHistory = asyncHandler(async (req, res) => {
-    const { id } = req.user;
-    const leaveHistory = await getUserLeaveHistory(id);
+    const { id, roleId } = req.user;
+    const { userId, departmentId, from, to } = req.query;
+    // Only admins may look at the leave of other users or whole departments.
+    // Without either filter everybody gets their own leave, as before.
+    const filter = roleId === 1 && (userId || departmentId)
+        ? { userId, departmentId, from, to }
+        : { userId: id, from, to };
+    const leaveHistory = await getUserLeaveHistory(filter);
     res.json({ leaveHistory });
 });
 
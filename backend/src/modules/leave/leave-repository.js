@@ -143,8 +143,9 @@ const updateLeaveRequestById = async (payload) => {
     return rowCount;
 }
 
-const getLeaveRequestHistoryByUser = async (userId) => {
-    const query = `
+const getLeaveRequestHistory = async (payload) => {
+    const { userId, departmentId, from, to } = payload;
+    let query = `
         SELECT
             t1.id,
             t2.name as policy,
@@ -159,16 +160,39 @@ const getLeaveRequestHistoryByUser = async (userId) => {
             t1.approved_dt AS "approved",
             t4.name AS approver,
             t5.name AS user,
+            t1.user_id AS "userId",
+            t6.department_id AS "departmentId",
+            t7.name AS department,
             EXTRACT(DAY FROM age(t1.to_dt +  INTERVAL '1 day', t1.from_dt)) AS days
         FROM user_leaves t1
         JOIN leave_policies t2 ON t1.leave_policy_id = t2.id
         JOIN leave_status t3 ON t1.status = t3.id
         LEFT JOIN users t4 ON t1.approver_id = t4.id
         JOIN users t5 ON t1.user_id = t5.id
-        WHERE t1.user_id = $1
-        ORDER BY submitted_dt DESC
+        LEFT JOIN user_profiles t6 ON t1.user_id = t6.user_id
+        LEFT JOIN departments t7 ON t6.department_id = t7.id
+        WHERE 1=1
     `;
-    const queryParams = [userId];
+    let queryParams = [];
+    if (userId) {
+        query += ` AND t1.user_id = $${queryParams.length + 1}`;
+        queryParams.push(userId);
+    }
+    if (departmentId) {
+        query += ` AND t6.department_id = $${queryParams.length + 1}`;
+        queryParams.push(departmentId);
+    }
+    if (from) {
+        query += ` AND t1.to_dt >= $${queryParams.length + 1}`;
+        queryParams.push(from);
+    }
+    if (to) {
+        query += ` AND t1.from_dt <= $${queryParams.length + 1}`;
+        queryParams.push(to);
+    }
+
+    query += ` ORDER BY submitted_dt DESC`;
+
     const { rows } = await processDBRequest({ query, queryParams });
     return rows;
 }
@@ -244,7 +268,7 @@ module.exports = {
     getPolicyEligibleUsers,
     createNewLeaveRequest,
     updateLeaveRequestById,
-    getLeaveRequestHistoryByUser,
+    getLeaveRequestHistory,
     deleteLeaveRequestByRequestId,
     getPendingLeaveRequests,
     approveOrCancelPendingLeaveRequest,
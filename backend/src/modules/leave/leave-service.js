@@ -1,5 +1,5 @@
 const { ApiError } = require("../../utils");
-const { createNewLeavePolicy, updateLeavePolicyById, getLeavePolicies, getUsersByPolicyId, updatePolicyUsersById, enableDisableLeavePolicy, deleteUserFromPolicyById, getPolicyEligibleUsers, createNewLeaveRequest, updateLeaveRequestById, getLeaveRequestHistoryByUser, deleteLeaveRequestByRequestId, getPendingLeaveRequests, approveOrCancelPendingLeaveRequest, findReviewerIdByRequestId, getMyLeavePolicy, findPolicyStatusById } = require("./leave-repository");
+const { createNewLeavePolicy, updateLeavePolicyById, getLeavePolicies, getUsersByPolicyId, updatePolicyUsersById, enableDisableLeavePolicy, deleteUserFromPolicyById, getPolicyEligibleUsers, createNewLeaveRequest, updateLeaveRequestById, getLeaveRequestHistory, deleteLeaveRequestByRequestId, getPendingLeaveRequests, approveOrCancelPendingLeaveRequest, findReviewerIdByRequestId, getMyLeavePolicy, findPolicyStatusById } = require("./leave-repository");
 
 const checkIfPolicyIsActive = async (id) => {
     const policy = await findPolicyStatusById(id);
@@ -119,8 +119,8 @@ const updateLeaveRequest = async (payload) => {
     return { message: "Leave request updated successfully" };
 }
 
-const getUserLeaveHistory = async (userId) => {
-    const leaves = await getLeaveRequestHistoryByUser(userId);
+const getUserLeaveHistory = async (payload) => {
+    const leaves = await getLeaveRequestHistory(payload);
     if (!Array.isArray(leaves) || leaves.length <= 0) {
         throw new ApiError(404, "Leaves not found");
     }
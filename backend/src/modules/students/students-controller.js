@@ -3,7 +3,7 @@ const { getAllStudents, addNewStudent, getStudentDetail, setStudentStatus, updat
 
 const handleGetAllStudents = asyncHandler(async (req, res) => {
     //write your code
-    const students = await getAllStudents(req.params);
+    const students = await getAllStudents(req.query);
     res.json({ students });
 });
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,9 +14,24 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 
 	"goservice/configs"
+	"goservice/internal/access"
 	"goservice/internal/auth"
+	"goservice/internal/certificate"
 	"goservice/internal/client"
+	"goservice/internal/dashboard"
+	"goservice/internal/idcard"
+	"goservice/internal/issuance"
+	"goservice/internal/jobs"
+	"goservice/internal/leave"
+	"goservice/internal/mail"
+	"goservice/internal/notice"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"goservice/internal/roster"
+	"goservice/internal/schedule"
+	"goservice/internal/staff"
 	"goservice/internal/student"
+	"goservice/internal/webhook"
 	"log"
 )
 
@@ -25,10 +41,234 @@ func main() {
 
 	backend := client.NewBackendClient(conf.NodeServer.BaseURL)
 
-	studentsrv := student.NewService(backend)
+	fontFiles := make([]report.FontFile, 0, len(conf.Reports.Fonts))
+	for _, f := range conf.Reports.Fonts {
+		fontFiles = append(fontFiles, report.FontFile(f))
+	}
+	fonts, err := report.LoadFonts(fontFiles)
+	if err != nil {
+		log.Fatalf("Error loading report fonts: %v", err)
+	}
+
+	templates, err := report.LoadTemplates(conf.Reports.TemplateDir, fonts)
+	if err != nil {
+		log.Fatalf("Error loading report templates: %v", err)
+	}
+
+	brand, err := report.LoadBrand(report.Branding(conf.Branding))
+	if err != nil {
+		log.Fatalf("Error loading branding: %v", err)
+	}
+	branding := report.NewBrandStore(brand)
+	configs.Watch(func(c *configs.Config) {
+		brand, err := report.LoadBrand(report.Branding(c.Branding))
+		if err != nil {
+			log.Printf("Keeping previous branding: %v", err)
+			return
+		}
+		branding.Update(brand)
+		log.Println("branding reloaded")
+	})
+
+	var signer *pdfsign.Signer
+	if conf.Signing.CertFile != "" {
+		signer, err = pdfsign.NewSigner(conf.Signing.CertFile, conf.Signing.KeyFile, conf.Signing.Reason, conf.Signing.Location)
+		if err != nil {
+			log.Fatalf("Error loading signing certificate: %v", err)
+		}
+	}
+
+	policies := make(map[string]report.EncryptionPolicy, len(conf.Encryption.Reports))
+	for kind, policy := range conf.Encryption.Reports {
+		policies[kind] = report.EncryptionPolicy(policy)
+	}
+	protection, err := report.NewProtection(report.EncryptionPolicy(conf.Encryption.Default), policies)
+	if err != nil {
+		log.Fatalf("Error loading encryption policy: %v", err)
+	}
+
+	var (
+		issued *issuance.Store
+		issuer *issuance.Issuer
+	)
+	if conf.Issuing.Database != "" {
+		issued, err = issuance.OpenStore(conf.Issuing.Database)
+		if err != nil {
+			log.Fatalf("Error opening report registry: %v", err)
+		}
+		defer issued.Close()
+		issuer = issuance.NewIssuer(issued, conf.Issuing.PublicURL, conf.Issuing.Issuer)
+	}
+
+	var (
+		mailer  mail.Mailer
+		outbox  *mail.Outbox
+		mailLog *mail.Store
+	)
+	switch conf.Mail.Driver {
+	case "smtp":
+		mailer, err = mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     conf.Mail.Host,
+			Port:     conf.Mail.Port,
+			Username: conf.Mail.Username,
+			Password: conf.Mail.Password,
+			TLS:      conf.Mail.TLS,
+		})
+	case "file":
+		mailer, err = mail.NewFileMailer(conf.Mail.DropDir)
+	case "":
+	default:
+		err = fmt.Errorf("unknown mail driver %q", conf.Mail.Driver)
+	}
+	if err != nil {
+		log.Fatalf("Error configuring mail: %v", err)
+	}
+	if mailer != nil {
+		if conf.Mail.Database != "" {
+			mailLog, err = mail.OpenStore(conf.Mail.Database)
+			if err != nil {
+				log.Fatalf("Error opening mail delivery log: %v", err)
+			}
+		}
+		outbox, err = mail.NewOutbox(mailer, mail.Options{
+			From:        conf.Mail.From,
+			Subject:     conf.Mail.Subject,
+			Body:        conf.Mail.Body,
+			MaxAttempts: conf.Mail.MaxAttempts,
+			Backoff:     conf.Mail.Backoff,
+			Store:       mailLog,
+		})
+		if err != nil {
+			log.Fatalf("Error configuring mail: %v", err)
+		}
+	}
+
+	reportCache := report.NewCache(conf.Reports.Cache.TTL, conf.Reports.Cache.MaxBytes)
+	var hookStore *webhook.Store
+	if conf.Webhooks.Database != "" {
+		hookStore, err = webhook.OpenStore(conf.Webhooks.Database)
+		if err != nil {
+			log.Fatalf("Error opening webhook store: %v", err)
+		}
+	}
+	dispatcher, err := webhook.NewDispatcher(webhook.Options{
+		Store:       hookStore,
+		MaxAttempts: conf.Webhooks.MaxAttempts,
+		Backoff:     conf.Webhooks.Backoff,
+		Timeout:     conf.Webhooks.Timeout,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring webhooks: %v", err)
+	}
+
+	studentsrv := student.NewService(backend, templates, branding, signer, protection, issuer, reportCache, outbox)
+	studentsrv = webhook.Observe(studentsrv, dispatcher)
 	studentHdlr := student.NewHandler(studentsrv)
+	staffHdlr := staff.NewHandler(staff.NewService(backend, templates, branding, signer, protection))
+	leaveHdlr := leave.NewHandler(leave.NewService(backend, fonts, branding, signer))
+	noticeHdlr := notice.NewHandler(notice.NewService(backend, fonts, branding, signer))
+	rosterHdlr := roster.NewHandler(roster.NewService(backend, fonts, branding, signer))
+	idcardHdlr := idcard.NewHandler(idcard.NewService(backend, fonts, branding, signer))
+	dashboardHdlr := dashboard.NewHandler(dashboard.NewService(backend, fonts, branding, signer))
 
+	certTemplates, err := certificate.LoadTemplates(conf.Certificates.TemplateDir)
+	if err != nil {
+		log.Fatalf("Error loading certificate templates: %v", err)
+	}
+	var certStore *certificate.Store
+	if conf.Certificates.Database != "" {
+		certStore, err = certificate.OpenStore(conf.Certificates.Database)
+		if err != nil {
+			log.Fatalf("Error opening certificate register: %v", err)
+		}
+		defer certStore.Close()
+	}
+	certHdlr := certificate.NewHandler(certificate.NewService(backend, certStore, certTemplates, certificate.Options{
+		Fonts:          fonts,
+		Branding:       branding,
+		Signer:         signer,
+		Issuer:         issuer,
+		YearStartMonth: time.Month(conf.Certificates.YearStartMonth),
+		Signatory:      conf.Certificates.Signatory,
+	}))
+
+	var accessStore *access.Store
+	if conf.Access.Database != "" {
+		accessStore, err = access.OpenStore(conf.Access.Database)
+		if err != nil {
+			log.Fatalf("Error opening access snapshots: %v", err)
+		}
+		defer accessStore.Close()
+	}
+	accessHdlr := access.NewHandler(access.NewService(backend, accessStore, fonts, branding, signer))
+
+	var jobStore *jobs.Store
+	if conf.Jobs.Database != "" {
+		jobStore, err = jobs.OpenStore(conf.Jobs.Database)
+		if err != nil {
+			log.Fatalf("Error opening report job store: %v", err)
+		}
+	}
+	jobManager, err := jobs.NewManager(studentsrv, jobs.Options{
+		Dir:          conf.Jobs.ResultDir,
+		Workers:      conf.Jobs.Workers,
+		QueueSize:    conf.Jobs.QueueSize,
+		Store:        jobStore,
+		Retention:    conf.Jobs.Retention,
+		CookieSecret: conf.Jobs.CookieSecret,
+		Notify: func(j jobs.Job) {
+			dispatcher.Publish(webhook.EventJobCompleted, j)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error starting report jobs: %v", err)
+	}
+	jobManager.Start()
+	jobsHandler := jobs.NewHandler(jobManager)
+
+	location := time.Local
+	if conf.Scheduler.Timezone != "" {
+		location, err = time.LoadLocation(conf.Scheduler.Timezone)
+		if err != nil {
+			log.Fatalf("Error loading scheduler time zone: %v", err)
+		}
+	}
+	var scheduleStore *schedule.Store
+	if conf.Scheduler.Database != "" {
+		scheduleStore, err = schedule.OpenStore(conf.Scheduler.Database)
+		if err != nil {
+			log.Fatalf("Error opening schedule run store: %v", err)
+		}
+	}
+	schedules := make([]schedule.Definition, 0, len(conf.Scheduler.Schedules))
+	for _, sc := range conf.Scheduler.Schedules {
+		schedules = append(schedules, schedule.Definition{
+			Name:     sc.Name,
+			Cron:     sc.Cron,
+			Class:    sc.Class,
+			Section:  sc.Section,
+			Template: sc.Template,
+			Format:   report.Format(sc.Format),
+		})
+	}
+	scheduler, err := schedule.New(studentsrv, schedules, schedule.Options{
+		ArchiveDir: conf.Scheduler.ArchiveDir,
+		Store:      scheduleStore,
+		Username:   conf.Scheduler.Username,
+		Password:   conf.Scheduler.Password,
+		Location:   location,
+	})
+	if err != nil {
+		log.Fatalf("Error loading report schedules: %v", err)
+	}
+	scheduler.Start()
+	scheduleHandler := schedule.NewHandler(scheduler)
+
+	mailHandler := mail.NewHandler(outbox)
+	webhookHandler := webhook.NewHandler(dispatcher)
 	authHandler := auth.NewHandler(backend)
+	verifyHandler := pdfsign.NewHandler(signer)
+	issuedHandler := issuance.NewHandler(issued, reportCache)
 
 	r := chi.NewRouter()
 
@@ -39,6 +279,31 @@ func main() {
 
 	r.Mount("/api/v1/auth", authHandler.Routes())
 	r.Mount("/api/v1/students", studentHdlr.Routes())
+	r.Mount("/api/v1/staffs", staffHdlr.Routes())
+	r.Mount("/api/v1/report-jobs", jobsHandler.Routes())
+	r.Mount("/api/v1/certificates", certHdlr.Routes())
+	r.Route("/api/v1/reports", func(r chi.Router) {
+		r.Mount("/classes", studentHdlr.ClassRoutes())
+		r.Mount("/leave", leaveHdlr.Routes())
+		r.Mount("/notices", noticeHdlr.Routes())
+		r.Mount("/roster", rosterHdlr.Routes())
+		r.Mount("/idcards", idcardHdlr.Routes())
+		r.Mount("/dashboard", dashboardHdlr.Routes())
+		r.Route("/verify", func(r chi.Router) {
+			r.Post("/", verifyHandler.Verify)
+			r.Get("/{serial}", issuedHandler.Verify)
+		})
+	})
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(auth.RequireAdminToken(conf.Admin.Token))
+		r.Mount("/reports", issuedHandler.AdminRoutes())
+		r.Mount("/schedules", scheduleHandler.Routes())
+		r.Mount("/deliveries", mailHandler.Routes())
+		r.Mount("/webhooks", webhookHandler.Routes())
+		r.Mount("/certificates", certHdlr.AdminRoutes())
+		r.Mount("/access", accessHdlr.Routes())
+		r.Get("/metrics", expvar.Handler().ServeHTTP)
+	})
 
 	addr := fmt.Sprintf("%s:%d", conf.AppServer.Host, conf.AppServer.Port)
 
@@ -72,12 +337,35 @@ func main() {
 		}
 	}
 
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Println("graceful shutdown failed")
-	} else {
+	// The job manager goes first: it closes the event streams, which would
+	// otherwise hold up the server for its whole timeout. Every subsystem
+	// gets its own deadline, and a store is only closed once its workers
+	// have stopped writing to it.
+	if stopWithin("report jobs", 10*time.Second, jobManager.Shutdown) && jobStore != nil {
+		jobStore.Close()
+	}
+	if stopWithin("http server", 20*time.Second, srv.Shutdown) {
 		log.Println("server stopped gracefully")
 	}
+	if stopWithin("scheduled reports", 10*time.Second, scheduler.Shutdown) && scheduleStore != nil {
+		scheduleStore.Close()
+	}
+	if outbox != nil && stopWithin("mail outbox", 10*time.Second, outbox.Shutdown) && mailLog != nil {
+		mailLog.Close()
+	}
+	if stopWithin("webhooks", 10*time.Second, dispatcher.Shutdown) && hookStore != nil {
+		hookStore.Close()
+	}
+}
+
+// stopWithin runs a subsystem's shutdown with its own timeout and reports
+// whether it finished in time.
+func stopWithin(name string, timeout time.Duration, shutdown func(context.Context) error) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		log.Printf("%s did not stop: %v", name, err)
+		return false
+	}
+	return true
 }
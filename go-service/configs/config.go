@@ -2,7 +2,9 @@ package configs
 
 import (
 	"log"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -15,9 +17,140 @@ type Backend struct {
 	BaseURL string `mapstructure:"baseurl"`
 }
 
+type Font struct {
+	Family     string `mapstructure:"family"`
+	Regular    string `mapstructure:"regular"`
+	Bold       string `mapstructure:"bold"`
+	Italic     string `mapstructure:"italic"`
+	BoldItalic string `mapstructure:"bolditalic"`
+}
+
+type ReportCache struct {
+	TTL      time.Duration `mapstructure:"ttl"`
+	MaxBytes int64         `mapstructure:"maxbytes"`
+}
+
+type Reports struct {
+	TemplateDir string      `mapstructure:"templatedir"`
+	Fonts       []Font      `mapstructure:"fonts"`
+	Cache       ReportCache `mapstructure:"cache"`
+}
+
+type Branding struct {
+	SchoolName            string `mapstructure:"schoolname"`
+	Address               string `mapstructure:"address"`
+	LogoPath              string `mapstructure:"logopath"`
+	AccentColor           string `mapstructure:"accentcolor"`
+	FooterText            string `mapstructure:"footertext"`
+	ConfidentialityNotice string `mapstructure:"confidentialitynotice"`
+}
+
+type Signing struct {
+	CertFile string `mapstructure:"certfile"`
+	KeyFile  string `mapstructure:"keyfile"`
+	Reason   string `mapstructure:"reason"`
+	Location string `mapstructure:"location"`
+}
+
+type EncryptionPolicy struct {
+	Enabled              bool     `mapstructure:"enabled"`
+	UserPassword         string   `mapstructure:"userpassword"`
+	OwnerPassword        string   `mapstructure:"ownerpassword"`
+	Permissions          []string `mapstructure:"permissions"`
+	AllowRequestPassword bool     `mapstructure:"allowrequestpassword"`
+}
+
+type Encryption struct {
+	Default EncryptionPolicy            `mapstructure:"default"`
+	Reports map[string]EncryptionPolicy `mapstructure:"reports"`
+}
+
+type Issuing struct {
+	PublicURL string `mapstructure:"publicurl"`
+	Database  string `mapstructure:"database"`
+	Issuer    string `mapstructure:"issuer"`
+}
+
+type Admin struct {
+	Token string `mapstructure:"token"`
+}
+
+type Jobs struct {
+	Workers      int           `mapstructure:"workers"`
+	QueueSize    int           `mapstructure:"queuesize"`
+	ResultDir    string        `mapstructure:"resultdir"`
+	Database     string        `mapstructure:"database"`
+	Retention    time.Duration `mapstructure:"retention"`
+	CookieSecret string        `mapstructure:"cookiesecret"`
+}
+
+type Schedule struct {
+	Name     string `mapstructure:"name"`
+	Cron     string `mapstructure:"cron"`
+	Class    string `mapstructure:"class"`
+	Section  string `mapstructure:"section"`
+	Template string `mapstructure:"template"`
+	Format   string `mapstructure:"format"`
+}
+
+type Scheduler struct {
+	Timezone   string     `mapstructure:"timezone"`
+	ArchiveDir string     `mapstructure:"archivedir"`
+	Database   string     `mapstructure:"database"`
+	Username   string     `mapstructure:"username"`
+	Password   string     `mapstructure:"password"`
+	Schedules  []Schedule `mapstructure:"schedules"`
+}
+
+type Mail struct {
+	Driver      string        `mapstructure:"driver"`
+	From        string        `mapstructure:"from"`
+	Host        string        `mapstructure:"host"`
+	Port        int           `mapstructure:"port"`
+	Username    string        `mapstructure:"username"`
+	Password    string        `mapstructure:"password"`
+	TLS         string        `mapstructure:"tls"`
+	DropDir     string        `mapstructure:"dropdir"`
+	Database    string        `mapstructure:"database"`
+	Subject     string        `mapstructure:"subject"`
+	Body        string        `mapstructure:"body"`
+	MaxAttempts int           `mapstructure:"maxattempts"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+}
+
+type Webhooks struct {
+	Database    string        `mapstructure:"database"`
+	MaxAttempts int           `mapstructure:"maxattempts"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+type Certificates struct {
+	Database       string `mapstructure:"database"`
+	TemplateDir    string `mapstructure:"templatedir"`
+	YearStartMonth int    `mapstructure:"yearstartmonth"`
+	Signatory      string `mapstructure:"signatory"`
+}
+
+type Access struct {
+	Database string `mapstructure:"database"`
+}
+
 type Config struct {
-	AppServer  Server  `mapstructure:"server"`
-	NodeServer Backend `mapstructure:"backend"`
+	AppServer    Server       `mapstructure:"server"`
+	NodeServer   Backend      `mapstructure:"backend"`
+	Reports      Reports      `mapstructure:"reports"`
+	Branding     Branding     `mapstructure:"branding"`
+	Signing      Signing      `mapstructure:"signing"`
+	Encryption   Encryption   `mapstructure:"encryption"`
+	Issuing      Issuing      `mapstructure:"issuing"`
+	Admin        Admin        `mapstructure:"admin"`
+	Jobs         Jobs         `mapstructure:"jobs"`
+	Scheduler    Scheduler    `mapstructure:"scheduler"`
+	Mail         Mail         `mapstructure:"mail"`
+	Webhooks     Webhooks     `mapstructure:"webhooks"`
+	Certificates Certificates `mapstructure:"certificates"`
+	Access       Access       `mapstructure:"access"`
 }
 
 func Load() *Config {
@@ -36,3 +169,17 @@ func Load() *Config {
 
 	return &cfg
 }
+
+// Watch calls onChange with the re-read configuration whenever the config
+// file changes. Invalid edits are logged and skipped.
+func Watch(onChange func(*Config)) {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		var cfg Config
+		if err := viper.Unmarshal(&cfg); err != nil {
+			log.Printf("Error reloading config: %v", err)
+			return
+		}
+		onChange(&cfg)
+	})
+	viper.WatchConfig()
+}
@@ -3,12 +3,28 @@ module goservice
 go 1.24.2
 
 require (
+	github.com/boombuler/barcode v1.0.2
 	github.com/go-chi/chi/v5 v5.2.2
 	github.com/spf13/viper v1.20.1
+	github.com/xuri/excelize/v2 v2.9.0
+	go.etcd.io/bbolt v1.3.11
+	go.mozilla.org/pkcs7 v0.10.0
+	golang.org/x/image v0.18.0
+	golang.org/x/text v0.21.0
 )
 
 require (
-	github.com/fsnotify/fsnotify v1.8.0 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/richardlehane/mscfb v1.0.4 // indirect
+	github.com/richardlehane/msoleps v1.0.4 // indirect
+	github.com/xuri/efp v0.0.0-20240408161823-9ad904a10d6d // indirect
+	github.com/xuri/nfp v0.0.0-20240318013403-ab9948c2c4a7 // indirect
+	golang.org/x/crypto v0.32.0 // indirect
+	golang.org/x/net v0.33.0 // indirect
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
 	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
@@ -21,6 +37,5 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
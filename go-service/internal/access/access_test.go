@@ -0,0 +1,256 @@
+package access
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBackend serves roles with the permission and user ids in grants and
+// users. Permission n is named "Permission n" and user n "User n".
+type fakeBackend struct {
+	client.IBackend
+	roles  []models.Role
+	grants map[int][]int
+	users  map[int][]int
+	err    error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		roles: []models.Role{
+			{ID: 1, Name: "Admin", Active: true},
+			{ID: 2, Name: "Teacher", Active: true},
+			{ID: 3, Name: "Student", Active: false},
+		},
+		grants: map[int][]int{1: {1, 2, 3}, 2: {2}},
+		users:  map[int][]int{1: {1}, 2: {2, 3}},
+	}
+}
+
+func (f *fakeBackend) ListRoles(ctx context.Context, cookies []*http.Cookie) ([]models.Role, error) {
+	return f.roles, f.err
+}
+
+func (f *fakeBackend) ListRolePermissions(ctx context.Context, id int, cookies []*http.Cookie) ([]models.Permission, error) {
+	perms := []models.Permission{}
+	for _, p := range f.grants[id] {
+		perms = append(perms, models.Permission{ID: p, Name: fmt.Sprintf("Permission %d", p)})
+	}
+	return perms, nil
+}
+
+func (f *fakeBackend) ListRoleUsers(ctx context.Context, id int, cookies []*http.Cookie) ([]models.RoleUser, error) {
+	users := []models.RoleUser{}
+	for _, u := range f.users[id] {
+		users = append(users, models.RoleUser{ID: u, Name: fmt.Sprintf("User %d", u)})
+	}
+	return users, nil
+}
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(filepath.Join(t.TempDir(), "access.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestMatrix(t *testing.T) {
+	s := NewService(newFakeBackend(), nil, nil, nil, nil).(*service)
+	m, err := s.matrix(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Roles) != 3 || len(m.Permissions) != 3 || m.Users() != 3 {
+		t.Fatalf("unexpected matrix %+v", m)
+	}
+	if !m.Grants(2, 2) || m.Grants(2, 1) || m.Grants(3, 2) || m.Grants(9, 1) {
+		t.Error("unexpected grants")
+	}
+	if !m.HasUser(2, 3) || m.HasUser(1, 3) {
+		t.Error("unexpected users")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	b := newFakeBackend()
+	s := NewService(b, nil, nil, nil, nil).(*service)
+	prev, _ := s.matrix(context.Background(), nil)
+
+	// Teachers gain permission 3 and lose 2, user 3 becomes an admin and a
+	// new role appears with one permission.
+	b.grants[2] = []int{3}
+	b.users = map[int][]int{1: {1, 3}, 2: {2}}
+	b.roles = append(b.roles, models.Role{ID: 4, Name: "Auditor", Active: true})
+	b.grants[4] = []int{1}
+	cur, _ := s.matrix(context.Background(), nil)
+
+	var got []string
+	for _, c := range Compare(prev, cur) {
+		got = append(got, fmt.Sprintf("%s %s %s%s", c.Kind, c.Role, c.Permission, c.User))
+	}
+	want := []string{
+		"granted Teacher Permission 3",
+		"granted Auditor Permission 1",
+		"user added Admin User 3",
+		"revoked Teacher Permission 2",
+		"user removed Teacher User 3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected changes:\n%s", strings.Join(got, "\n"))
+	}
+	if changes := Compare(cur, cur); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := openStore(t)
+	if _, err := store.Latest(); err != ErrSnapshotNotFound {
+		t.Fatalf("expected ErrSnapshotNotFound, got %v", err)
+	}
+	s := NewService(newFakeBackend(), store, nil, nil, nil).(*service)
+	m, _ := s.matrix(context.Background(), nil)
+	for range 2 {
+		if _, err := store.Save(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := store.Latest()
+	if err != nil || latest.ID != 2 || !latest.Matrix.Grants(2, 2) {
+		t.Fatalf("unexpected latest snapshot %+v, %v", latest, err)
+	}
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != 1 || list[0].Matrix != nil || list[0].Roles != 3 || list[0].Users != 3 {
+		t.Errorf("unexpected snapshot list %+v, %v", list, err)
+	}
+	if _, err := store.Get(3); err != ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	fonts, _ := report.LoadFonts(nil)
+	b := newFakeBackend()
+	s := NewService(b, nil, fonts, nil, nil).(*service)
+	prev, _ := s.matrix(context.Background(), nil)
+
+	// Enough roles and access controls to split the matrix across tables
+	// and pages.
+	for i := 4; i <= 30; i++ {
+		b.roles = append(b.roles, models.Role{ID: i, Name: fmt.Sprintf("Role %d", i), Active: true})
+		for p := 1; p <= 60; p += i%5 + 1 {
+			b.grants[i] = append(b.grants[i], p)
+		}
+		b.users[i] = []int{100 + i}
+	}
+	cur, _ := s.matrix(context.Background(), nil)
+
+	pdf := renderMatrixPDF(cur, fonts, nil)
+	if pdf.Err() {
+		t.Fatal(pdf.Error())
+	}
+	if pdf.PageNo() < 3 {
+		t.Errorf("expected the matrix to span pages, got %d", pdf.PageNo())
+	}
+
+	d := &Diff{Snapshot: &Snapshot{ID: 1, TakenAt: prev.TakenAt, Matrix: prev}, Current: cur, Changes: Compare(prev, cur)}
+	if !d.NewlyGranted(30, 1) || d.NewlyGranted(1, 1) {
+		t.Error("unexpected new grants")
+	}
+	pdf = renderDiffPDF(d, fonts, nil)
+	if pdf.Err() {
+		t.Fatal(pdf.Error())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	b := newFakeBackend()
+	routes := NewHandler(NewService(b, openStore(t), nil, nil, nil)).Routes()
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, "/diff"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without snapshots, got %d", rec.Code)
+	}
+	rec := serve(http.MethodPost, "/snapshots")
+	var created struct {
+		Data Snapshot `json:"data"`
+	}
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &created) != nil || created.Data.ID != 1 || created.Data.Matrix != nil {
+		t.Fatalf("unexpected snapshot %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(http.MethodGet, "/?format=csv")
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("unexpected matrix %d, %v", rec.Code, err)
+	}
+	// A header, four grants and three users.
+	if len(rows) != 8 || rows[1][0] != "grant" || rows[1][2] != "Admin" || rows[7][7] != "User 3" {
+		t.Errorf("unexpected matrix rows %q", rows)
+	}
+
+	b.grants[3] = []int{1}
+	rec = serve(http.MethodGet, "/diff?since=1&format=csv")
+	if rows, _ := csv.NewReader(rec.Body).ReadAll(); len(rows) != 2 || strings.Join(rows[1], ",") != "granted,3,Student,1,Permission 1,," {
+		t.Errorf("unexpected diff %q", rows)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd == "" || !strings.HasPrefix(cd, "attachment; filename=access_diff_1_") {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodGet, "/diff", http.StatusOK},
+		{http.MethodGet, "/diff?since=2", http.StatusNotFound},
+		{http.MethodGet, "/diff?since=first", http.StatusBadRequest},
+		{http.MethodGet, "/?format=xlsx", http.StatusBadRequest},
+		{http.MethodGet, "/snapshots", http.StatusOK},
+	} {
+		if rec := serve(tc.method, tc.target); rec.Code != tc.status {
+			t.Errorf("%s %s: expected %d, got %d: %s", tc.method, tc.target, tc.status, rec.Code, rec.Body)
+		}
+	}
+
+	// Accept naming a format the reports lack falls back to the PDF.
+	for _, target := range []string{"/", "/diff"} {
+		req := testutil.AuthedRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+			t.Errorf("%s: expected the PDF, got %d %s", target, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	// Snapshots need a store, and backend refusals are passed on.
+	b.err = &client.StatusError{Resource: "roles", Status: http.StatusForbidden}
+	routes = NewHandler(NewService(b, nil, nil, nil, nil)).Routes()
+	if rec := serve(http.MethodPost, "/snapshots"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a store, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves access audits, mounted under /api/v1/admin/access behind
+// the admin token. The matrix is read from the backend with the session
+// cookies sent along, so they are required as well.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.Matrix)
+	r.Get("/diff", h.Diff)
+	r.Get("/snapshots", h.Snapshots)
+	r.Post("/snapshots", h.TakeSnapshot)
+	return r
+}
+
+// errorStatus maps access audit errors to HTTP status codes. Backend
+// refusals of the session are passed on.
+func errorStatus(err error) int {
+	var statusErr *client.StatusError
+	switch {
+	case errors.Is(err, report.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrSnapshotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrSnapshotsDisabled):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &statusErr) && (statusErr.Status == http.StatusUnauthorized || statusErr.Status == http.StatusForbidden):
+		return statusErr.Status
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) Matrix(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	format, err := report.NegotiateFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"), report.FormatPDF, report.FormatCSV)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateMatrix(r.Context(), Options{Format: format}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	serve(w, rep, format, fmt.Sprintf("access_matrix_%s", time.Now().Format("20060102")))
+}
+
+// Diff compares the matrix with the snapshot in ?since=, the latest one by
+// default.
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+	q := r.URL.Query()
+	var since int
+	if s := q.Get("since"); s != "" {
+		if since, err = strconv.Atoi(s); err != nil || since < 1 {
+			response.Error(w, http.StatusBadRequest, errors.New("since must be a snapshot id"))
+			return
+		}
+	}
+	w.Header().Add("Vary", "Accept")
+	format, err := report.NegotiateFormat(q.Get("format"), r.Header.Get("Accept"), report.FormatPDF, report.FormatCSV)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateDiff(r.Context(), Options{Format: format, Since: since}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	name := fmt.Sprintf("access_diff_%s", time.Now().Format("20060102"))
+	if since != 0 {
+		name = fmt.Sprintf("access_diff_%d_%s", since, time.Now().Format("20060102"))
+	}
+	serve(w, rep, format, name)
+}
+
+func (h *Handler) Snapshots(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.Snapshots()
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, list)
+}
+
+// TakeSnapshot saves the current matrix and answers with its summary.
+func (h *Handler) TakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+	snap, err := h.service.TakeSnapshot(r.Context(), cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	summary := *snap
+	summary.Matrix = nil
+	response.JSON(w, http.StatusCreated, summary)
+}
+
+func serve(w http.ResponseWriter, rep report.Writer, format report.Format, name string) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format.Extension()}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,331 @@
+package access
+
+import (
+	"encoding/csv"
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const timeLayout = "02 Jan 2006 15:04"
+
+var (
+	titleFont = report.Font{Style: "B", Size: 16}
+	headFont  = report.Font{Style: "B", Size: 12}
+	textFont  = report.Font{Size: 10}
+	tableHead = report.Font{Style: "B", Size: 8}
+	tableFont = report.Font{Size: 8}
+	rowHeight = 5.5
+	// permWidth is the access control column; role columns share the rest
+	// of the page, and roles that do not fit go to another table.
+	permWidth    = 80.0
+	minRoleWidth = 14.0
+	maxRoleWidth = 24.0
+	// highlight fills newly granted access in diffs.
+	highlight = [3]int{255, 214, 153}
+)
+
+// mark is the content of a matrix cell and whether it is highlighted.
+type mark func(roleID, permID int) (string, bool)
+
+// renderMatrixPDF lays out the matrix with a column per role and a row per
+// access control, followed by the users of each role.
+func renderMatrixPDF(m *Matrix, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := newPDF("Access Matrix", fonts, brand)
+	fonts.Cell(pdf, titleFont, 0, 10, "Access Matrix", "", 1, "", 0)
+	fonts.Cell(pdf, textFont, 0, 6, fmt.Sprintf("%d roles, %d access controls, %d users - as of %s", len(m.Roles), len(m.Permissions), m.Users(), m.TakenAt.Local().Format(timeLayout)), "", 1, "", 0)
+	pdf.Ln(4)
+
+	matrixTable(pdf, fonts, m.Roles, m.Permissions, func(roleID, permID int) (string, bool) {
+		if m.Grants(roleID, permID) {
+			return "X", false
+		}
+		return "", false
+	})
+	usersTable(pdf, fonts, m.Roles, func(int, int) bool { return false })
+	return pdf
+}
+
+// renderDiffPDF lists the changes since the snapshot, new access first and
+// highlighted, then the matrix and users marking what changed.
+func renderDiffPDF(d *Diff, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	prev, cur := d.Snapshot.Matrix, d.Current
+	pdf := newPDF("Access Changes", fonts, brand)
+	fonts.Cell(pdf, titleFont, 0, 10, "Access Changes", "", 1, "", 0)
+	fonts.Cell(pdf, textFont, 0, 6, fmt.Sprintf("Since snapshot %d of %s, as of %s", d.Snapshot.ID, d.Snapshot.TakenAt.Local().Format(timeLayout), cur.TakenAt.Local().Format(timeLayout)), "", 1, "", 0)
+	pdf.Ln(4)
+
+	fonts.Cell(pdf, headFont, 0, 8, "Changes", "", 1, "", 0)
+	if len(d.Changes) == 0 {
+		fonts.Cell(pdf, textFont, 0, 7, "No changes since the snapshot.", "", 1, "", 0)
+	} else {
+		changesTable(pdf, fonts, d.Changes)
+	}
+	pdf.Ln(6)
+
+	roles, perms := union(prev, cur)
+	fonts.Cell(pdf, headFont, 0, 8, "Current matrix", "", 1, "", 0)
+	fonts.Cell(pdf, textFont, 0, 6, "+ newly granted (highlighted), - revoked since the snapshot", "", 1, "", 0)
+	matrixTable(pdf, fonts, roles, perms, func(roleID, permID int) (string, bool) {
+		now, before := cur.Grants(roleID, permID), prev.Grants(roleID, permID)
+		switch {
+		case now && !before:
+			return "+", true
+		case before && !now:
+			return "-", false
+		case now:
+			return "X", false
+		}
+		return "", false
+	})
+	usersTable(pdf, fonts, cur.Roles, func(roleID, userID int) bool { return !prev.HasUser(roleID, userID) })
+	return pdf
+}
+
+func newPDF(title string, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	fonts.Register(pdf)
+	pdf.SetTitle(title, true)
+	brand.Apply(pdf, fonts, nil)
+	pdf.AddPage()
+	return pdf
+}
+
+// breakPage starts a new page when h millimetres do not fit, ahead of
+// gofpdf so that the caller can repeat its header.
+func breakPage(pdf *gofpdf.Fpdf, h float64) bool {
+	_, pageH := pdf.GetPageSize()
+	_, breakMargin := pdf.GetAutoPageBreak()
+	if pdf.GetY()+h > pageH-breakMargin {
+		pdf.AddPage()
+		return true
+	}
+	return false
+}
+
+// headerCell draws a filled table header cell.
+func headerCell(pdf *gofpdf.Fpdf, fonts *report.FontSet, w float64, text, align string) {
+	text = fonts.Fit(pdf, tableHead, text, w-2)
+	fonts.SetFont(pdf, tableHead, text)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(w, rowHeight+1, text, "1", 0, align, true, 0, "")
+}
+
+// cell draws a table cell, filled with the highlight when marked.
+func cell(pdf *gofpdf.Fpdf, fonts *report.FontSet, w float64, text, align string, marked bool) {
+	text = fonts.Fit(pdf, tableFont, text, w-2)
+	fonts.SetFont(pdf, tableFont, text)
+	pdf.SetFillColor(highlight[0], highlight[1], highlight[2])
+	pdf.CellFormat(w, rowHeight, text, "1", 0, align, marked, 0, "")
+}
+
+// matrixTable draws a row per access control and as many role columns as
+// fit the page, repeating the table for the remaining roles. Inactive roles
+// are starred.
+func matrixTable(pdf *gofpdf.Fpdf, fonts *report.FontSet, roles []RoleAccess, perms []models.Permission, mark mark) {
+	if len(roles) == 0 || len(perms) == 0 {
+		fonts.Cell(pdf, textFont, 0, 7, "No access controls are granted to any role.", "", 1, "", 0)
+		return
+	}
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	room := pageW - left - right - permWidth
+	perTable := max(1, int(room/minRoleWidth))
+
+	inactive := false
+	for start := 0; start < len(roles); start += perTable {
+		chunk := roles[start:min(start+perTable, len(roles))]
+		width := min(maxRoleWidth, room/float64(len(chunk)))
+		header := func() {
+			headerCell(pdf, fonts, permWidth, "Access control", "L")
+			for _, r := range chunk {
+				name := r.Name
+				if !r.Active {
+					name, inactive = name+" *", true
+				}
+				headerCell(pdf, fonts, width, name, "C")
+			}
+			pdf.Ln(-1)
+		}
+		breakPage(pdf, 2*rowHeight+1)
+		header()
+		for _, p := range perms {
+			if breakPage(pdf, rowHeight) {
+				header()
+			}
+			cell(pdf, fonts, permWidth, p.Name, "L", false)
+			for _, r := range chunk {
+				text, marked := mark(r.ID, p.ID)
+				cell(pdf, fonts, width, text, "C", marked)
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(4)
+	}
+	if inactive {
+		fonts.Cell(pdf, textFont, 0, 6, "* inactive role", "", 1, "", 0)
+	}
+}
+
+// usersTable lists the users of each role, highlighting those for which
+// added returns true.
+func usersTable(pdf *gofpdf.Fpdf, fonts *report.FontSet, roles []RoleAccess, added func(roleID, userID int) bool) {
+	cols := []struct {
+		title string
+		width float64
+	}{{"Role", 70}, {"User ID", 25}, {"User", 100}, {"Last login", 45}}
+	header := func() {
+		for _, c := range cols {
+			headerCell(pdf, fonts, c.width, c.title, "L")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.Ln(2)
+	breakPage(pdf, 8+2*rowHeight+1)
+	fonts.Cell(pdf, headFont, 0, 8, "Users per role", "", 1, "", 0)
+	header()
+	for _, r := range roles {
+		if len(r.Users) == 0 {
+			if breakPage(pdf, rowHeight) {
+				header()
+			}
+			cell(pdf, fonts, cols[0].width, r.Name, "L", false)
+			cell(pdf, fonts, cols[1].width+cols[2].width+cols[3].width, "No users", "L", false)
+			pdf.Ln(-1)
+			continue
+		}
+		for _, u := range r.Users {
+			if breakPage(pdf, rowHeight) {
+				header()
+			}
+			marked := added(r.ID, u.ID)
+			cell(pdf, fonts, cols[0].width, r.Name, "L", marked)
+			cell(pdf, fonts, cols[1].width, strconv.Itoa(u.ID), "R", marked)
+			cell(pdf, fonts, cols[2].width, u.Name, "L", marked)
+			cell(pdf, fonts, cols[3].width, lastLogin(u.LastLogin), "L", marked)
+			pdf.Ln(-1)
+		}
+	}
+}
+
+// changesTable lists changes, highlighting new access.
+func changesTable(pdf *gofpdf.Fpdf, fonts *report.FontSet, changes []Change) {
+	cols := []struct {
+		title string
+		width float64
+	}{{"Change", 30}, {"Role", 70}, {"ID", 20}, {"Access control or user", 160}}
+	header := func() {
+		for _, c := range cols {
+			headerCell(pdf, fonts, c.width, c.title, "L")
+		}
+		pdf.Ln(-1)
+	}
+	header()
+	for _, c := range changes {
+		if breakPage(pdf, rowHeight) {
+			header()
+		}
+		id, subject := c.PermissionID, c.Permission
+		if c.Kind == UserAdded || c.Kind == UserRemoved {
+			id, subject = c.UserID, c.User
+		}
+		marked := c.Kind == Granted || c.Kind == UserAdded
+		cell(pdf, fonts, cols[0].width, string(c.Kind), "L", marked)
+		cell(pdf, fonts, cols[1].width, c.Role, "L", marked)
+		cell(pdf, fonts, cols[2].width, strconv.Itoa(id), "R", marked)
+		cell(pdf, fonts, cols[3].width, subject, "L", marked)
+		pdf.Ln(-1)
+	}
+}
+
+// union returns the roles and access controls of either matrix, so that
+// revoked access still has a cell.
+func union(prev, cur *Matrix) ([]RoleAccess, []models.Permission) {
+	roles := append([]RoleAccess{}, cur.Roles...)
+	for _, r := range prev.Roles {
+		if cur.role(r.ID) == nil {
+			roles = append(roles, r)
+		}
+	}
+	perms := append([]models.Permission{}, cur.Permissions...)
+	seen := map[int]bool{}
+	for _, p := range perms {
+		seen[p.ID] = true
+	}
+	for _, p := range prev.Permissions {
+		if !seen[p.ID] {
+			perms = append(perms, p)
+		}
+	}
+	return roles, perms
+}
+
+func lastLogin(t *time.Time) string {
+	if t == nil {
+		return "Never"
+	}
+	return t.Local().Format(timeLayout)
+}
+
+// matrixCSV emits one "grant" row per access control of each role and one
+// "user" row per user of each role, role by role.
+type matrixCSV struct {
+	matrix *Matrix
+}
+
+func (c *matrixCSV) Output(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	names := map[int]string{}
+	for _, p := range c.matrix.Permissions {
+		names[p.ID] = p.Name
+	}
+	rows := [][]string{{"row", "roleId", "role", "active", "permissionId", "permission", "userId", "user", "lastLogin"}}
+	for _, r := range c.matrix.Roles {
+		role := []string{strconv.Itoa(r.ID), r.Name, strconv.FormatBool(r.Active)}
+		for _, id := range r.Permissions {
+			rows = append(rows, append(append([]string{"grant"}, role...), strconv.Itoa(id), names[id], "", "", ""))
+		}
+		for _, u := range r.Users {
+			login := ""
+			if u.LastLogin != nil {
+				login = u.LastLogin.UTC().Format(time.RFC3339)
+			}
+			rows = append(rows, append(append([]string{"user"}, role...), "", "", strconv.Itoa(u.ID), u.Name, login))
+		}
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// diffCSV emits one row per change. Grants and revocations fill the
+// permission columns, user changes the user columns.
+type diffCSV struct {
+	diff *Diff
+}
+
+func (c *diffCSV) Output(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"change", "roleId", "role", "permissionId", "permission", "userId", "user"}}
+	for _, ch := range c.diff.Changes {
+		row := []string{string(ch.Kind), strconv.Itoa(ch.RoleID), ch.Role, "", ch.Permission, "", ch.User}
+		if ch.PermissionID != 0 {
+			row[3] = strconv.Itoa(ch.PermissionID)
+		}
+		if ch.UserID != 0 {
+			row[5] = strconv.Itoa(ch.UserID)
+		}
+		rows = append(rows, row)
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,297 @@
+// Package access reports who can do what: the backend's access controls
+// granted to each role and the users holding each role, and what changed
+// since a saved snapshot of that matrix.
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// KindAccess identifies access reports in signatures.
+const KindAccess = "access"
+
+var (
+	ErrSnapshotsDisabled = errors.New("access snapshots are not configured")
+	ErrSnapshotNotFound  = errors.New("access snapshot not found")
+)
+
+// RoleAccess is a role with the ids of the access controls it grants, in
+// ascending order, and its users.
+type RoleAccess struct {
+	models.Role
+	Permissions []int             `json:"permissions"`
+	Users       []models.RoleUser `json:"users"`
+}
+
+// Matrix is every role against every access control granted to any role.
+type Matrix struct {
+	Roles       []RoleAccess        `json:"roles"`
+	Permissions []models.Permission `json:"permissions"`
+	TakenAt     time.Time           `json:"takenAt"`
+}
+
+// Grants reports whether role roleID grants access control permID.
+func (m *Matrix) Grants(roleID, permID int) bool {
+	r := m.role(roleID)
+	if r == nil {
+		return false
+	}
+	i := sort.SearchInts(r.Permissions, permID)
+	return i < len(r.Permissions) && r.Permissions[i] == permID
+}
+
+// HasUser reports whether role roleID is held by user userID.
+func (m *Matrix) HasUser(roleID, userID int) bool {
+	if r := m.role(roleID); r != nil {
+		for _, u := range r.Users {
+			if u.ID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Users counts the users of all roles.
+func (m *Matrix) Users() int {
+	n := 0
+	for _, r := range m.Roles {
+		n += len(r.Users)
+	}
+	return n
+}
+
+func (m *Matrix) role(id int) *RoleAccess {
+	for i := range m.Roles {
+		if m.Roles[i].ID == id {
+			return &m.Roles[i]
+		}
+	}
+	return nil
+}
+
+// ChangeKind says how access changed between two matrices.
+type ChangeKind string
+
+const (
+	Granted     ChangeKind = "granted"
+	UserAdded   ChangeKind = "user added"
+	Revoked     ChangeKind = "revoked"
+	UserRemoved ChangeKind = "user removed"
+)
+
+// changeOrder puts new access first.
+var changeOrder = map[ChangeKind]int{Granted: 0, UserAdded: 1, Revoked: 2, UserRemoved: 3}
+
+// Change is an access control granted to or revoked from a role, or a user
+// added to or removed from one. A user switching roles shows as removed
+// from the old role and added to the new one.
+type Change struct {
+	Kind         ChangeKind `json:"kind"`
+	RoleID       int        `json:"roleId"`
+	Role         string     `json:"role"`
+	PermissionID int        `json:"permissionId,omitempty"`
+	Permission   string     `json:"permission,omitempty"`
+	UserID       int        `json:"userId,omitempty"`
+	User         string     `json:"user,omitempty"`
+}
+
+// Diff is the current matrix compared with a snapshot.
+type Diff struct {
+	Snapshot *Snapshot
+	Current  *Matrix
+	Changes  []Change
+}
+
+// NewlyGranted reports whether role roleID grants permID now but did not
+// in the snapshot.
+func (d *Diff) NewlyGranted(roleID, permID int) bool {
+	return d.Current.Grants(roleID, permID) && !d.Snapshot.Matrix.Grants(roleID, permID)
+}
+
+// Compare lists the changes from prev to cur, new access first, then by
+// role in the order of the matrices. Roles that appear or disappear count as
+// granting or revoking all of their access.
+func Compare(prev, cur *Matrix) []Change {
+	changes := []Change{}
+	diff := func(from, to *Matrix, granted, userAdded ChangeKind) {
+		names := map[int]string{}
+		for _, p := range to.Permissions {
+			names[p.ID] = p.Name
+		}
+		for _, r := range to.Roles {
+			for _, id := range r.Permissions {
+				if !from.Grants(r.ID, id) {
+					changes = append(changes, Change{Kind: granted, RoleID: r.ID, Role: r.Name, PermissionID: id, Permission: names[id]})
+				}
+			}
+			for _, u := range r.Users {
+				if !from.HasUser(r.ID, u.ID) {
+					changes = append(changes, Change{Kind: userAdded, RoleID: r.ID, Role: r.Name, UserID: u.ID, User: u.Name})
+				}
+			}
+		}
+	}
+	diff(prev, cur, Granted, UserAdded)
+	diff(cur, prev, Revoked, UserRemoved)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return changeOrder[a.Kind] < changeOrder[b.Kind]
+		}
+		return a.RoleID < b.RoleID
+	})
+	return changes
+}
+
+// Options are the per-request choices of an access report.
+type Options struct {
+	// Format is pdf or csv, empty means PDF.
+	Format report.Format
+	// Since is the snapshot a diff compares with, the latest when zero.
+	Since int
+}
+
+type Service interface {
+	// GenerateMatrix reports the current access matrix.
+	GenerateMatrix(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	// GenerateDiff reports the changes since a snapshot.
+	GenerateDiff(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	// TakeSnapshot saves the current access matrix.
+	TakeSnapshot(ctx context.Context, authCookies []*http.Cookie) (*Snapshot, error)
+	// Snapshots lists the saved snapshots without their matrix.
+	Snapshots() ([]Snapshot, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	store    *Store
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService saves snapshots in store, which may be nil when snapshots are
+// disabled, and draws text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, store *Store, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, store: store, fonts: fonts, branding: branding, signer: signer}
+}
+
+func checkFormat(f report.Format) error {
+	if f != "" && f != report.FormatPDF && f != report.FormatCSV {
+		return fmt.Errorf("%w: access reports are pdf or csv", report.ErrUnsupportedFormat)
+	}
+	return nil
+}
+
+func (s *service) GenerateMatrix(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	if err := checkFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	m, err := s.matrix(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == report.FormatCSV {
+		return &matrixCSV{matrix: m}, nil
+	}
+	pdf := renderMatrixPDF(m, s.fonts, s.branding.Current())
+	return s.signer.Wrap(pdf, pdfsign.Document{Kind: KindAccess, Name: "Access matrix"}), nil
+}
+
+func (s *service) GenerateDiff(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	if err := checkFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrSnapshotsDisabled
+	}
+	var snap *Snapshot
+	var err error
+	if opts.Since == 0 {
+		snap, err = s.store.Latest()
+	} else {
+		snap, err = s.store.Get(opts.Since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.matrix(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	d := &Diff{Snapshot: snap, Current: m, Changes: Compare(snap.Matrix, m)}
+
+	if opts.Format == report.FormatCSV {
+		return &diffCSV{diff: d}, nil
+	}
+	pdf := renderDiffPDF(d, s.fonts, s.branding.Current())
+	doc := pdfsign.Document{Kind: KindAccess, ID: snap.ID, Name: fmt.Sprintf("Access changes since snapshot %d", snap.ID)}
+	return s.signer.Wrap(pdf, doc), nil
+}
+
+func (s *service) TakeSnapshot(ctx context.Context, authCookies []*http.Cookie) (*Snapshot, error) {
+	if s.store == nil {
+		return nil, ErrSnapshotsDisabled
+	}
+	m, err := s.matrix(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Save(m)
+}
+
+func (s *service) Snapshots() ([]Snapshot, error) {
+	if s.store == nil {
+		return nil, ErrSnapshotsDisabled
+	}
+	return s.store.List()
+}
+
+// matrix fetches every role with its access controls and users.
+func (s *service) matrix(ctx context.Context, authCookies []*http.Cookie) (*Matrix, error) {
+	roles, err := s.backend.ListRoles(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	m := &Matrix{Roles: make([]RoleAccess, 0, len(roles)), Permissions: []models.Permission{}, TakenAt: time.Now().UTC()}
+	seen := map[int]bool{}
+	for _, role := range roles {
+		perms, err := s.backend.ListRolePermissions(ctx, role.ID, authCookies)
+		if err != nil {
+			return nil, err
+		}
+		users, err := s.backend.ListRoleUsers(ctx, role.ID, authCookies)
+		if err != nil {
+			return nil, err
+		}
+		r := RoleAccess{Role: role, Permissions: make([]int, 0, len(perms)), Users: users}
+		for _, p := range perms {
+			r.Permissions = append(r.Permissions, p.ID)
+			if !seen[p.ID] {
+				seen[p.ID] = true
+				m.Permissions = append(m.Permissions, p)
+			}
+		}
+		sort.Ints(r.Permissions)
+		sort.SliceStable(r.Users, func(i, j int) bool { return r.Users[i].Name < r.Users[j].Name })
+		m.Roles = append(m.Roles, r)
+	}
+	sort.SliceStable(m.Roles, func(i, j int) bool { return m.Roles[i].ID < m.Roles[j].ID })
+	sort.Slice(m.Permissions, func(i, j int) bool { return m.Permissions[i].ID < m.Permissions[j].ID })
+	return m, nil
+}
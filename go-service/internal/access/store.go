@@ -0,0 +1,133 @@
+package access
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var snapshotsBucket = []byte("access_snapshots")
+
+// Snapshot is a saved access matrix, numbered from 1 in the order taken.
+// The counts summarise the matrix for listings, which leave it out.
+type Snapshot struct {
+	ID          int       `json:"id"`
+	TakenAt     time.Time `json:"takenAt"`
+	Roles       int       `json:"roles"`
+	Permissions int       `json:"permissions"`
+	Users       int       `json:"users"`
+	Matrix      *Matrix   `json:"matrix,omitempty"`
+}
+
+// Store keeps access snapshots in a bbolt database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the snapshot database at path.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("access snapshots: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("access snapshots %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(snapshotsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save records m as the next snapshot.
+func (s *Store) Save(m *Matrix) (*Snapshot, error) {
+	snap := &Snapshot{TakenAt: m.TakenAt, Roles: len(m.Roles), Permissions: len(m.Permissions), Users: m.Users(), Matrix: m}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		snap.ID = int(id)
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		return b.Put(snapshotKey(snap.ID), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Get returns snapshot id with its matrix.
+func (s *Store) Get(id int) (*Snapshot, error) {
+	var snap Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(snapshotsBucket).Get(snapshotKey(id))
+		if data == nil {
+			return ErrSnapshotNotFound
+		}
+		return json.Unmarshal(data, &snap)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Latest returns the last snapshot taken with its matrix.
+func (s *Store) Latest() (*Snapshot, error) {
+	var snap Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		_, data := tx.Bucket(snapshotsBucket).Cursor().Last()
+		if data == nil {
+			return ErrSnapshotNotFound
+		}
+		return json.Unmarshal(data, &snap)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// List returns every snapshot without its matrix, oldest first.
+func (s *Store) List() ([]Snapshot, error) {
+	list := []Snapshot{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEach(func(_, data []byte) error {
+			var snap Snapshot
+			if err := json.Unmarshal(data, &snap); err != nil {
+				return err
+			}
+			snap.Matrix = nil
+			list = append(list, snap)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// snapshotKey is big-endian so that keys sort in the order taken.
+func snapshotKey(id int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
@@ -15,6 +15,7 @@ import (
 
 // --- Mock IBackend ---
 type mockBackend struct {
+	client.IBackend
 	loginFn func(ctx context.Context, username, password string) ([]*http.Cookie, error)
 }
 
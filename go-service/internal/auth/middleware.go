@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"goservice/internal/response"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrAdminDisabled = errors.New("admin endpoints are disabled")
+	ErrAdminToken    = errors.New("missing or invalid admin token")
+)
+
+// RequireAdminToken only lets requests through that present token as a
+// bearer token. An empty token disables the wrapped routes.
+func RequireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				response.Error(w, http.StatusForbidden, ErrAdminDisabled)
+				return
+			}
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				response.Error(w, http.StatusUnauthorized, ErrAdminToken)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		name, token, header string
+		want                int
+	}{
+		{"valid", "s3cret", "Bearer s3cret", http.StatusNoContent},
+		{"wrong token", "s3cret", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"not a bearer token", "s3cret", "s3cret", http.StatusUnauthorized},
+		{"disabled", "", "Bearer ", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		RequireAdminToken(tc.token)(ok).ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package certificate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleStudent() *models.Student {
+	return &models.Student{
+		ID: 7, Name: "Cara Lee", Gender: "Female", Class: "10", Section: "A", Roll: 4,
+		FatherName: "Sam Lee", MotherName: "Ivy Lee",
+		DOB:           time.Date(2010, 3, 4, 0, 0, 0, 0, time.Local),
+		AdmissionDate: time.Date(2016, 4, 1, 0, 0, 0, 0, time.Local),
+	}
+}
+
+type fakeBackend struct {
+	client.IBackend
+}
+
+func (f *fakeBackend) GetStudentByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Student, error) {
+	switch id {
+	case 7:
+		return sampleStudent(), nil
+	case 8:
+		return nil, &client.StatusError{Resource: "student", Status: http.StatusForbidden}
+	}
+	return nil, &client.StatusError{Resource: "student", Status: http.StatusNotFound}
+}
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(filepath.Join(t.TempDir(), "certificates.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestAcademicYear(t *testing.T) {
+	for _, tc := range []struct {
+		date  time.Time
+		start time.Month
+		want  string
+	}{
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.April, "2024-25"},
+		{time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), time.April, "2024-25"},
+		{time.Date(2099, 6, 1, 0, 0, 0, 0, time.UTC), time.June, "2099-00"},
+		{time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), time.January, "2025"},
+	} {
+		if got := AcademicYear(tc.date, tc.start); got != tc.want {
+			t.Errorf("%s starting in %s: expected %s, got %s", tc.date.Format(time.DateOnly), tc.start, tc.want, got)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	reg, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, tmpl := range reg.Types() {
+		types = append(types, tmpl.Type)
+	}
+	if got := strings.Join(types, ","); got != "bonafide,character,transfer" {
+		t.Fatalf("unexpected built-in types %s", got)
+	}
+
+	tc, _ := reg.Lookup("transfer")
+	if _, err := tc.Values(map[string]string{"reason": "Relocation"}); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("expected the missing leaving date to be rejected, got %v", err)
+	}
+	if _, err := tc.Values(map[string]string{"reason": "Relocation", "leavingDate": "31/03/2025"}); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("expected a malformed date to be rejected, got %v", err)
+	}
+	if _, err := tc.Values(map[string]string{"reason": "Relocation", "leavingDate": "2025-03-31", "grade": "A"}); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("expected an unknown field to be rejected, got %v", err)
+	}
+	values, err := tc.Values(map[string]string{"reason": "Relocation", "leavingDate": "2025-03-31"})
+	if err != nil || values["conduct"] != "Good" {
+		t.Fatalf("expected the conduct default, got %v, %v", values, err)
+	}
+
+	body, err := tc.render(Data{Student: sampleStudent(), Fields: values, School: "Springfield School"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Join(body, "\n")
+	for _, want := range []string{
+		"Cara Lee, daughter of Sam Lee and Ivy Lee, was a student of Springfield School from 01 April 2016 to 31 March 2025.",
+		"Her date of birth according to the admission register is 04 March 2010.",
+		"Reason for leaving: Relocation.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in\n%s", want, text)
+		}
+	}
+
+	st := sampleStudent()
+	st.Gender, st.FatherName, st.MotherName = "", "", ""
+	cc, _ := reg.Lookup("character")
+	body, _ = cc.render(Data{Student: st, Fields: map[string]string{"conduct": "good"}})
+	if !strings.HasPrefix(body[0], "This is to certify that Cara Lee is a student") || !strings.Contains(body[1], "Cara Lee's conduct") {
+		t.Errorf("unexpected text without parents or gender %q", body)
+	}
+}
+
+func TestLoadTemplates_Dir(t *testing.T) {
+	dir := t.TempDir()
+	custom := "type: bonafide\ntitle: Study Certificate\nprefix: SC\nbody: Certified {{.Student.Name}}.\n"
+	if err := os.WriteFile(filepath.Join(dir, "bonafide.yaml"), []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl, _ := reg.Lookup("bonafide"); tmpl.Prefix != "SC" {
+		t.Errorf("expected the directory to override the built-in type, got %+v", tmpl)
+	}
+
+	// A type defined twice in the directory, or a prefix used by two types,
+	// is rejected.
+	for name, files := range map[string]map[string]string{
+		"duplicate type": {
+			"a.yaml": "type: leaving\ntitle: Leaving\nprefix: LV\nbody: x\n",
+			"b.yaml": "type: leaving\ntitle: Leaving\nprefix: LC\nbody: x\n",
+		},
+		"duplicate prefix": {
+			"conduct.yaml": "type: conduct\ntitle: Conduct\nprefix: SC\nbody: x\n",
+			"study.yaml":   custom,
+		},
+	} {
+		dir := t.TempDir()
+		for file, data := range files {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := LoadTemplates(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"prefix.yaml": "type: x\ntitle: X\nprefix: x-1\nbody: x\n",
+		"field.yaml":  "type: x\ntitle: X\nprefix: X\nbody: '{{.Student.Nickname}}'\n",
+	} {
+		if _, err := ParseTemplate(name, []byte(data)); err == nil {
+			t.Errorf("%s: expected an invalid template", name)
+		}
+	}
+}
+
+func TestStore_Issue(t *testing.T) {
+	store := openStore(t)
+	fill := func(*Certificate) error { return nil }
+	issue := func(typ, year string, student int, once bool) (*Certificate, error) {
+		c := &Certificate{Type: typ, AcademicYear: year, StudentID: student, IssuedAt: time.Now()}
+		return c, store.Issue(c, strings.ToUpper(typ[:2]), once, fill)
+	}
+
+	var serials []string
+	for _, year := range []string{"2024-25", "2024-25", "2025-26"} {
+		c, err := issue("bonafide", year, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serials = append(serials, c.Serial)
+	}
+	if got := strings.Join(serials, ","); got != "BO-2024-25-0001,BO-2024-25-0002,BO-2025-26-0001" {
+		t.Errorf("expected numbers per academic year, got %s", got)
+	}
+
+	tc, err := issue("transfer", "2024-25", 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issue("transfer", "2024-25", 1, true); !errors.Is(err, ErrAlreadyIssued) {
+		t.Errorf("expected a second transfer certificate to be refused, got %v", err)
+	}
+	if _, err := store.Cancel(tc.Serial, "wrong date"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Cancel(tc.Serial, ""); !errors.Is(err, ErrCancelled) {
+		t.Errorf("expected ErrCancelled, got %v", err)
+	}
+	if c, err := issue("transfer", "2024-25", 1, true); err != nil || c.Number != 2 {
+		t.Errorf("expected a replacement with the next number, got %+v, %v", c, err)
+	}
+
+	if list, _ := store.List(Filter{Type: "transfer"}); len(list) != 2 || !list[0].Cancelled {
+		t.Errorf("expected the cancelled and the replacement certificate, got %+v", list)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	svc := NewService(&fakeBackend{}, openStore(t), nil, Options{}).(*service)
+	svc.now = func() time.Time { return time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC) }
+	routes := NewHandler(svc).Routes()
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/students/7", `{"type":"bonafide","fields":{"purpose":"a passport application"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var out struct {
+		Data Certificate `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	issued := out.Data
+	if issued.Serial != "BON-2024-25-0001" || rec.Header().Get("Location") != BasePath+"BON-2024-25-0001/pdf" {
+		t.Fatalf("unexpected certificate %s at %s", issued.Serial, rec.Header().Get("Location"))
+	}
+	if !strings.Contains(strings.Join(issued.Body, " "), "for the purpose of a passport application") {
+		t.Errorf("unexpected body %q", issued.Body)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"unknown type", http.MethodPost, "/students/7", `{"type":"migration"}`, http.StatusBadRequest},
+		{"missing field", http.MethodPost, "/students/7", `{"type":"transfer","fields":{"reason":"Relocation"}}`, http.StatusBadRequest},
+		{"unknown student", http.MethodPost, "/students/9", `{"type":"bonafide"}`, http.StatusNotFound},
+		{"forbidden student", http.MethodGet, "/students/8", "", http.StatusForbidden},
+		{"list", http.MethodGet, "/students/7", "", http.StatusOK},
+		{"get lower case", http.MethodGet, "/bon-2024-25-0001", "", http.StatusOK},
+		{"unknown serial", http.MethodGet, "/BON-2024-25-0099/pdf", "", http.StatusNotFound},
+		{"types", http.MethodGet, "/types", "", http.StatusOK},
+	} {
+		if rec := serve(tc.method, tc.target, tc.body); rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+		}
+	}
+
+	for i := range 2 {
+		rec := serve(http.MethodGet, "/BON-2024-25-0001/pdf", "")
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "%PDF") {
+			t.Fatalf("print %d: expected a PDF, got %d: %.60s", i+1, rec.Code, rec.Body)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=certificate_BON-2024-25-0001.pdf" {
+			t.Errorf("unexpected content disposition %q", cd)
+		}
+	}
+	// A print that is not written out is not counted.
+	pdf, _, err := svc.Print(context.Background(), "BON-2024-25-0001", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pdf.Output(failingWriter{}); err == nil {
+		t.Fatal("expected the output to fail")
+	}
+	if c, _ := svc.store.Get("BON-2024-25-0001"); c.Prints != 2 {
+		t.Errorf("expected two prints to be counted, got %d", c.Prints)
+	}
+
+	// Cancelling is an administrator operation.
+	if rec := serve(http.MethodPost, "/BON-2024-25-0001/cancel", ""); rec.Code != http.StatusMethodNotAllowed && rec.Code != http.StatusNotFound {
+		t.Errorf("expected no cancel route for sessions, got %d", rec.Code)
+	}
+	admin := NewHandler(svc).AdminRoutes()
+	cancel := func(body string) int {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/BON-2024-25-0001/cancel", strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := cancel(`{"reason":"misspelt name"}`); code != http.StatusOK {
+		t.Fatalf("expected the certificate to be cancelled, got %d", code)
+	}
+	if code := cancel(""); code != http.StatusConflict {
+		t.Errorf("expected 409 cancelling twice, got %d", code)
+	}
+	if rec := serve(http.MethodGet, "/BON-2024-25-0001/pdf", ""); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 printing a cancelled certificate, got %d", rec.Code)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestRenderCertificate_Duplicate(t *testing.T) {
+	fonts, _ := report.LoadFonts(nil)
+	brand, err := report.LoadBrand(report.Branding{SchoolName: "Springfield School"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Certificate{Serial: "CC-2024-25-0001", Title: "Character Certificate", Body: []string{"First.", "Second."}, IssuedAt: time.Now()}
+	for _, duplicate := range []bool{false, true} {
+		pdf := renderCertificate(c, duplicate, "Principal", fonts, brand, &report.Stamp{Serial: "ABCDE-FGHJK", URL: "http://example.com"})
+		if pdf.Err() {
+			t.Fatalf("duplicate %v: %v", duplicate, pdf.Error())
+		}
+		if pdf.PageNo() != 1 {
+			t.Errorf("duplicate %v: expected one page, got %d", duplicate, pdf.PageNo())
+		}
+	}
+}
+
+func TestRegisterDisabled(t *testing.T) {
+	routes := NewHandler(NewService(&fakeBackend{}, nil, nil, Options{})).AdminRoutes()
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a register, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,207 @@
+package certificate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BasePath is where the certificate routes are mounted.
+const BasePath = "/api/v1/certificates/"
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves certificates, mounted under /api/v1/certificates. Every
+// endpoint requires the backend session cookies, and a certificate is only
+// served to sessions that can read its student. Cancelling is left to
+// AdminRoutes.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/types", h.Types)
+	r.Get("/students/{id}", h.List)
+	r.Post("/students/{id}", h.Issue)
+	r.Get("/{serial}", h.Get)
+	r.Get("/{serial}/pdf", h.Print)
+	return r
+}
+
+// AdminRoutes serves the register of every student and cancellations,
+// mounted under /api/v1/admin/certificates behind the admin token.
+func (h *Handler) AdminRoutes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.Register)
+	r.Post("/{serial}/cancel", h.Cancel)
+	return r
+}
+
+// errorStatus maps certificate errors to HTTP status codes. Backend
+// refusals of the session are passed on.
+func errorStatus(err error) int {
+	var statusErr *client.StatusError
+	switch {
+	case errors.Is(err, ErrUnknownType), errors.Is(err, ErrInvalidFields):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrCertificateNotFound), errors.Is(err, ErrStudentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyIssued), errors.Is(err, ErrCancelled):
+		return http.StatusConflict
+	case errors.Is(err, ErrRegisterDisabled):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &statusErr) && (statusErr.Status == http.StatusUnauthorized || statusErr.Status == http.StatusForbidden):
+		return statusErr.Status
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) Types(w http.ResponseWriter, r *http.Request) {
+	if _, err := student.CheckRequiredCookie(r); err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, h.service.Types())
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, errors.New("invalid student id"))
+		return
+	}
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	list, err := h.service.List(r.Context(), id, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, list)
+}
+
+// Issue records a certificate from {"type": "...", "fields": {...}} and
+// answers with the register entry. The PDF is printed from its Location.
+func (h *Handler) Issue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, errors.New("invalid student id"))
+		return
+	}
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req IssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	c, err := h.service.Issue(r.Context(), id, req, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", BasePath+c.Serial+"/pdf")
+	response.JSON(w, http.StatusCreated, c)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	c, err := h.service.Get(r.Context(), chi.URLParam(r, "serial"), cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, c)
+}
+
+// Print serves the PDF of a certificate, the original on the first request
+// and a duplicate afterwards.
+func (h *Handler) Print(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	pdf, c, err := h.service.Print(r.Context(), chi.URLParam(r, "serial"), cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "certificate_" + c.Serial + ".pdf"}))
+	w.WriteHeader(http.StatusOK)
+	if err := pdf.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Cancel cancels a certificate. The body may carry {"reason": "..."}.
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	c, err := h.service.Cancel(chi.URLParam(r, "serial"), body.Reason)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, c)
+}
+
+// Register lists the certificates of every student, filtered by the type,
+// year and student query parameters.
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := Filter{Type: q.Get("type"), AcademicYear: q.Get("year")}
+	if raw := q.Get("student"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Errorf("invalid student id %q", raw))
+			return
+		}
+		filter.StudentID = id
+	}
+
+	list, err := h.service.Register(filter)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,94 @@
+package certificate
+
+import (
+	"goservice/internal/report"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	titleFont     = report.Font{Style: "B", Size: 20}
+	metaFont      = report.Font{Size: 10}
+	bodyFont      = report.Font{Size: 12}
+	signatureFont = report.Font{Style: "B", Size: 11}
+	noteFont      = report.Font{Style: "I", Size: 9}
+	watermarkFont = report.Font{Style: "B", Size: 72}
+)
+
+// renderCertificate lays out an A4 certificate: title, serial and date, the
+// body paragraphs and the seal and signature block. Duplicates carry a
+// watermark and a note under the title.
+func renderCertificate(c *Certificate, duplicate bool, signatory string, fonts *report.FontSet, brand *report.Brand, stamp *report.Stamp) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 15, 20)
+	fonts.Register(pdf)
+	brand.Apply(pdf, fonts, func() string { return c.Serial })
+	pdf.AddPage()
+	stamp.Draw(pdf, fonts)
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, pageH := pdf.GetPageSize()
+	width := pageW - left - right
+
+	if duplicate {
+		watermark(pdf, fonts, "DUPLICATE")
+	}
+
+	accent := brand.Accent()
+	pdf.Ln(6)
+	pdf.SetTextColor(accent[0], accent[1], accent[2])
+	fonts.Cell(pdf, titleFont, width, 12, c.Title, "", 1, "C", 0)
+	pdf.SetTextColor(0, 0, 0)
+	if duplicate {
+		fonts.Cell(pdf, noteFont, width, 5, "Duplicate copy of the certificate issued on "+formatDate(c.IssuedAt), "", 1, "C", 0)
+	}
+	pdf.Ln(6)
+
+	fonts.Cell(pdf, metaFont, width/2, 6, "No. "+c.Serial, "", 0, "L", 0)
+	fonts.Cell(pdf, metaFont, width/2, 6, "Date: "+formatDate(c.IssuedAt), "", 1, "R", 0)
+	pdf.Ln(8)
+
+	for _, p := range c.Body {
+		pdf.SetX(left)
+		fonts.MultiCell(pdf, bodyFont, width, 7, p, "L")
+		pdf.Ln(4)
+	}
+
+	// The seal and signature sit at the bottom of a one page certificate,
+	// or below the text when it runs longer.
+	_, breakMargin := pdf.GetAutoPageBreak()
+	y := max(pdf.GetY()+20, pageH-breakMargin-30)
+	if y+20 > pageH-breakMargin {
+		pdf.AddPage()
+		y = pdf.GetY() + 20
+	}
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Line(pageW-right-60, y, pageW-right, y)
+	pdf.SetXY(pageW-right-60, y+1)
+	fonts.Cell(pdf, signatureFont, 60, 6, signatory, "", 2, "C", 0)
+	if brand != nil && brand.SchoolName != "" {
+		fonts.Cell(pdf, metaFont, 60, 5, fonts.Fit(pdf, metaFont, brand.SchoolName, 60), "", 0, "C", 0)
+	}
+	pdf.SetXY(left, y+1)
+	pdf.SetTextColor(150, 150, 150)
+	fonts.Cell(pdf, metaFont, 40, 6, "School seal", "", 0, "L", 0)
+	pdf.SetTextColor(0, 0, 0)
+	return pdf
+}
+
+// watermark draws text diagonally across the middle of the page in light
+// grey, behind the content drawn afterwards.
+func watermark(pdf *gofpdf.Fpdf, fonts *report.FontSet, text string) {
+	pageW, pageH := pdf.GetPageSize()
+	x, y := pdf.GetXY()
+	pdf.SetTextColor(225, 225, 225)
+	fonts.SetFont(pdf, watermarkFont, text)
+	w := pdf.GetStringWidth(text)
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, pageW/2, pageH/2)
+	pdf.SetXY(pageW/2-w/2, pageH/2-15)
+	fonts.Cell(pdf, watermarkFont, w, 30, text, "", 0, "C", 0)
+	pdf.TransformEnd()
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(x, y)
+}
@@ -0,0 +1,255 @@
+// Package certificate issues bonafide, transfer and character certificates
+// from templated document types. Every certificate gets a sequential serial
+// per type and academic year and is kept in a register so that it can be
+// listed, reprinted and cancelled.
+package certificate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/issuance"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KindCertificate identifies certificates in signatures and the report
+// registry.
+const KindCertificate = "certificate"
+
+// DefaultYearStartMonth is April, when the academic year starts unless
+// configured otherwise.
+const DefaultYearStartMonth = time.April
+
+var (
+	ErrRegisterDisabled = errors.New("the certificate register is not configured")
+	ErrInvalidFields    = errors.New("invalid certificate fields")
+	ErrStudentNotFound  = errors.New("student not found")
+)
+
+// IssueRequest is what the office fills in to issue a certificate.
+type IssueRequest struct {
+	Type   string            `json:"type"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Options configure the service. Zero values fall back to the bundled
+// font, no branding, signature or serial, an April academic year and a
+// "Principal" signatory.
+type Options struct {
+	Fonts    *report.FontSet
+	Branding *report.BrandStore
+	Signer   *pdfsign.Signer
+	Issuer   *issuance.Issuer
+	// YearStartMonth is the first month of the academic year.
+	YearStartMonth time.Month
+	// Signatory is printed under the signature line.
+	Signatory string
+}
+
+type Service interface {
+	Types() []*Template
+	Issue(ctx context.Context, studentID int, req IssueRequest, authCookies []*http.Cookie) (*Certificate, error)
+	List(ctx context.Context, studentID int, authCookies []*http.Cookie) ([]Certificate, error)
+	Get(ctx context.Context, serial string, authCookies []*http.Cookie) (*Certificate, error)
+	Print(ctx context.Context, serial string, authCookies []*http.Cookie) (report.Writer, *Certificate, error)
+	// Cancel and Register are administrator operations on the register of
+	// every student.
+	Cancel(serial, reason string) (*Certificate, error)
+	Register(filter Filter) ([]Certificate, error)
+}
+
+type service struct {
+	backend   client.IBackend
+	store     *Store
+	templates *Registry
+	opts      Options
+	now       func() time.Time
+}
+
+// NewService issues certificates into store, which may be nil when the
+// register is disabled, using templates or the built-in types when nil.
+func NewService(b client.IBackend, store *Store, templates *Registry, opts Options) Service {
+	if templates == nil {
+		templates, _ = LoadTemplates("")
+	}
+	if opts.Fonts == nil {
+		opts.Fonts, _ = report.LoadFonts(nil)
+	}
+	if opts.YearStartMonth < time.January || opts.YearStartMonth > time.December {
+		opts.YearStartMonth = DefaultYearStartMonth
+	}
+	if opts.Signatory == "" {
+		opts.Signatory = "Principal"
+	}
+	return &service{backend: b, store: store, templates: templates, opts: opts, now: time.Now}
+}
+
+// AcademicYear names the academic year of t, such as "2024-25" for a year
+// starting in April 2024, or "2024" when it starts in January.
+func AcademicYear(t time.Time, start time.Month) string {
+	year := t.Year()
+	if t.Month() < start {
+		year--
+	}
+	if start == time.January {
+		return fmt.Sprint(year)
+	}
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+func (s *service) Types() []*Template {
+	return s.templates.Types()
+}
+
+func (s *service) Issue(ctx context.Context, studentID int, req IssueRequest, authCookies []*http.Cookie) (*Certificate, error) {
+	if s.store == nil {
+		return nil, ErrRegisterDisabled
+	}
+	t, err := s.templates.Lookup(strings.ToLower(strings.TrimSpace(req.Type)))
+	if err != nil {
+		return nil, err
+	}
+	values, err := t.Values(req.Fields)
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.student(ctx, studentID, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	c := &Certificate{
+		Type:         t.Type,
+		Title:        t.Title,
+		AcademicYear: AcademicYear(now, s.opts.YearStartMonth),
+		StudentID:    st.ID,
+		StudentName:  st.Name,
+		Class:        st.Class,
+		Section:      st.Section,
+		Fields:       values,
+		IssuedAt:     now.UTC(),
+	}
+	school := ""
+	if brand := s.opts.Branding.Current(); brand != nil {
+		school = brand.SchoolName
+	}
+	err = s.store.Issue(c, t.Prefix, t.Once, func(c *Certificate) (err error) {
+		c.Body, err = t.render(Data{
+			Student:      st,
+			Fields:       values,
+			School:       school,
+			Serial:       c.Serial,
+			AcademicYear: c.AcademicYear,
+			IssuedOn:     now,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) List(ctx context.Context, studentID int, authCookies []*http.Cookie) ([]Certificate, error) {
+	if s.store == nil {
+		return nil, ErrRegisterDisabled
+	}
+	if _, err := s.student(ctx, studentID, authCookies); err != nil {
+		return nil, err
+	}
+	return s.store.List(Filter{StudentID: studentID})
+}
+
+func (s *service) Get(ctx context.Context, serial string, authCookies []*http.Cookie) (*Certificate, error) {
+	if s.store == nil {
+		return nil, ErrRegisterDisabled
+	}
+	c, err := s.store.Get(normalizeSerial(serial))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.student(ctx, c.StudentID, authCookies); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Print renders a certificate. The first print is the original, later ones
+// are marked as duplicates. A print is only counted once the document has
+// been written out.
+func (s *service) Print(ctx context.Context, serial string, authCookies []*http.Cookie) (report.Writer, *Certificate, error) {
+	c, err := s.Get(ctx, serial, authCookies)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Cancelled {
+		return nil, nil, ErrCancelled
+	}
+
+	stamp, err := s.opts.Issuer.Stamp()
+	if err != nil {
+		return nil, nil, err
+	}
+	pdf := renderCertificate(c, c.Prints > 0, s.opts.Signatory, s.opts.Fonts, s.opts.Branding.Current(), stamp)
+	name := c.Title + " " + c.Serial
+	pdf.SetTitle(name, true)
+	doc := pdfsign.Document{Kind: KindCertificate, ID: c.StudentID, Name: name}
+	subject := issuance.Subject{Kind: KindCertificate, StudentID: c.StudentID, Name: c.Serial + " " + c.StudentName}
+	w := s.opts.Issuer.Wrap(s.opts.Signer.Wrap(pdf, doc), stamp, subject)
+	return &printWriter{inner: w, store: s.store, serial: c.Serial}, c, nil
+}
+
+// printWriter counts a print of the certificate after it has been output, so
+// that a failed render does not turn the next print into a duplicate.
+type printWriter struct {
+	inner  report.Writer
+	store  *Store
+	serial string
+}
+
+func (w *printWriter) Output(out io.Writer) error {
+	if err := w.inner.Output(out); err != nil {
+		return err
+	}
+	return w.store.Printed(w.serial)
+}
+
+func (s *service) Cancel(serial, reason string) (*Certificate, error) {
+	if s.store == nil {
+		return nil, ErrRegisterDisabled
+	}
+	return s.store.Cancel(normalizeSerial(serial), strings.TrimSpace(reason))
+}
+
+func (s *service) Register(filter Filter) ([]Certificate, error) {
+	if s.store == nil {
+		return nil, ErrRegisterDisabled
+	}
+	return s.store.List(filter)
+}
+
+// student fetches a student with the caller's session, which is also how
+// access to the student's certificates is checked.
+func (s *service) student(ctx context.Context, id int, authCookies []*http.Cookie) (*models.Student, error) {
+	st, err := s.backend.GetStudentByID(ctx, id, authCookies)
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %d", ErrStudentNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// normalizeSerial accepts serials typed in lower case.
+func normalizeSerial(serial string) string {
+	return strings.ToUpper(strings.TrimSpace(serial))
+}
@@ -0,0 +1,243 @@
+package certificate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrCertificateNotFound = errors.New("certificate not found")
+	ErrAlreadyIssued       = errors.New("certificate already issued to the student")
+	ErrCancelled           = errors.New("certificate is cancelled")
+)
+
+var (
+	certificatesBucket = []byte("certificates")
+	// countersBucket holds the last number issued per type and academic
+	// year, keyed by "<type>/<year>".
+	countersBucket = []byte("certificate_counters")
+)
+
+// Certificate is the register entry of one issued certificate. The body is
+// kept as issued so that reprints read the same whatever changes later in
+// the student record or the template.
+type Certificate struct {
+	Serial       string            `json:"serial"`
+	Type         string            `json:"type"`
+	Title        string            `json:"title"`
+	AcademicYear string            `json:"academicYear"`
+	Number       int               `json:"number"`
+	StudentID    int               `json:"studentId"`
+	StudentName  string            `json:"studentName"`
+	Class        string            `json:"class"`
+	Section      string            `json:"section"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	Body         []string          `json:"body"`
+	IssuedAt     time.Time         `json:"issuedAt"`
+
+	// Prints counts the PDFs delivered; every print after the first is
+	// marked as a duplicate.
+	Prints        int        `json:"prints"`
+	LastPrintedAt *time.Time `json:"lastPrintedAt,omitempty"`
+
+	Cancelled    bool       `json:"cancelled"`
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+	CancelReason string     `json:"cancelReason,omitempty"`
+}
+
+// Filter selects register entries. Zero fields match everything.
+type Filter struct {
+	StudentID    int
+	Type         string
+	AcademicYear string
+}
+
+func (f Filter) matches(c *Certificate) bool {
+	return (f.StudentID == 0 || c.StudentID == f.StudentID) &&
+		(f.Type == "" || c.Type == f.Type) &&
+		(f.AcademicYear == "" || c.AcademicYear == f.AcademicYear)
+}
+
+// Store is the certificate register, kept in a bbolt database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the register database at path.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("certificate register: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("certificate register %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{certificatesBucket, countersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Issue assigns c the next number of its type and academic year and the
+// serial <prefix>-<year>-<number>, lets fill complete it and records it, all
+// in one transaction so that numbers are neither skipped nor reused. With
+// once set, a student holding an uncancelled certificate of the type gets
+// ErrAlreadyIssued.
+func (s *Store) Issue(c *Certificate, prefix string, once bool, fill func(*Certificate) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		certs := tx.Bucket(certificatesBucket)
+		if once {
+			err := certs.ForEach(func(_, data []byte) error {
+				var prev Certificate
+				if err := json.Unmarshal(data, &prev); err != nil {
+					return err
+				}
+				if prev.StudentID == c.StudentID && prev.Type == c.Type && !prev.Cancelled {
+					return fmt.Errorf("%w: %s", ErrAlreadyIssued, prev.Serial)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		counters := tx.Bucket(countersBucket)
+		key := []byte(c.Type + "/" + c.AcademicYear)
+		var n uint64
+		if v := counters.Get(key); v != nil {
+			n = binary.BigEndian.Uint64(v)
+		}
+		n++
+		c.Number = int(n)
+		c.Serial = fmt.Sprintf("%s-%s-%04d", prefix, c.AcademicYear, n)
+		if certs.Get([]byte(c.Serial)) != nil {
+			return fmt.Errorf("certificate serial %s already recorded", c.Serial)
+		}
+		if err := fill(c); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if err := counters.Put(key, binary.BigEndian.AppendUint64(nil, n)); err != nil {
+			return err
+		}
+		return certs.Put([]byte(c.Serial), data)
+	})
+}
+
+// Get returns the certificate with serial.
+func (s *Store) Get(serial string) (*Certificate, error) {
+	var c Certificate
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(certificatesBucket).Get([]byte(serial))
+		if data == nil {
+			return ErrCertificateNotFound
+		}
+		return json.Unmarshal(data, &c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns the certificates matching f, oldest first.
+func (s *Store) List(f Filter) ([]Certificate, error) {
+	list := []Certificate{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(certificatesBucket).ForEach(func(_, data []byte) error {
+			var c Certificate
+			if err := json.Unmarshal(data, &c); err != nil {
+				return err
+			}
+			if f.matches(&c) {
+				list = append(list, c)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].IssuedAt.Before(list[j].IssuedAt) })
+	return list, nil
+}
+
+// Printed counts a print of serial. Cancelled certificates are not printed.
+func (s *Store) Printed(serial string) error {
+	return s.update(serial, func(c *Certificate) error {
+		if c.Cancelled {
+			return ErrCancelled
+		}
+		now := time.Now().UTC()
+		c.Prints++
+		c.LastPrintedAt = &now
+		return nil
+	})
+}
+
+// Cancel marks serial as cancelled and returns the updated certificate. Its
+// number is not reused.
+func (s *Store) Cancel(serial, reason string) (*Certificate, error) {
+	var after Certificate
+	err := s.update(serial, func(c *Certificate) error {
+		if c.Cancelled {
+			return ErrCancelled
+		}
+		now := time.Now().UTC()
+		c.Cancelled, c.CancelledAt, c.CancelReason = true, &now, reason
+		after = *c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+func (s *Store) update(serial string, change func(*Certificate) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(certificatesBucket)
+		data := b.Get([]byte(serial))
+		if data == nil {
+			return ErrCertificateNotFound
+		}
+		var c Certificate
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		if err := change(&c); err != nil {
+			return err
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(serial), data)
+	})
+}
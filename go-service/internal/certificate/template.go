@@ -0,0 +1,342 @@
+package certificate
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownType is returned for certificate types without a template.
+var ErrUnknownType = errors.New("unknown certificate type")
+
+const (
+	// dateLayout is how dates are written in certificate text.
+	dateLayout = "02 January 2006"
+	// maxFieldLength bounds values typed in by the issuing office.
+	maxFieldLength = 500
+)
+
+var (
+	typePattern   = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	prefixPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+	// paragraphBreak separates the paragraphs of a rendered body.
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+)
+
+//go:embed templates/*.yaml
+var builtinFS embed.FS
+
+// Field is a value the issuing office supplies with a certificate, such as
+// the date of leaving on a transfer certificate.
+type Field struct {
+	Name     string `yaml:"name" json:"name"`
+	Label    string `yaml:"label" json:"label"`
+	Required bool   `yaml:"required" json:"required"`
+	// Date fields take YYYY-MM-DD values.
+	Date    bool   `yaml:"date" json:"date,omitempty"`
+	Default string `yaml:"default" json:"default,omitempty"`
+}
+
+// Template is a certificate type: its title, serial prefix, the fields the
+// office fills in and the body, a Go template over Data whose paragraphs
+// are separated by blank lines.
+type Template struct {
+	Type   string `yaml:"type" json:"type"`
+	Title  string `yaml:"title" json:"title"`
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Once allows a single certificate of this type per student until it is
+	// cancelled.
+	Once   bool    `yaml:"once" json:"once,omitempty"`
+	Fields []Field `yaml:"fields" json:"fields"`
+	Body   string  `yaml:"body" json:"-"`
+
+	body *template.Template
+}
+
+// Data is what certificate bodies are executed with.
+type Data struct {
+	Student      *models.Student
+	Fields       map[string]string
+	School       string
+	Serial       string
+	AcademicYear string
+	IssuedOn     time.Time
+}
+
+var funcs = template.FuncMap{
+	"date":       formatDate,
+	"title":      title,
+	"parents":    parents,
+	"relation":   func(st *models.Student) string { return byGender(st, "son", "daughter", "child") },
+	"pronoun":    func(st *models.Student) string { return byGender(st, "he", "she", st.Name) },
+	"objective":  func(st *models.Student) string { return byGender(st, "him", "her", st.Name) },
+	"possessive": func(st *models.Student) string { return byGender(st, "his", "her", st.Name+"'s") },
+}
+
+// ParseTemplate decodes and validates a YAML certificate template.
+func ParseTemplate(name string, data []byte) (*Template, error) {
+	var t Template
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("certificate template %s: %v", name, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("certificate template %s: %v", name, err)
+	}
+	return &t, nil
+}
+
+func (t *Template) validate() error {
+	if !typePattern.MatchString(t.Type) {
+		return fmt.Errorf("type %q must be lower case letters, digits and dashes", t.Type)
+	}
+	if t.Title == "" {
+		return errors.New("title is required")
+	}
+	if !prefixPattern.MatchString(t.Prefix) {
+		return fmt.Errorf("prefix %q must be upper case letters and digits", t.Prefix)
+	}
+	seen := map[string]bool{}
+	for _, f := range t.Fields {
+		if f.Name == "" || seen[f.Name] {
+			return fmt.Errorf("field names must be set and unique, got %q", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Date && f.Default != "" {
+			if _, err := time.Parse(time.DateOnly, f.Default); err != nil {
+				return fmt.Errorf("field %s: default must be YYYY-MM-DD", f.Name)
+			}
+		}
+	}
+	body, err := template.New(t.Type).Funcs(funcs).Option("missingkey=zero").Parse(t.Body)
+	if err != nil {
+		return err
+	}
+	t.body = body
+	// Executing with an empty student catches references to unknown student
+	// fields at startup rather than when a certificate is issued.
+	if _, err := t.render(Data{Student: &models.Student{}, Fields: t.sample()}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sample returns plausible values of every field.
+func (t *Template) sample() map[string]string {
+	values := map[string]string{}
+	for _, f := range t.Fields {
+		values[f.Name] = "x"
+		if f.Date {
+			values[f.Name] = "2000-01-01"
+		}
+	}
+	return values
+}
+
+// Values checks the fields supplied for a certificate against the template
+// and fills in defaults. Unknown fields are rejected so that typos do not
+// silently disappear from the certificate.
+func (t *Template) Values(in map[string]string) (map[string]string, error) {
+	known := map[string]bool{}
+	values := map[string]string{}
+	for _, f := range t.Fields {
+		known[f.Name] = true
+		v := strings.TrimSpace(in[f.Name])
+		if v == "" {
+			v = f.Default
+		}
+		switch {
+		case v == "" && f.Required:
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidFields, f.Name)
+		case len(v) > maxFieldLength:
+			return nil, fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidFields, f.Name, maxFieldLength)
+		case v != "" && f.Date:
+			if _, err := time.Parse(time.DateOnly, v); err != nil {
+				return nil, fmt.Errorf("%w: %s must be YYYY-MM-DD", ErrInvalidFields, f.Name)
+			}
+		}
+		if v != "" {
+			values[f.Name] = v
+		}
+	}
+	for name := range in {
+		if !known[name] {
+			return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidFields, name)
+		}
+	}
+	return values, nil
+}
+
+// render executes the body and returns its non-empty paragraphs.
+func (t *Template) render(data Data) ([]string, error) {
+	var buf bytes.Buffer
+	if err := t.body.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	var paragraphs []string
+	for _, p := range paragraphBreak.Split(buf.String(), -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs, nil
+}
+
+// Registry holds the certificate types by name.
+type Registry struct {
+	templates map[string]*Template
+}
+
+// LoadTemplates returns the built-in certificate types plus every *.yaml and
+// *.yml file in dir, which override built-in types of the same name. An
+// empty dir only loads the built-ins. Two files of dir defining the same type,
+// or two types sharing a serial prefix, are an error.
+func LoadTemplates(dir string) (*Registry, error) {
+	reg := &Registry{templates: map[string]*Template{}}
+	builtins := map[string]string{}
+	err := fs.WalkDir(builtinFS, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return reg.load(builtinFS.ReadFile, path, builtins)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return reg, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate template dir: %v", err)
+	}
+	custom := map[string]string{}
+	for _, e := range entries {
+		if ext := filepath.Ext(e.Name()); e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		if err := reg.load(os.ReadFile, filepath.Join(dir, e.Name()), custom); err != nil {
+			return nil, err
+		}
+	}
+	if err := reg.checkPrefixes(); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// load adds the template in path. files maps the types loaded so far from the
+// same source to their file, a type may only be defined once per source.
+func (r *Registry) load(read func(string) ([]byte, error), path string, files map[string]string) error {
+	data, err := read(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	t, err := ParseTemplate(name, data)
+	if err != nil {
+		return err
+	}
+	if other, ok := files[t.Type]; ok {
+		return fmt.Errorf("certificate template %s: type %q is already defined in %s", name, t.Type, other)
+	}
+	files[t.Type] = name
+	r.templates[t.Type] = t
+	return nil
+}
+
+// checkPrefixes makes sure serials tell the certificate types apart.
+func (r *Registry) checkPrefixes() error {
+	types := map[string]string{}
+	for _, t := range r.Types() {
+		if other, ok := types[t.Prefix]; ok {
+			return fmt.Errorf("certificate types %q and %q share the prefix %s", other, t.Type, t.Prefix)
+		}
+		types[t.Prefix] = t.Type
+	}
+	return nil
+}
+
+// Lookup returns the template of a certificate type.
+func (r *Registry) Lookup(typ string) (*Template, error) {
+	t, ok := r.templates[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, typ)
+	}
+	return t, nil
+}
+
+// Types lists the certificate types by name.
+func (r *Registry) Types() []*Template {
+	list := make([]*Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+// formatDate writes a time or a YYYY-MM-DD field value as a date.
+func formatDate(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return report.BackendDay(v).Format(dateLayout)
+	case string:
+		if d, err := time.Parse(time.DateOnly, v); err == nil {
+			return d.Format(dateLayout)
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// parents names the father and mother, or the guardian when neither is
+// recorded.
+func parents(st *models.Student) string {
+	switch {
+	case st.FatherName != "" && st.MotherName != "":
+		return st.FatherName + " and " + st.MotherName
+	case st.FatherName != "":
+		return st.FatherName
+	case st.MotherName != "":
+		return st.MotherName
+	}
+	return st.GuardianName
+}
+
+// byGender picks the word matching the student's recorded gender, or other
+// when none is recorded.
+func byGender(st *models.Student, male, female, other string) string {
+	switch strings.ToLower(st.Gender) {
+	case "male":
+		return male
+	case "female":
+		return female
+	}
+	return other
+}
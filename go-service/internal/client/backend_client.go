@@ -3,10 +3,13 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"goservice/internal/models"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,6 +28,27 @@ type BackendClient struct {
 type IBackend interface {
 	Login(ctx context.Context, username, password string) ([]*http.Cookie, error)
 	GetStudentByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Student, error)
+	ListStudents(ctx context.Context, filter models.StudentFilter, rawCookies []*http.Cookie) ([]models.Student, error)
+	GetStaffByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Staff, error)
+	ListLeaves(ctx context.Context, filter models.LeaveFilter, rawCookies []*http.Cookie) ([]models.Leave, error)
+	ListNotices(ctx context.Context, rawCookies []*http.Cookie) ([]models.Notice, error)
+	GetNoticeByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Notice, error)
+	GetDashboard(ctx context.Context, rawCookies []*http.Cookie) (*models.Dashboard, error)
+	ListRoles(ctx context.Context, rawCookies []*http.Cookie) ([]models.Role, error)
+	ListRolePermissions(ctx context.Context, id int, rawCookies []*http.Cookie) ([]models.Permission, error)
+	ListRoleUsers(ctx context.Context, id int, rawCookies []*http.Cookie) ([]models.RoleUser, error)
+}
+
+// StatusError is returned when the backend answers with a status other than
+// 200 OK.
+type StatusError struct {
+	Resource string
+	Status   int
+	Body     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to get %s: %s", e.Resource, e.Body)
 }
 
 func NewBackendClient(baseURL string) IBackend {
@@ -71,6 +95,192 @@ func (b *BackendClient) Login(ctx context.Context, username, password string) ([
 
 func (b *BackendClient) GetStudentByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Student, error) {
 	url := fmt.Sprintf("%s/api/v1/students/%d", b.BaseURL, id)
+
+	var student models.Student
+	if err := b.getJSON(ctx, url, rawCookies, "student", &student); err != nil {
+		return nil, err
+	}
+
+	return &student, nil
+}
+
+func (b *BackendClient) ListStudents(ctx context.Context, filter models.StudentFilter, rawCookies []*http.Cookie) ([]models.Student, error) {
+	query := url.Values{}
+	if filter.Name != "" {
+		query.Set("name", filter.Name)
+	}
+	if filter.ClassName != "" {
+		query.Set("className", filter.ClassName)
+	}
+	if filter.Section != "" {
+		query.Set("section", filter.Section)
+	}
+	if filter.Roll != 0 {
+		query.Set("roll", strconv.Itoa(filter.Roll))
+	}
+
+	listURL := fmt.Sprintf("%s/api/v1/students", b.BaseURL)
+	if len(query) > 0 {
+		listURL += "?" + query.Encode()
+	}
+
+	var out struct {
+		Students []models.Student `json:"students"`
+	}
+	if err := b.getJSON(ctx, listURL, rawCookies, "students", &out); err != nil {
+		return nil, err
+	}
+
+	return out.Students, nil
+}
+
+func (b *BackendClient) GetStaffByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Staff, error) {
+	url := fmt.Sprintf("%s/api/v1/staffs/%d", b.BaseURL, id)
+
+	var staff models.Staff
+	if err := b.getJSON(ctx, url, rawCookies, "staff", &staff); err != nil {
+		return nil, err
+	}
+
+	return &staff, nil
+}
+
+// ListLeaves returns the leave requests matching filter. The backend answers
+// 404 when there are none, which is returned as an empty list.
+func (b *BackendClient) ListLeaves(ctx context.Context, filter models.LeaveFilter, rawCookies []*http.Cookie) ([]models.Leave, error) {
+	query := url.Values{}
+	if filter.UserID != 0 {
+		query.Set("userId", strconv.Itoa(filter.UserID))
+	}
+	if filter.DepartmentID != 0 {
+		query.Set("departmentId", strconv.Itoa(filter.DepartmentID))
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.DateOnly))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.DateOnly))
+	}
+
+	listURL := fmt.Sprintf("%s/api/v1/leave/request", b.BaseURL)
+	if len(query) > 0 {
+		listURL += "?" + query.Encode()
+	}
+
+	var out struct {
+		LeaveHistory []models.Leave `json:"leaveHistory"`
+	}
+	var statusErr *StatusError
+	if err := b.getJSON(ctx, listURL, rawCookies, "leave history", &out); errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return []models.Leave{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return out.LeaveHistory, nil
+}
+
+// ListNotices returns the notices the caller may see. The backend answers
+// 404 when there are none, which is returned as an empty list.
+func (b *BackendClient) ListNotices(ctx context.Context, rawCookies []*http.Cookie) ([]models.Notice, error) {
+	listURL := fmt.Sprintf("%s/api/v1/notices", b.BaseURL)
+
+	var out struct {
+		Notices []models.Notice `json:"notices"`
+	}
+	var statusErr *StatusError
+	if err := b.getJSON(ctx, listURL, rawCookies, "notices", &out); errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return []models.Notice{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return out.Notices, nil
+}
+
+func (b *BackendClient) GetNoticeByID(ctx context.Context, id int, rawCookies []*http.Cookie) (*models.Notice, error) {
+	url := fmt.Sprintf("%s/api/v1/notices/%d", b.BaseURL, id)
+
+	// The detail names the status id "status".
+	var out struct {
+		models.Notice
+		StatusID int `json:"status"`
+	}
+	if err := b.getJSON(ctx, url, rawCookies, "notice", &out); err != nil {
+		return nil, err
+	}
+	out.Notice.StatusID = out.StatusID
+
+	return &out.Notice, nil
+}
+
+// GetDashboard returns the caller's dashboard: head counts, latest notices
+// and leave, leave policy usage, celebrations and who is out.
+func (b *BackendClient) GetDashboard(ctx context.Context, rawCookies []*http.Cookie) (*models.Dashboard, error) {
+	url := fmt.Sprintf("%s/api/v1/dashboard", b.BaseURL)
+
+	var dashboard models.Dashboard
+	if err := b.getJSON(ctx, url, rawCookies, "dashboard", &dashboard); err != nil {
+		return nil, err
+	}
+
+	return &dashboard, nil
+}
+
+func (b *BackendClient) ListRoles(ctx context.Context, rawCookies []*http.Cookie) ([]models.Role, error) {
+	listURL := fmt.Sprintf("%s/api/v1/roles", b.BaseURL)
+
+	var out struct {
+		Roles []models.Role `json:"roles"`
+	}
+	if err := b.getJSON(ctx, listURL, rawCookies, "roles", &out); err != nil {
+		return nil, err
+	}
+
+	return out.Roles, nil
+}
+
+// ListRolePermissions returns the access controls granted to role id. The
+// backend answers 404 when there are none, which is returned as an empty
+// list.
+func (b *BackendClient) ListRolePermissions(ctx context.Context, id int, rawCookies []*http.Cookie) ([]models.Permission, error) {
+	listURL := fmt.Sprintf("%s/api/v1/roles/%d/permissions", b.BaseURL, id)
+
+	var out struct {
+		Permissions []models.Permission `json:"permissions"`
+	}
+	var statusErr *StatusError
+	if err := b.getJSON(ctx, listURL, rawCookies, "role permissions", &out); errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return []models.Permission{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return out.Permissions, nil
+}
+
+// ListRoleUsers returns the users holding role id. The backend answers 404
+// when there are none, which is returned as an empty list.
+func (b *BackendClient) ListRoleUsers(ctx context.Context, id int, rawCookies []*http.Cookie) ([]models.RoleUser, error) {
+	listURL := fmt.Sprintf("%s/api/v1/roles/%d/users", b.BaseURL, id)
+
+	var out struct {
+		Users []models.RoleUser `json:"users"`
+	}
+	var statusErr *StatusError
+	if err := b.getJSON(ctx, listURL, rawCookies, "role users", &out); errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return []models.RoleUser{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return out.Users, nil
+}
+
+// getJSON performs an authenticated GET against the backend, forwarding the
+// caller's cookies and CSRF token, and decodes the JSON body into out. The
+// resource name is only used to build error messages.
+func (b *BackendClient) getJSON(ctx context.Context, url string, rawCookies []*http.Cookie, resource string, out any) error {
 	var csrfToken string
 
 	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -84,19 +294,18 @@ func (b *BackendClient) GetStudentByID(ctx context.Context, id int, rawCookies [
 
 	resp, err := b.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch student: %v", err)
+		return fmt.Errorf("failed to fetch %s: %v", resource, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get student: %s", string(body))
+		return &StatusError{Resource: resource, Status: resp.StatusCode, Body: string(body)}
 	}
 
-	var student models.Student
-	if err := json.NewDecoder(resp.Body).Decode(&student); err != nil {
-		return nil, fmt.Errorf("failed to decode student: %v", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", resource, err)
 	}
 
-	return &student, nil
+	return nil
 }
@@ -168,3 +168,241 @@ func TestBackendClient_GetStudentByID_BadJSON(t *testing.T) {
 		t.Errorf("expected nil student, got %+v", got)
 	}
 }
+
+func TestBackendClient_ListStudents_Success(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/students" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("className"); got != "10" {
+			t.Errorf("expected className=10, got %q", got)
+		}
+		if got := r.URL.Query().Get("section"); got != "A" {
+			t.Errorf("expected section=A, got %q", got)
+		}
+		if got := r.URL.Query().Get("roll"); got != "7" {
+			t.Errorf("expected roll=7, got %q", got)
+		}
+		if got := r.URL.Query().Get("name"); got != "Ann Lee" {
+			t.Errorf("expected name=Ann Lee, got %q", got)
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"students": []*models.Student{sampleStudent()}})
+	}))
+	defer ts.Close()
+
+	client := NewBackendClient(ts.URL)
+	cookie := &http.Cookie{Name: CSFRTokenName, Value: "csrf123"}
+	got, err := client.ListStudents(context.Background(), models.StudentFilter{Name: "Ann Lee", ClassName: "10", Section: "A", Roll: 7}, []*http.Cookie{cookie})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("unexpected students: %+v", got)
+	}
+}
+
+func TestBackendClient_GetStaffByID_Success(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/staffs/4" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-csrf-token") != "csrf123" {
+			t.Errorf("expected CSRF token header, got %s", r.Header.Get("x-csrf-token"))
+		}
+		// Shaped like the backend's answer, with the nulls of an incomplete profile.
+		io.WriteString(w, `{"id":4,"name":"Dana","systemAccess":true,"role":2,"roleName":"Teacher",
+			"department":"Science","reporterId":null,"reporterName":null,
+			"joinDate":"2019-04-01T00:00:00.000Z","dob":null,"phone":"5551234"}`)
+	}))
+	defer ts.Close()
+
+	client := NewBackendClient(ts.URL)
+	cookie := &http.Cookie{Name: CSFRTokenName, Value: "csrf123"}
+	got, err := client.GetStaffByID(context.Background(), 4, []*http.Cookie{cookie})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.Name != "Dana" || got.RoleName != "Teacher" || got.Department != "Science" {
+		t.Errorf("unexpected staff %+v", got)
+	}
+	if !got.JoinDate.Equal(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)) || !got.DOB.IsZero() {
+		t.Errorf("unexpected dates %v, %v", got.JoinDate, got.DOB)
+	}
+}
+
+func TestBackendClient_GetStaffByID_Failure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Staff detail not found"}`))
+	}))
+	defer ts.Close()
+
+	client := NewBackendClient(ts.URL)
+	got, err := client.GetStaffByID(context.Background(), 9, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to get staff") {
+		t.Fatalf("expected a staff error, got %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected nil staff, got %+v", got)
+	}
+}
+
+func TestBackendClient_ListLeaves(t *testing.T) {
+	var gotQuery string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/leave/request" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		gotQuery = r.URL.RawQuery
+		if r.URL.Query().Get("userId") == "9" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":"Leaves not found"}`)
+			return
+		}
+		io.WriteString(w, `{"leaveHistory":[{"id":1,"userId":4,"user":"Dana","policy":"Sick",
+			"from":"2024-03-04T00:00:00.000Z","to":"2024-03-06T00:00:00.000Z","statusId":2,
+			"status":"Approved","approver":null,"approved":null,"days":"3"}]}`)
+	}))
+	defer ts.Close()
+
+	client := NewBackendClient(ts.URL)
+	filter := models.LeaveFilter{
+		UserID: 4,
+		From:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	leaves, err := client.ListLeaves(context.Background(), filter, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotQuery != "from=2024-01-01&to=2024-12-31&userId=4" {
+		t.Errorf("unexpected query %q", gotQuery)
+	}
+	if len(leaves) != 1 || leaves[0].Policy != "Sick" || leaves[0].StatusID != models.LeaveApproved {
+		t.Errorf("unexpected leaves %+v", leaves)
+	}
+
+	leaves, err = client.ListLeaves(context.Background(), models.LeaveFilter{UserID: 9}, nil)
+	if err != nil || leaves == nil || len(leaves) != 0 {
+		t.Errorf("expected an empty list for a 404, got %v, %v", leaves, err)
+	}
+}
+
+func TestBackendClient_Notices(t *testing.T) {
+	empty := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/notices":
+			if empty {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"error":"Notices not found"}`)
+				return
+			}
+			io.WriteString(w, `{"notices":[{"id":3,"title":"Sports day","description":"All classes",
+				"authorId":1,"author":"John","createdDate":"2024-05-01T08:00:00.000Z","updatedDate":null,
+				"reviewerName":"John","reviewedDate":"2024-05-02T09:00:00.000Z","status":"Approved",
+				"statusId":5,"whoHasAccess":null}]}`)
+		case "/api/v1/notices/3":
+			io.WriteString(w, `{"id":3,"title":"Sports day","description":"All classes","status":5,
+				"authorId":1,"createdDate":"2024-05-01T08:00:00.000Z","updatedDate":null,
+				"recipientType":"SP","recipientRole":3,"firstField":"Grade 5","author":"John"}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewBackendClient(ts.URL)
+	notices, err := client.ListNotices(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notices) != 1 || notices[0].StatusID != models.NoticeApproved || notices[0].ReviewedDate == nil {
+		t.Errorf("unexpected notices %+v", notices)
+	}
+
+	notice, err := client.GetNoticeByID(context.Background(), 3, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if notice.StatusID != models.NoticeApproved || notice.RecipientType != models.NoticeForSpecific ||
+		notice.RecipientRole != 3 || notice.FirstField != "Grade 5" {
+		t.Errorf("unexpected notice %+v", notice)
+	}
+
+	empty = true
+	notices, err = client.ListNotices(context.Background(), nil)
+	if err != nil || notices == nil || len(notices) != 0 {
+		t.Errorf("expected an empty list for a 404, got %v, %v", notices, err)
+	}
+}
+
+func TestBackendClient_GetDashboard(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/dashboard" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		// Dates are written by PostgreSQL's JSON functions, without a zone.
+		io.WriteString(w, `{"students":{"totalNumberCurrentYear":12,"totalNumberPercInComparisonFromPrevYear":50,
+			"totalNumberValueInComparisonFromPrevYear":4},
+			"notices":[{"id":1,"title":"Exams","createdDate":"2024-05-01T08:30:15.123456","reviewedDate":null}],
+			"leavePolicies":[{"id":1,"name":"Sick","totalDaysUsed":3}],
+			"celebrations":[{"userId":2,"user":"Ann","event":"Happy Birthday!","eventDate":"2010-05-04"}],
+			"oneMonthLeave":[{"userId":3,"user":"Bob","fromDate":"2024-05-06","toDate":"2024-05-08","leaveType":"Sick"}]}`)
+	}))
+	defer ts.Close()
+
+	dashboard, err := NewBackendClient(ts.URL).GetDashboard(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if dashboard.Students.CurrentYear != 12 || dashboard.Students.PreviousYear() != 8 {
+		t.Errorf("unexpected head count %+v", dashboard.Students)
+	}
+	if got := dashboard.Notices[0].CreatedDate.Format(time.DateTime); got != "2024-05-01 08:30:15" || !dashboard.Notices[0].ReviewedDate.IsZero() {
+		t.Errorf("unexpected notice dates %v, %v", got, dashboard.Notices[0].ReviewedDate)
+	}
+	if got := dashboard.OneMonthLeave[0].ToDate.Format(time.DateOnly); got != "2024-05-08" {
+		t.Errorf("unexpected absence end %s", got)
+	}
+}
+
+func TestBackendClient_Roles(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/roles":
+			io.WriteString(w, `{"roles":[{"id":1,"name":"Admin","usersAssociated":"1","status":true},{"id":3,"name":"Student","usersAssociated":"0","status":false}]}`)
+		case "/api/v1/roles/1/permissions":
+			io.WriteString(w, `{"permissions":[{"id":1,"name":"Get all roles"}]}`)
+		case "/api/v1/roles/1/users":
+			io.WriteString(w, `{"users":[{"id":1,"name":"John","lastLogin":"2024-05-01T08:30:15.000Z"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error":"Users not found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewBackendClient(ts.URL)
+	roles, err := c.ListRoles(context.Background(), nil)
+	if err != nil || len(roles) != 2 || roles[0].Name != "Admin" || !roles[0].Active || roles[1].Active {
+		t.Fatalf("unexpected roles %+v, %v", roles, err)
+	}
+	perms, err := c.ListRolePermissions(context.Background(), 1, nil)
+	if err != nil || len(perms) != 1 || perms[0].Name != "Get all roles" {
+		t.Errorf("unexpected permissions %+v, %v", perms, err)
+	}
+	users, err := c.ListRoleUsers(context.Background(), 1, nil)
+	if err != nil || len(users) != 1 || users[0].LastLogin == nil {
+		t.Errorf("unexpected users %+v, %v", users, err)
+	}
+
+	// Roles without permissions or users are answered with 404.
+	if perms, err := c.ListRolePermissions(context.Background(), 3, nil); err != nil || perms == nil || len(perms) != 0 {
+		t.Errorf("expected no permissions, got %+v, %v", perms, err)
+	}
+	if users, err := c.ListRoleUsers(context.Background(), 3, nil); err != nil || users == nil || len(users) != 0 {
+		t.Errorf("expected no users, got %+v, %v", users, err)
+	}
+}
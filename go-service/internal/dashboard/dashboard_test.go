@@ -0,0 +1,182 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) models.DBTime {
+	t, _ := time.Parse(time.DateOnly, s)
+	return models.DBTime{Time: t}
+}
+
+// sampleDashboard is the dashboard of an admin in the week starting Monday
+// 13 October 2025.
+func sampleDashboard() *models.Dashboard {
+	return &models.Dashboard{
+		Students: models.HeadCount{CurrentYear: 12, Change: 4, ChangePercent: 50},
+		Teachers: models.HeadCount{CurrentYear: 3, Change: -1, ChangePercent: -25},
+		Notices:  []models.DashboardNotice{{ID: 1, Title: "Sports day", Author: "John", CreatedDate: date("2025-10-10")}},
+		LeavePolicies: []models.LeavePolicyUsage{
+			{ID: 1, Name: "Sick leave", TotalDaysUsed: 4},
+			{ID: 2, Name: "Casual leave", TotalDaysUsed: 2.5},
+		},
+		LeaveHistory: []models.DashboardLeave{{ID: 1, User: "Ann", Policy: "Sick leave", From: date("2025-10-14"), To: date("2025-10-15"), Status: "Approved"}},
+		Celebrations: []models.Celebration{
+			{UserID: 2, User: "Ann", Event: "Happy Birthday!", EventDate: date("2010-10-19")},
+			{UserID: 3, User: "Bob", Event: "Happy 2 Anniversary!", EventDate: date("2023-10-14")},
+			{UserID: 4, User: "Cy", Event: "Happy Birthday!", EventDate: date("2011-11-30")},
+		},
+		OneMonthLeave: []models.Absence{
+			{UserID: 5, User: "Dee", FromDate: date("2025-10-10"), ToDate: date("2025-10-14"), LeaveType: "Sick leave"},
+			{UserID: 6, User: "Eve", FromDate: date("2025-10-14"), ToDate: date("2025-10-14"), LeaveType: "Casual leave"},
+			{UserID: 7, User: "Fay", FromDate: date("2025-10-20"), ToDate: date("2025-10-22"), LeaveType: "Casual leave"},
+		},
+	}
+}
+
+type fakeBackend struct {
+	client.IBackend
+	dashboard *models.Dashboard
+	err       error
+}
+
+func (f *fakeBackend) GetDashboard(ctx context.Context, cookies []*http.Cookie) (*models.Dashboard, error) {
+	return f.dashboard, f.err
+}
+
+func TestParseWeek(t *testing.T) {
+	now := time.Date(2025, 10, 15, 18, 30, 0, 0, time.Local)
+	week, err := ParseWeek("", now)
+	if err != nil || week.From.Format(time.DateOnly) != "2025-10-15" || week.To.Format(time.DateOnly) != "2025-10-21" {
+		t.Errorf("expected the week to start today, got %+v, %v", week, err)
+	}
+	if week, _ = ParseWeek("2025-12-29", now); week.To.Format(time.DateOnly) != "2026-01-04" {
+		t.Errorf("unexpected week %+v", week)
+	}
+	if _, err := ParseWeek("next week", now); !errors.Is(err, ErrInvalidWeek) {
+		t.Errorf("expected ErrInvalidWeek, got %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	week, _ := ParseWeek("2025-10-13", time.Now())
+	s := Summarize(sampleDashboard(), week)
+
+	var events []string
+	for _, e := range s.Events {
+		events = append(events, e.User+" "+e.On.Format("01-02"))
+	}
+	if got := strings.Join(events, ","); got != "Bob 10-14,Ann 10-19" {
+		t.Errorf("expected the week's celebrations in date order, got %s", got)
+	}
+	if len(s.Absences) != 2 || s.Absences[0].User != "Dee" {
+		t.Errorf("expected the absences overlapping the week, got %+v", s.Absences)
+	}
+	if got := fmt.Sprint(s.OutPerDay); got != "[1 2 0 0 0 0 0]" {
+		t.Errorf("unexpected absences per day %s", got)
+	}
+
+	// A week across new year finds anniversaries in either year.
+	week, _ = ParseWeek("2025-12-29", time.Now())
+	d := &models.Dashboard{Celebrations: []models.Celebration{{User: "Gus", EventDate: date("2012-01-02")}}}
+	if s := Summarize(d, week); len(s.Events) != 1 || s.Events[0].On.Year() != 2026 {
+		t.Errorf("expected the birthday in the new year, got %+v", s.Events)
+	}
+}
+
+func TestRenderPDF_OnePage(t *testing.T) {
+	fonts, _ := report.LoadFonts(nil)
+	brand, err := report.LoadBrand(report.Branding{SchoolName: "Springfield School", Address: "1 Main St", FooterText: "Springfield", ConfidentialityNotice: "Confidential"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	week, _ := ParseWeek("2025-10-13", time.Now())
+
+	// Long lists are cut rather than spilling onto a second page.
+	d := sampleDashboard()
+	for i := range 30 {
+		d.OneMonthLeave = append(d.OneMonthLeave, models.Absence{User: fmt.Sprintf("User %d", i), FromDate: date("2025-10-13"), ToDate: date("2025-10-19"), LeaveType: "Sick leave"})
+		d.Celebrations = append(d.Celebrations, models.Celebration{User: fmt.Sprintf("User %d", i), Event: "Happy Birthday!", EventDate: date("2000-10-15")})
+		d.LeavePolicies = append(d.LeavePolicies, models.LeavePolicyUsage{Name: fmt.Sprintf("Policy %d", i), TotalDaysUsed: float64(i)})
+		d.LeaveHistory = append(d.LeaveHistory, d.LeaveHistory[0])
+		d.Notices = append(d.Notices, d.Notices[0])
+	}
+	for _, dashboard := range []*models.Dashboard{sampleDashboard(), d, {}} {
+		pdf := renderPDF(Summarize(dashboard, week), fonts, brand)
+		if pdf.Err() {
+			t.Fatal(pdf.Error())
+		}
+		if pdf.PageNo() != 1 {
+			t.Errorf("expected one page, got %d", pdf.PageNo())
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	routes := NewHandler(NewService(&fakeBackend{dashboard: sampleDashboard()}, nil, nil, nil)).Routes()
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+		prefix string
+	}{
+		{"pdf", "/?week=2025-10-13", http.StatusOK, "%PDF"},
+		{"html", "/?week=2025-10-13&format=html", http.StatusOK, "<!DOCTYPE html>"},
+		{"csv", "/?format=csv", http.StatusBadRequest, ""},
+		{"bad week", "/?week=monday", http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+			continue
+		}
+		if tc.prefix != "" && !strings.HasPrefix(rec.Body.String(), tc.prefix) {
+			t.Errorf("%s: unexpected body %.40q", tc.name, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/?week=2025-10-13&format=html", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != "inline; filename=dashboard_20251013.html" {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Week of 13 Oct 2025 to 19 Oct 2025", "Mon 13 Oct", "Bob Happy 2 Anniversary!", "Dee - Sick leave, 10 Oct - 14 Oct 2025", "background:#3c3c3c"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the HTML summary", want)
+		}
+	}
+
+	// Accept naming a format the summary lacks falls back to the PDF.
+	req := testutil.AuthedRequest(http.MethodGet, "/?week=2025-10-13", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("expected the PDF, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	failing := NewHandler(NewService(&fakeBackend{err: &client.StatusError{Resource: "dashboard", Status: http.StatusBadGateway}}, nil, nil, nil)).Routes()
+	rec = httptest.NewRecorder()
+	failing.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/", nil))
+	var out struct {
+		Error string `json:"error"`
+	}
+	if rec.Code != http.StatusInternalServerError || json.Unmarshal(rec.Body.Bytes(), &out) != nil || out.Error == "" {
+		t.Errorf("expected a backend failure to be reported, got %d: %s", rec.Code, rec.Body)
+	}
+}
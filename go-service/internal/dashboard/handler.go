@@ -0,0 +1,83 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves the weekly summary, mounted under /api/v1/reports/dashboard.
+// It shows the dashboard of the caller, so head counts are only filled for
+// admins.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.Summary)
+	return r
+}
+
+// errorStatus maps dashboard errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidWeek), errors.Is(err, report.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Summary reads the first day of the week from ?week= and the format from
+// ?format= or the Accept header. HTML is served inline for email clients.
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	q := r.URL.Query()
+	week, err := ParseWeek(q.Get("week"), time.Now())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	format, err := report.NegotiateFormat(q.Get("format"), r.Header.Get("Accept"), report.FormatPDF, report.FormatHTML)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateSummary(r.Context(), Options{Week: week, Format: format}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	disposition := "attachment"
+	if format == report.FormatHTML {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fmt.Sprintf("dashboard_%s.%s", week.From.Format("20060102"), format.Extension())}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,165 @@
+package dashboard
+
+import (
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"html/template"
+	"io"
+	"time"
+)
+
+// summaryHTML is written for email clients: tables and inline styles only,
+// with bars drawn as coloured table cells.
+var summaryHTML = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"day":    func(t time.Time) string { return t.Format("Mon 02 Jan") },
+	"period": period,
+	"number": number,
+	"change": change,
+	"width":  barWidth,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Weekly Summary</title>
+</head>
+<body style="margin:0;padding:16px;background:#f4f4f4;font-family:Arial,Helvetica,sans-serif;color:#222;">
+<table role="presentation" width="640" align="center" cellpadding="0" cellspacing="0" style="background:#fff;border-collapse:collapse;">
+<tr><td style="padding:16px 24px;background:{{.Accent}};color:#fff;">
+{{with .School}}<div style="font-size:13px;">{{.}}</div>{{end}}
+<div style="font-size:22px;font-weight:bold;">Weekly Summary</div>
+<div style="font-size:13px;">Week of {{.Summary.Week.From.Format "02 Jan 2006"}} to {{.Summary.Week.To.Format "02 Jan 2006"}}</div>
+</td></tr>
+
+<tr><td style="padding:16px 24px;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="8">
+<tr>{{range .Counts}}
+<td width="33%" style="border:1px solid {{$.Accent}};text-align:center;padding:8px;">
+<div style="font-size:24px;font-weight:bold;color:{{$.Accent}};">{{.Count.CurrentYear}}</div>
+<div style="font-size:13px;">{{.Label}} this year</div>
+<div style="font-size:12px;color:#666;">{{change .Count}} on last year</div>
+</td>{{end}}
+</tr>
+</table>
+</td></tr>
+
+{{if .Policies}}<tr><td style="padding:0 24px 16px;">
+<h2 style="font-size:16px;border-bottom:1px solid #ccc;">Leave days used by policy</h2>
+<table role="presentation" width="100%" cellpadding="2" cellspacing="0" style="font-size:13px;">
+{{range .Policies}}<tr>
+<td width="35%">{{.Name}}</td>
+<td><table role="presentation" cellpadding="0" cellspacing="0" width="100%"><tr>
+{{with width .TotalDaysUsed $.MaxPolicy}}<td width="{{.}}%" style="background:{{$.Accent}};height:12px;font-size:1px;">&nbsp;</td>{{end}}
+<td style="padding-left:6px;">{{number .TotalDaysUsed}}</td>
+</tr></table></td>
+</tr>{{end}}
+</table>
+</td></tr>{{end}}
+
+<tr><td style="padding:0 24px 16px;">
+<h2 style="font-size:16px;border-bottom:1px solid #ccc;">Who is out this week</h2>
+<table role="presentation" width="100%" cellpadding="2" cellspacing="0" style="font-size:13px;">
+{{range .Days}}<tr>
+<td width="20%">{{day .Day}}</td>
+<td><table role="presentation" cellpadding="0" cellspacing="0" width="100%"><tr>
+{{with width .Out $.MaxOut}}<td width="{{.}}%" style="background:{{$.Accent}};height:12px;font-size:1px;">&nbsp;</td>{{end}}
+<td style="padding-left:6px;">{{.Out}}</td>
+</tr></table></td>
+</tr>{{end}}
+</table>
+{{if .Summary.Absences}}<ul style="font-size:13px;padding-left:20px;">
+{{range .Summary.Absences}}<li>{{.User}} - {{.LeaveType}}, {{period .FromDate.Time .ToDate.Time}}</li>
+{{end}}</ul>{{else}}<p style="font-size:13px;color:#666;"><em>Nobody is on approved leave this week.</em></p>{{end}}
+</td></tr>
+
+<tr><td style="padding:0 24px 16px;">
+<h2 style="font-size:16px;border-bottom:1px solid #ccc;">Celebrations</h2>
+{{if .Summary.Events}}<ul style="font-size:13px;padding-left:20px;">
+{{range .Summary.Events}}<li>{{day .On}} - {{.User}} {{.Event}}</li>
+{{end}}</ul>{{else}}<p style="font-size:13px;color:#666;"><em>No birthdays or anniversaries this week.</em></p>{{end}}
+</td></tr>
+
+<tr><td style="padding:0 24px 16px;">
+<h2 style="font-size:16px;border-bottom:1px solid #ccc;">Latest notices</h2>
+{{if .Summary.Dashboard.Notices}}<ul style="font-size:13px;padding-left:20px;">
+{{range .Summary.Dashboard.Notices}}<li>{{if not .CreatedDate.IsZero}}{{.CreatedDate.Format "02 Jan"}} - {{end}}{{.Title}}{{with .Author}} ({{.}}){{end}}</li>
+{{end}}</ul>{{else}}<p style="font-size:13px;color:#666;"><em>No notices.</em></p>{{end}}
+</td></tr>
+
+<tr><td style="padding:0 24px 16px;">
+<h2 style="font-size:16px;border-bottom:1px solid #ccc;">Latest leave requests</h2>
+{{if .Summary.Dashboard.LeaveHistory}}<table role="presentation" width="100%" cellpadding="4" cellspacing="0" style="font-size:13px;border-collapse:collapse;">
+<tr style="text-align:left;border-bottom:1px solid #ccc;"><th>Name</th><th>Policy</th><th>Dates</th><th>Status</th></tr>
+{{range .Summary.Dashboard.LeaveHistory}}<tr><td>{{.User}}</td><td>{{.Policy}}</td><td>{{period .From.Time .To.Time}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>{{else}}<p style="font-size:13px;color:#666;"><em>No leave requests.</em></p>{{end}}
+</td></tr>
+
+<tr><td style="padding:12px 24px;font-size:11px;color:#888;border-top:1px solid #eee;">
+Generated {{.Summary.GeneratedAt.Format "2006-01-02 15:04"}}{{with .Footer}} - {{.}}{{end}}
+</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// htmlWriter renders the summary as a standalone HTML email body.
+type htmlWriter struct {
+	summary *Summary
+	brand   *report.Brand
+}
+
+type htmlCount struct {
+	Label string
+	Count models.HeadCount
+}
+
+type htmlDay struct {
+	Day time.Time
+	Out float64
+}
+
+func (h *htmlWriter) Output(w io.Writer) error {
+	s, d := h.summary, h.summary.Dashboard
+	accent := h.brand.Accent()
+	data := struct {
+		Summary   *Summary
+		School    string
+		Footer    string
+		Accent    string
+		Counts    []htmlCount
+		Policies  []models.LeavePolicyUsage
+		MaxPolicy float64
+		Days      []htmlDay
+		MaxOut    float64
+	}{
+		Summary: s,
+		Accent:  fmt.Sprintf("#%02x%02x%02x", accent[0], accent[1], accent[2]),
+		Counts: []htmlCount{
+			{"New students", d.Students},
+			{"New teachers", d.Teachers},
+			{"New parents", d.Parents},
+		},
+		Policies: d.LeavePolicies,
+	}
+	if h.brand != nil {
+		data.School, data.Footer = h.brand.SchoolName, h.brand.FooterText
+	}
+	for _, p := range d.LeavePolicies {
+		data.MaxPolicy = max(data.MaxPolicy, p.TotalDaysUsed)
+	}
+	for i, day := range s.Week.Days() {
+		out := float64(s.OutPerDay[i])
+		data.Days = append(data.Days, htmlDay{Day: day, Out: out})
+		data.MaxOut = max(data.MaxOut, out)
+	}
+	return summaryHTML.Execute(w, data)
+}
+
+// barWidth is the percentage of the row a bar of v takes when top fills
+// 85% of it. Zero values get no bar.
+func barWidth(v, top float64) int {
+	if top <= 0 || v <= 0 {
+		return 0
+	}
+	return max(1, int(v/top*85))
+}
@@ -0,0 +1,322 @@
+package dashboard
+
+import (
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	dayLayout = "02 Jan 2006"
+	gutter    = 8.0
+	lineH     = 5.0
+	// Lists are cut to keep the summary on one page.
+	maxAbsences = 8
+	maxEvents   = 6
+	maxPolicies = 6
+	maxRequests = 6
+)
+
+var (
+	titleFont   = report.Font{Style: "B", Size: 18}
+	subFont     = report.Font{Size: 10}
+	headingFont = report.Font{Style: "B", Size: 11}
+	kpiValue    = report.Font{Style: "B", Size: 20}
+	kpiLabel    = report.Font{Size: 9}
+	textFont    = report.Font{Size: 9}
+	boldFont    = report.Font{Style: "B", Size: 9}
+	chartFont   = report.Font{Size: 7}
+	mutedFont   = report.Font{Style: "I", Size: 8}
+)
+
+// renderPDF lays the summary out on one A4 page: head count tiles, charts
+// of head counts, leave usage and absences, then the week's absences,
+// celebrations, latest notices and leave requests.
+func renderPDF(s *Summary, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	fonts.Register(pdf)
+	pdf.SetTitle("Weekly Summary", true)
+	brand.Apply(pdf, fonts, nil)
+	pdf.AddPage()
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+	width := pageW - left - right
+	half := (width - gutter) / 2
+	accent := brand.Accent()
+	light := tint(accent)
+	d := s.Dashboard
+
+	fonts.Cell(pdf, titleFont, width, 9, "Weekly Summary", "", 1, "C", 0)
+	fonts.Cell(pdf, subFont, width, 6, "Week of "+s.Week.From.Format(dayLayout)+" to "+s.Week.To.Format(dayLayout), "", 1, "C", 0)
+	pdf.Ln(3)
+
+	// Head count tiles.
+	y := pdf.GetY()
+	tileW := (width - 2*gutter) / 3
+	for i, hc := range []struct {
+		label string
+		count models.HeadCount
+	}{{"New students", d.Students}, {"New teachers", d.Teachers}, {"New parents", d.Parents}} {
+		tile(pdf, fonts, left+float64(i)*(tileW+gutter), y, tileW, hc.label, hc.count, accent)
+	}
+	pdf.SetY(y + 22 + 5)
+
+	// Charts: head counts next to leave usage.
+	y = pdf.GetY()
+	heading(pdf, fonts, left, y, half, "This year and last year")
+	barChart(pdf, fonts, left, y+7, half, 38,
+		[]string{"Students", "Teachers", "Parents"},
+		[][]float64{
+			{float64(d.Students.CurrentYear), float64(d.Teachers.CurrentYear), float64(d.Parents.CurrentYear)},
+			{float64(d.Students.PreviousYear()), float64(d.Teachers.PreviousYear()), float64(d.Parents.PreviousYear())},
+		},
+		[][3]int{accent, light})
+	legend(pdf, fonts, left, y+7+38+1, []string{"This year", "Last year"}, [][3]int{accent, light})
+
+	x := left + half + gutter
+	heading(pdf, fonts, x, y, half, "Leave days used by policy")
+	policies := d.LeavePolicies
+	if len(policies) > maxPolicies {
+		policies = policies[:maxPolicies]
+	}
+	if len(policies) == 0 {
+		muted(pdf, fonts, x, y+7, half, "No leave policies assigned.")
+	} else {
+		labels := make([]string, len(policies))
+		values := make([]float64, len(policies))
+		for i, p := range policies {
+			labels[i], values[i] = p.Name, p.TotalDaysUsed
+		}
+		hbarChart(pdf, fonts, x, y+7, half, labels, values, accent)
+	}
+	pdf.SetY(y + 7 + 38 + 6 + 4)
+
+	// Who is out: per day chart next to the list.
+	y = pdf.GetY()
+	heading(pdf, fonts, left, y, width, "Who is out this week")
+	days := make([]string, weekDays)
+	counts := make([]float64, weekDays)
+	for i, day := range s.Week.Days() {
+		days[i], counts[i] = day.Format("Mon 02"), float64(s.OutPerDay[i])
+	}
+	barChart(pdf, fonts, left, y+7, half, 36, days, [][]float64{counts}, [][3]int{accent})
+	lines := make([]string, 0, len(s.Absences))
+	for _, a := range s.Absences {
+		lines = append(lines, fmt.Sprintf("%s - %s, %s", a.User, a.LeaveType, period(a.FromDate.Time, a.ToDate.Time)))
+	}
+	list(pdf, fonts, left+half+gutter, y+7, half, lines, maxAbsences, "Nobody is on approved leave this week.")
+	pdf.SetY(y + 7 + 42 + 4)
+
+	// Celebrations next to the latest notices.
+	y = pdf.GetY()
+	heading(pdf, fonts, left, y, half, "Celebrations")
+	lines = lines[:0]
+	for _, e := range s.Events {
+		lines = append(lines, fmt.Sprintf("%s - %s %s", e.On.Format("Mon 02 Jan"), e.User, e.Event))
+	}
+	endLeft := list(pdf, fonts, left, y+7, half, lines, maxEvents, "No birthdays or anniversaries this week.")
+	heading(pdf, fonts, x, y, half, "Latest notices")
+	lines = lines[:0]
+	for _, n := range d.Notices {
+		line := n.Title
+		if n.Author != "" {
+			line += " (" + n.Author + ")"
+		}
+		if !n.CreatedDate.IsZero() {
+			line = n.CreatedDate.Format("02 Jan") + " - " + line
+		}
+		lines = append(lines, line)
+	}
+	endRight := list(pdf, fonts, x, y+7, half, lines, maxEvents, "No notices.")
+	pdf.SetY(max(endLeft, endRight) + 4)
+
+	// Latest leave requests.
+	y = pdf.GetY()
+	heading(pdf, fonts, left, y, width, "Latest leave requests")
+	pdf.SetXY(left, y+7)
+	if len(d.LeaveHistory) == 0 {
+		muted(pdf, fonts, left, y+7, width, "No leave requests.")
+		return pdf
+	}
+	cols := []float64{50, 40, 60, 30}
+	for i, h := range []string{"Name", "Policy", "Dates", "Status"} {
+		fonts.Cell(pdf, boldFont, cols[i], lineH, h, "B", 0, "L", 0)
+	}
+	pdf.Ln(-1)
+	for i, l := range d.LeaveHistory {
+		if i == maxRequests {
+			break
+		}
+		pdf.SetX(left)
+		for j, v := range []string{l.User, l.Policy, period(l.From.Time, l.To.Time), l.Status} {
+			fonts.Cell(pdf, textFont, cols[j], lineH, fonts.Fit(pdf, textFont, v, cols[j]-1), "", 0, "L", 0)
+		}
+		pdf.Ln(-1)
+	}
+	return pdf
+}
+
+// tile draws a head count with its change from last year.
+func tile(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w float64, label string, hc models.HeadCount, accent [3]int) {
+	pdf.SetDrawColor(accent[0], accent[1], accent[2])
+	pdf.SetLineWidth(0.4)
+	pdf.Rect(x, y, w, 22, "D")
+	pdf.SetLineWidth(0.2)
+
+	pdf.SetXY(x, y+1.5)
+	pdf.SetTextColor(accent[0], accent[1], accent[2])
+	fonts.Cell(pdf, kpiValue, w, 9, strconv.Itoa(hc.CurrentYear), "", 2, "C", 0)
+	pdf.SetTextColor(0, 0, 0)
+	fonts.Cell(pdf, kpiLabel, w, 5, label+" this year", "", 2, "C", 0)
+	pdf.SetTextColor(90, 90, 90)
+	fonts.Cell(pdf, kpiLabel, w, 5, change(hc)+" on last year", "", 0, "C", 0)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetDrawColor(0, 0, 0)
+}
+
+func heading(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w float64, text string) {
+	pdf.SetXY(x, y)
+	fonts.Cell(pdf, headingFont, w, 6, text, "B", 0, "L", 0)
+}
+
+func muted(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w float64, text string) {
+	pdf.SetXY(x, y)
+	pdf.SetTextColor(110, 110, 110)
+	fonts.Cell(pdf, mutedFont, w, lineH, text, "", 0, "L", 0)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// list writes up to limit lines and a count of the rest, or empty when
+// there are none, and returns the y below the list.
+func list(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w float64, lines []string, limit int, empty string) float64 {
+	if len(lines) == 0 {
+		muted(pdf, fonts, x, y, w, empty)
+		return y + lineH
+	}
+	for i, line := range lines {
+		if i == limit-1 && len(lines) > limit {
+			muted(pdf, fonts, x, y, w, fmt.Sprintf("and %d more", len(lines)-i))
+			return y + lineH
+		}
+		pdf.SetXY(x, y)
+		fonts.Cell(pdf, textFont, w, lineH, fonts.Fit(pdf, textFont, line, w), "", 0, "L", 0)
+		y += lineH
+	}
+	return y
+}
+
+// barChart draws vertical bars of one or more series per label, scaled to
+// the largest value, with the values above the bars and the labels below
+// the axis. The chart fills w by h including its labels.
+func barChart(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w, h float64, labels []string, series [][]float64, colors [][3]int) {
+	top := maxValue(series...)
+	plotH := h - 10
+	base := y + 4 + plotH
+	slot := w / float64(len(labels))
+	barW := min(slot*0.7/float64(len(series)), 12)
+
+	pdf.SetDrawColor(150, 150, 150)
+	pdf.Line(x, base, x+w, base)
+	pdf.SetDrawColor(0, 0, 0)
+	for i, label := range labels {
+		groupX := x + float64(i)*slot + (slot-barW*float64(len(series)))/2
+		for j, values := range series {
+			v := values[i]
+			barH := 0.0
+			if top > 0 {
+				barH = plotH * v / top
+			}
+			bx := groupX + float64(j)*barW
+			c := colors[j%len(colors)]
+			pdf.SetFillColor(c[0], c[1], c[2])
+			if barH > 0 {
+				pdf.Rect(bx, base-barH, barW, barH, "F")
+			}
+			pdf.SetXY(bx-2, base-barH-3.5)
+			fonts.Cell(pdf, chartFont, barW+4, 3.5, number(v), "", 0, "C", 0)
+		}
+		pdf.SetXY(x+float64(i)*slot, base+1)
+		fonts.Cell(pdf, chartFont, slot, 4, fonts.Fit(pdf, chartFont, label, slot), "", 0, "C", 0)
+	}
+}
+
+// hbarChart draws one horizontal bar per label, the label on the left and
+// the value after the bar.
+func hbarChart(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y, w float64, labels []string, values []float64, color [3]int) {
+	top := maxValue(values)
+	labelW, valueW := w*0.35, 10.0
+	plotW := w - labelW - valueW
+	pdf.SetFillColor(color[0], color[1], color[2])
+	for i, label := range labels {
+		rowY := y + float64(i)*6
+		pdf.SetXY(x, rowY)
+		fonts.Cell(pdf, chartFont, labelW, 5, fonts.Fit(pdf, chartFont, label, labelW-1), "", 0, "L", 0)
+		barW := 0.0
+		if top > 0 {
+			barW = plotW * values[i] / top
+		}
+		if barW > 0 {
+			pdf.Rect(x+labelW, rowY+0.75, barW, 3.5, "F")
+		}
+		pdf.SetXY(x+labelW+barW+1, rowY)
+		fonts.Cell(pdf, chartFont, valueW, 5, number(values[i]), "", 0, "L", 0)
+	}
+}
+
+func legend(pdf *gofpdf.Fpdf, fonts *report.FontSet, x, y float64, names []string, colors [][3]int) {
+	for i, name := range names {
+		c := colors[i]
+		pdf.SetFillColor(c[0], c[1], c[2])
+		pdf.Rect(x, y+1, 3, 3, "F")
+		pdf.SetXY(x+4, y)
+		fonts.Cell(pdf, chartFont, 20, 5, name, "", 0, "L", 0)
+		x += 26
+	}
+}
+
+func maxValue(series ...[]float64) float64 {
+	top := 0.0
+	for _, values := range series {
+		for _, v := range values {
+			top = max(top, v)
+		}
+	}
+	return top
+}
+
+// tint mixes c with white for a second series.
+func tint(c [3]int) [3]int {
+	for i := range c {
+		c[i] += (255 - c[i]) * 55 / 100
+	}
+	return c
+}
+
+// change writes the difference from last year, with the percentage when
+// last year had any.
+func change(hc models.HeadCount) string {
+	s := fmt.Sprintf("%+d", hc.Change)
+	if hc.PreviousYear() != 0 {
+		s += fmt.Sprintf(" (%+.0f%%)", hc.ChangePercent)
+	}
+	return s
+}
+
+// number writes v with at most one decimal.
+func number(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+func period(from, to time.Time) string {
+	if from.Equal(to) {
+		return from.Format(dayLayout)
+	}
+	return from.Format("02 Jan") + " - " + to.Format(dayLayout)
+}
@@ -0,0 +1,155 @@
+// Package dashboard renders a one-page weekly summary of the backend's
+// dashboard for management, as PDF for printing or HTML for email.
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// KindDashboard identifies dashboard summaries in signatures.
+const KindDashboard = "dashboard"
+
+// weekDays is the length of the summarised period.
+const weekDays = 7
+
+var ErrInvalidWeek = errors.New("invalid week")
+
+// Week is the seven calendar days a summary covers, as UTC midnights.
+type Week struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseWeek reads the first day of the week as YYYY-MM-DD. The week starts
+// today when start is empty.
+func ParseWeek(start string, now time.Time) (Week, error) {
+	from := report.Day(now)
+	if start != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, start); err != nil {
+			return Week{}, fmt.Errorf("%w: week must be YYYY-MM-DD", ErrInvalidWeek)
+		}
+	}
+	return Week{From: from, To: from.AddDate(0, 0, weekDays-1)}, nil
+}
+
+// Days returns the dates of the week.
+func (w Week) Days() []time.Time {
+	days := make([]time.Time, weekDays)
+	for i := range days {
+		days[i] = w.From.AddDate(0, 0, i)
+	}
+	return days
+}
+
+// Event is a celebration on its date in the week.
+type Event struct {
+	models.Celebration
+	On time.Time
+}
+
+// Summary is the dashboard narrowed to one week.
+type Summary struct {
+	Week      Week
+	Dashboard *models.Dashboard
+	// Events are the celebrations of the week in date order.
+	Events []Event
+	// Absences are the approved leaves overlapping the week and OutPerDay
+	// counts those covering each day.
+	Absences    []models.Absence
+	OutPerDay   []int
+	GeneratedAt time.Time
+}
+
+// Options select the week and the format, pdf or html.
+type Options struct {
+	Week   Week
+	Format report.Format
+}
+
+type Service interface {
+	GenerateSummary(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService draws text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, fonts: fonts, branding: branding, signer: signer}
+}
+
+func (s *service) GenerateSummary(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	if opts.Format != "" && opts.Format != report.FormatPDF && opts.Format != report.FormatHTML {
+		return nil, fmt.Errorf("%w: dashboard summaries are pdf or html", report.ErrUnsupportedFormat)
+	}
+	dashboard, err := s.backend.GetDashboard(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	summary := Summarize(dashboard, opts.Week)
+	summary.GeneratedAt = time.Now()
+
+	if opts.Format == report.FormatHTML {
+		return &htmlWriter{summary: summary, brand: s.branding.Current()}, nil
+	}
+	pdf := renderPDF(summary, s.fonts, s.branding.Current())
+	title := "Weekly summary " + opts.Week.From.Format(time.DateOnly)
+	return s.signer.Wrap(pdf, pdfsign.Document{Kind: KindDashboard, Name: title}), nil
+}
+
+// Summarize keeps the celebrations and absences of the week.
+func Summarize(d *models.Dashboard, week Week) *Summary {
+	s := &Summary{Week: week, Dashboard: d, OutPerDay: make([]int, weekDays)}
+	for _, c := range d.Celebrations {
+		if on, ok := occursIn(c.EventDate.Time, week); ok {
+			s.Events = append(s.Events, Event{Celebration: c, On: on})
+		}
+	}
+	sort.SliceStable(s.Events, func(i, j int) bool { return s.Events[i].On.Before(s.Events[j].On) })
+
+	for _, a := range d.OneMonthLeave {
+		from, to := report.Day(a.FromDate.Time), report.Day(a.ToDate.Time)
+		if to.Before(week.From) || from.After(week.To) {
+			continue
+		}
+		s.Absences = append(s.Absences, a)
+		for i, day := range week.Days() {
+			if !day.Before(from) && !day.After(to) {
+				s.OutPerDay[i]++
+			}
+		}
+	}
+	sort.SliceStable(s.Absences, func(i, j int) bool { return s.Absences[i].FromDate.Before(s.Absences[j].FromDate.Time) })
+	return s
+}
+
+// occursIn returns the anniversary of date that falls in week, if any.
+func occursIn(date time.Time, week Week) (time.Time, bool) {
+	if date.IsZero() {
+		return time.Time{}, false
+	}
+	for _, year := range []int{week.From.Year(), week.To.Year()} {
+		on := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		if !on.Before(week.From) && !on.After(week.To) {
+			return on, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,153 @@
+package idcard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves ID cards, mounted under /api/v1/reports/idcards.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/students", h.StudentCards)
+	r.Get("/students/{id}", h.StudentCards)
+	r.Get("/staff", h.StaffCards)
+	r.Get("/staff/{id}", h.StaffCards)
+	r.Get("/classes/{class}/sections/{section}", h.ClassCards)
+	return r
+}
+
+// errorStatus maps ID card errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidOptions), errors.Is(err, ErrTooManyCards):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNoCards):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseIDs reads the {id} route parameter or the comma separated ids query
+// parameter.
+func parseIDs(r *http.Request) ([]int, error) {
+	raw := chi.URLParam(r, "id")
+	if raw == "" {
+		raw = r.URL.Query().Get("ids")
+	}
+	var ids []int
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", s)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("an id or the ids parameter is required")
+	}
+	return ids, nil
+}
+
+type cardsFunc func(ctx context.Context, ids []int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+
+func (h *Handler) StudentCards(w http.ResponseWriter, r *http.Request) {
+	h.serveIDs(w, r, "students", h.service.StudentCards)
+}
+
+func (h *Handler) StaffCards(w http.ResponseWriter, r *http.Request) {
+	h.serveIDs(w, r, "staff", h.service.StaffCards)
+}
+
+func (h *Handler) serveIDs(w http.ResponseWriter, r *http.Request, holders string, cards cardsFunc) {
+	ids, err := parseIDs(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	opts, err := parseOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pdf, err := cards(r.Context(), ids, opts, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	name := fmt.Sprintf("idcards_%s.pdf", holders)
+	if len(ids) == 1 {
+		name = fmt.Sprintf("idcard_%s_%d.pdf", holders, ids[0])
+	}
+	write(w, pdf, name)
+}
+
+func (h *Handler) ClassCards(w http.ResponseWriter, r *http.Request) {
+	class := chi.URLParam(r, "class")
+	section := chi.URLParam(r, "section")
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	opts, err := parseOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pdf, err := h.service.ClassCards(r.Context(), class, section, opts, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	write(w, pdf, fmt.Sprintf("idcards_class_%s_section_%s.pdf", class, section))
+}
+
+func parseOptions(r *http.Request) (Options, error) {
+	q := r.URL.Query()
+	return ParseOptions(q.Get("layout"), q.Get("barcode"), q.Get("valid"), time.Now())
+}
+
+func write(w http.ResponseWriter, pdf report.Writer, name string) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+	if err := pdf.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,180 @@
+package idcard
+
+import (
+	"context"
+	"errors"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleStudents() map[int]*models.Student {
+	dob := time.Date(2012, 3, 4, 0, 0, 0, 0, time.UTC)
+	return map[int]*models.Student{
+		1: {ID: 1, Name: "Cara", Class: "5", Section: "A", Roll: 3, DOB: dob, GuardianName: "Tom", GuardianPhone: "555-0101"},
+		2: {ID: 2, Name: "Abel", Class: "5", Section: "A", Roll: 1, DOB: dob, FatherName: "Sam", FatherPhone: "555-0102"},
+		3: {ID: 3, Name: "Bea", Class: "5", Section: "B", Roll: 2},
+	}
+}
+
+type fakeBackend struct {
+	client.IBackend
+	students map[int]*models.Student
+}
+
+func (f *fakeBackend) ListStudents(ctx context.Context, filter models.StudentFilter, cookies []*http.Cookie) ([]models.Student, error) {
+	var list []models.Student
+	for id := range f.students {
+		list = append(list, models.Student{ID: id})
+	}
+	return list, nil
+}
+
+func (f *fakeBackend) GetStudentByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Student, error) {
+	st, ok := f.students[id]
+	if !ok {
+		return nil, &client.StatusError{Resource: "student", Status: http.StatusNotFound}
+	}
+	return st, nil
+}
+
+func (f *fakeBackend) GetStaffByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Staff, error) {
+	return &models.Staff{ID: id, Name: "Dana", RoleName: "Teacher", Department: "Science", EmergencyPhone: "555-0199"}, nil
+}
+
+func TestParseOptions(t *testing.T) {
+	now := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+	opts, err := ParseOptions("", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Layout != LayoutSingle || opts.Barcode != Code128 || opts.ValidUntil.Format(time.DateOnly) != "2025-08-31" {
+		t.Errorf("unexpected defaults %+v", opts)
+	}
+	if opts, _ = ParseOptions("Sheet", "QR", "2025-07-31", now); opts.Layout != LayoutSheet || opts.Barcode != QR || opts.ValidUntil.Month() != time.July {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	for _, bad := range [][3]string{{"poster", "", ""}, {"", "ean13", ""}, {"", "", "next year"}} {
+		if _, err := ParseOptions(bad[0], bad[1], bad[2], now); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%q: expected ErrInvalidOptions, got %v", bad, err)
+		}
+	}
+}
+
+func TestCards(t *testing.T) {
+	students := sampleStudents()
+	c := StudentCard(students[1])
+	if c.EmergencyName != "Tom" || c.EmergencyPhone != "555-0101" || strings.Join(c.Details, "|") != "Class 5 - Section A|Roll 3" {
+		t.Errorf("unexpected student card %+v", c)
+	}
+	if c = StudentCard(students[2]); c.EmergencyName != "Sam" || c.EmergencyPhone != "555-0102" {
+		t.Errorf("expected the father as emergency contact, got %+v", c)
+	}
+	if c = StudentCard(students[3]); c.EmergencyPhone != "" {
+		t.Errorf("expected no emergency contact, got %+v", c)
+	}
+	c = StaffCard(&models.Staff{ID: 4, Name: "Dana", Department: "Science", JoinDate: time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)})
+	if c.Role != "Staff" || strings.Join(c.Details, "|") != "Science|Since Apr 2019" {
+		t.Errorf("unexpected staff card %+v", c)
+	}
+}
+
+func TestRenderCards(t *testing.T) {
+	fonts, _ := report.LoadFonts(nil)
+	cards := make([]Card, 13)
+	for i := range cards {
+		cards[i] = StudentCard(sampleStudents()[1])
+		cards[i].ID = i + 1
+	}
+	brand, err := report.LoadBrand(report.Branding{SchoolName: "Springfield School", Address: "1 Main St"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		opts  Options
+		pages int
+		width float64
+	}{
+		{Options{Layout: LayoutSingle, Barcode: Code128}, 26, cardW},
+		{Options{Layout: LayoutSingle, Barcode: QR}, 26, cardW},
+		// Two sheets of fronts, each followed by its backs.
+		{Options{Layout: LayoutSheet, Barcode: Code128}, 4, 210},
+	} {
+		pdf := renderCards(cards, tc.opts, fonts, brand)
+		if pdf.Err() {
+			t.Fatalf("%+v: %v", tc.opts, pdf.Error())
+		}
+		if pdf.PageNo() != tc.pages {
+			t.Errorf("%+v: expected %d pages, got %d", tc.opts, tc.pages, pdf.PageNo())
+		}
+		if w, _ := pdf.GetPageSize(); math.Abs(w-tc.width) > 0.01 {
+			t.Errorf("%+v: expected pages %vmm wide, got %v", tc.opts, tc.width, w)
+		}
+	}
+}
+
+func TestFetchCards(t *testing.T) {
+	s := NewService(&fakeBackend{students: sampleStudents()}, nil, nil, nil)
+	opts := Options{Layout: LayoutSingle, Barcode: Code128}
+
+	if _, err := s.StudentCards(context.Background(), []int{1, 9}, opts, nil); err == nil || !strings.Contains(err.Error(), "ID 9") {
+		t.Errorf("expected the missing student to fail the request, got %v", err)
+	}
+	if _, err := s.StudentCards(context.Background(), make([]int, MaxCards+1), opts, nil); !errors.Is(err, ErrTooManyCards) {
+		t.Errorf("expected ErrTooManyCards, got %v", err)
+	}
+
+	cards, err := fetchCards(context.Background(), []int{3, 1, 2}, func(ctx context.Context, id int) (Card, error) {
+		if id == 1 {
+			return Card{}, errSkip
+		}
+		return Card{ID: id}, nil
+	})
+	if err != nil || len(cards) != 2 || cards[0].ID != 3 || cards[1].ID != 2 {
+		t.Errorf("expected the cards in request order without skipped ones, got %+v, %v", cards, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	routes := NewHandler(NewService(&fakeBackend{students: sampleStudents()}, nil, nil, nil)).Routes()
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+		file   string
+	}{
+		{"student", "/students/1", http.StatusOK, "idcard_students_1.pdf"},
+		{"students", "/students?ids=1,2&layout=sheet", http.StatusOK, "idcards_students.pdf"},
+		{"staff", "/staff/4?barcode=qr", http.StatusOK, "idcard_staff_4.pdf"},
+		{"class", "/classes/5/sections/A?layout=sheet", http.StatusOK, "idcards_class_5_section_A.pdf"},
+		{"empty class", "/classes/6/sections/A", http.StatusNotFound, ""},
+		{"no ids", "/students", http.StatusBadRequest, ""},
+		{"bad id", "/students?ids=1,x", http.StatusBadRequest, ""},
+		{"bad layout", "/students/1?layout=poster", http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+			continue
+		}
+		if tc.file == "" {
+			continue
+		}
+		if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename="+tc.file {
+			t.Errorf("%s: unexpected content disposition %q", tc.name, cd)
+		}
+		if !strings.HasPrefix(rec.Body.String(), "%PDF") {
+			t.Errorf("%s: unexpected body %.40q", tc.name, rec.Body)
+		}
+	}
+}
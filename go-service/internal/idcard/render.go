@@ -0,0 +1,249 @@
+package idcard
+
+import (
+	"bytes"
+	"fmt"
+	"goservice/internal/report"
+	"strconv"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// CR80 card size in millimetres.
+const (
+	cardW = 85.6
+	cardH = 53.98
+)
+
+// A4 sheet of two columns and five rows of cards, centred.
+const (
+	sheetCols = 2
+	sheetRows = 5
+	// cropGap and cropLen place crop marks outside the grid so that they
+	// are cut away with the margin.
+	cropGap = 2.0
+	cropLen = 5.0
+	// barcodeModulePixels is the rendered size of one barcode module.
+	barcodeModulePixels = 6
+	dateLayout          = "02 Jan 2006"
+)
+
+var (
+	schoolFont  = report.Font{Style: "B", Size: 9}
+	nameFont    = report.Font{Style: "B", Size: 10}
+	roleFont    = report.Font{Style: "B", Size: 8}
+	detailFont  = report.Font{Size: 7}
+	labelFont   = report.Font{Size: 6}
+	backFont    = report.Font{Size: 7}
+	backBold    = report.Font{Style: "B", Size: 8}
+	placeholder = report.Font{Size: 6}
+)
+
+// renderCards lays out the cards according to opts.Layout.
+func renderCards(cards []Card, opts Options, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	var pdf *gofpdf.Fpdf
+	if opts.Layout == LayoutSheet {
+		pdf = gofpdf.New("P", "mm", "A4", "")
+	} else {
+		pdf = gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "mm", Size: gofpdf.SizeType{Wd: cardW, Ht: cardH}})
+	}
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	fonts.Register(pdf)
+	r := &cardRenderer{pdf: pdf, fonts: fonts, brand: brand, opts: opts}
+
+	if opts.Layout != LayoutSheet {
+		for _, c := range cards {
+			pdf.AddPage()
+			r.front(c, 0, 0)
+			pdf.AddPage()
+			r.back(c, 0, 0)
+		}
+		return pdf
+	}
+
+	pageW, pageH := pdf.GetPageSize()
+	ox := (pageW - sheetCols*cardW) / 2
+	oy := (pageH - sheetRows*cardH) / 2
+	perSheet := sheetCols * sheetRows
+	for start := 0; start < len(cards); start += perSheet {
+		sheet := cards[start:min(start+perSheet, len(cards))]
+		pdf.AddPage()
+		for i, c := range sheet {
+			col, row := i%sheetCols, i/sheetCols
+			r.front(c, ox+float64(col)*cardW, oy+float64(row)*cardH)
+		}
+		cropMarks(pdf, ox, oy)
+		pdf.AddPage()
+		for i, c := range sheet {
+			// Turning the sheet over its long edge swaps the columns.
+			col, row := sheetCols-1-i%sheetCols, i/sheetCols
+			r.back(c, ox+float64(col)*cardW, oy+float64(row)*cardH)
+		}
+		cropMarks(pdf, ox, oy)
+	}
+	return pdf
+}
+
+// cropMarks draws short hairlines in the margin on the extension of every
+// cut line of the card grid at ox, oy.
+func cropMarks(pdf *gofpdf.Fpdf, ox, oy float64) {
+	w, h := sheetCols*cardW, sheetRows*cardH
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.1)
+	for c := 0; c <= sheetCols; c++ {
+		x := ox + float64(c)*cardW
+		pdf.Line(x, oy-cropGap-cropLen, x, oy-cropGap)
+		pdf.Line(x, oy+h+cropGap, x, oy+h+cropGap+cropLen)
+	}
+	for r := 0; r <= sheetRows; r++ {
+		y := oy + float64(r)*cardH
+		pdf.Line(ox-cropGap-cropLen, y, ox-cropGap, y)
+		pdf.Line(ox+w+cropGap, y, ox+w+cropGap+cropLen, y)
+	}
+	pdf.SetLineWidth(0.2)
+}
+
+type cardRenderer struct {
+	pdf   *gofpdf.Fpdf
+	fonts *report.FontSet
+	brand *report.Brand
+	opts  Options
+}
+
+// front draws the branded band, the photo placeholder and the holder's
+// name, role and details of a card with its top left corner at x, y.
+func (r *cardRenderer) front(c Card, x, y float64) {
+	pdf, fonts := r.pdf, r.fonts
+	accent := r.brand.Accent()
+
+	pdf.SetFillColor(accent[0], accent[1], accent[2])
+	pdf.Rect(x, y, cardW, 11, "F")
+	pdf.Rect(x, y+cardH-3, cardW, 3, "F")
+	logoW := r.brand.DrawLogo(pdf, x+3, y+2, 7)
+	school := "Identity Card"
+	if r.brand != nil && r.brand.SchoolName != "" {
+		school = r.brand.SchoolName
+	}
+	textX := x + 3
+	if logoW > 0 {
+		textX += logoW + 2
+	}
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetXY(textX, y+2)
+	width := x + cardW - 3 - textX
+	fonts.Cell(pdf, schoolFont, width, 7, fonts.Fit(pdf, schoolFont, school, width), "", 0, "L", 0)
+
+	// Photo placeholder, the photo is glued on after printing.
+	pdf.SetTextColor(150, 150, 150)
+	pdf.SetDrawColor(150, 150, 150)
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(x+4, y+14, 22, 28, "D")
+	pdf.SetXY(x+4, y+26)
+	fonts.Cell(pdf, placeholder, 22, 4, "PHOTO", "", 0, "C", 0)
+	pdf.SetDrawColor(0, 0, 0)
+
+	textX, width = x+30, cardW-30-3
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(textX, y+14)
+	fonts.Cell(pdf, nameFont, width, 5, fonts.Fit(pdf, nameFont, c.Name, width), "", 2, "L", 0)
+	pdf.SetTextColor(accent[0], accent[1], accent[2])
+	fonts.Cell(pdf, roleFont, width, 4.5, fonts.Fit(pdf, roleFont, c.Role, width), "", 2, "L", 0)
+	pdf.SetTextColor(0, 0, 0)
+	for _, d := range c.Details {
+		fonts.Cell(pdf, detailFont, width, 3.8, fonts.Fit(pdf, detailFont, d, width), "", 2, "L", 0)
+	}
+	if !c.DOB.IsZero() {
+		fonts.Cell(pdf, detailFont, width, 3.8, "Date of birth "+c.DOB.Format(dateLayout), "", 2, "L", 0)
+	}
+	pdf.SetXY(textX, y+cardH-9)
+	fonts.Cell(pdf, roleFont, width, 4, fmt.Sprintf("ID %d", c.ID), "", 0, "L", 0)
+}
+
+// back draws the validity, emergency contact, return address and barcode of
+// a card with its top left corner at x, y.
+func (r *cardRenderer) back(c Card, x, y float64) {
+	pdf, fonts := r.pdf, r.fonts
+	width := cardW - 8
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(x+4, y+4)
+	fonts.Cell(pdf, backBold, width, 4.5, "Valid until "+r.opts.ValidUntil.Format(dateLayout), "", 2, "L", 0)
+	pdf.Ln(1)
+	pdf.SetX(x + 4)
+	fonts.Cell(pdf, labelFont, width, 3, "In case of emergency, please contact", "", 2, "L", 0)
+	contact := c.EmergencyPhone
+	if c.EmergencyName != "" {
+		contact = c.EmergencyName + " - " + contact
+	}
+	if contact == "" {
+		contact = "-"
+	}
+	fonts.Cell(pdf, backFont, width, 3.5, fonts.Fit(pdf, backFont, contact, width), "", 2, "L", 0)
+
+	if r.brand != nil && (r.brand.SchoolName != "" || r.brand.Address != "") {
+		pdf.Ln(1)
+		pdf.SetX(x + 4)
+		fonts.Cell(pdf, labelFont, width, 3, "If found, please return to", "", 2, "L", 0)
+		returnTo := r.brand.SchoolName
+		if r.brand.Address != "" {
+			returnTo += ", " + r.brand.Address
+		}
+		fonts.Cell(pdf, backFont, width, 3.5, fonts.Fit(pdf, backFont, returnTo, width), "", 2, "L", 0)
+	}
+
+	r.barcode(c, x, y)
+
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Line(x+4, y+cardH-7, x+34, y+cardH-7)
+	pdf.SetXY(x+4, y+cardH-6.5)
+	pdf.SetTextColor(90, 90, 90)
+	fonts.Cell(pdf, labelFont, 30, 3, "Authorised signature", "", 0, "L", 0)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// barcode draws the holder's ID as a Code128 strip across the bottom right
+// or a QR code in the bottom right corner of the back.
+func (r *cardRenderer) barcode(c Card, x, y float64) {
+	pdf := r.pdf
+	content := strconv.Itoa(c.ID)
+	var (
+		code barcode.Barcode
+		err  error
+	)
+	if r.opts.Barcode == QR {
+		code, err = qr.Encode(content, qr.M, qr.Auto)
+	} else {
+		code, err = code128.Encode(content)
+	}
+	if err != nil {
+		pdf.SetError(err)
+		return
+	}
+	height := code.Bounds().Dx()
+	if r.opts.Barcode != QR {
+		height = 10
+	}
+	data, err := report.BarcodePNG(code, code.Bounds().Dx()*barcodeModulePixels, height*barcodeModulePixels)
+	if err != nil {
+		pdf.SetError(err)
+		return
+	}
+
+	name := fmt.Sprintf("idcard-%s-%s-%d", c.Holder, r.opts.Barcode, c.ID)
+	opts := gofpdf.ImageOptions{ImageType: "png"}
+	if pdf.GetImageInfo(name) == nil {
+		pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+	}
+	if r.opts.Barcode == QR {
+		pdf.ImageOptions(name, x+cardW-4-18, y+cardH-4-18, 18, 18, false, opts, 0, "")
+		return
+	}
+	w := 40.0
+	pdf.ImageOptions(name, x+cardW-4-w, y+cardH-4-12, w, 9, false, opts, 0, "")
+	pdf.SetXY(x+cardW-4-w, y+cardH-4-3)
+	r.fonts.Cell(pdf, labelFont, w, 3, content, "", 0, "C", 0)
+}
@@ -0,0 +1,285 @@
+// Package idcard renders CR80 identity cards for students and staff, one
+// card per page or ten to an A4 sheet with crop marks.
+package idcard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KindIDCard identifies ID card documents in signatures.
+const KindIDCard = "idcard"
+
+const (
+	// MaxCards bounds the cards of one request.
+	MaxCards = 200
+	// workers bounds the concurrent backend lookups of a request.
+	workers = 4
+)
+
+var (
+	ErrNoCards        = errors.New("no ID cards requested")
+	ErrTooManyCards   = fmt.Errorf("at most %d ID cards can be printed at once", MaxCards)
+	ErrInvalidOptions = errors.New("invalid ID card options")
+)
+
+// Layout is how cards are placed on pages.
+type Layout string
+
+const (
+	// LayoutSingle puts each side of a card on its own CR80 page.
+	LayoutSingle Layout = "single"
+	// LayoutSheet puts ten fronts on an A4 sheet followed by a sheet of the
+	// backs, mirrored for long-edge duplex printing.
+	LayoutSheet Layout = "sheet"
+)
+
+// Symbology is the kind of barcode printed on the back of a card.
+type Symbology string
+
+const (
+	Code128 Symbology = "code128"
+	QR      Symbology = "qr"
+)
+
+// Options are the per-request choices of an ID card document.
+type Options struct {
+	Layout     Layout
+	Barcode    Symbology
+	ValidUntil time.Time
+}
+
+// ParseOptions reads the layout, barcode and valid query parameters. Cards
+// are valid for a year from now unless valid names the last day.
+func ParseOptions(layout, barcode, valid string, now time.Time) (Options, error) {
+	opts := Options{Layout: Layout(strings.ToLower(layout)), Barcode: Symbology(strings.ToLower(barcode))}
+	switch opts.Layout {
+	case "":
+		opts.Layout = LayoutSingle
+	case LayoutSingle, LayoutSheet:
+	default:
+		return Options{}, fmt.Errorf("%w: layout must be single or sheet", ErrInvalidOptions)
+	}
+	switch opts.Barcode {
+	case "":
+		opts.Barcode = Code128
+	case Code128, QR:
+	default:
+		return Options{}, fmt.Errorf("%w: barcode must be code128 or qr", ErrInvalidOptions)
+	}
+	if valid == "" {
+		opts.ValidUntil = time.Date(now.Year()+1, now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+		return opts, nil
+	}
+	var err error
+	if opts.ValidUntil, err = time.Parse(time.DateOnly, valid); err != nil {
+		return Options{}, fmt.Errorf("%w: valid must be YYYY-MM-DD", ErrInvalidOptions)
+	}
+	return opts, nil
+}
+
+// Card is what is printed on an ID card, taken from a student or staff
+// record.
+type Card struct {
+	// Holder is Student or Staff.
+	Holder  string
+	ID      int
+	Name    string
+	Role    string
+	Details []string
+	DOB     time.Time
+	// EmergencyName may be empty when only a phone number is known.
+	EmergencyName  string
+	EmergencyPhone string
+}
+
+// StudentCard fills a card from a student. The guardian is the emergency
+// contact, or a parent when no guardian is recorded.
+func StudentCard(st *models.Student) Card {
+	c := Card{Holder: "Student", ID: st.ID, Name: st.Name, Role: "Student", DOB: st.DOB}
+	class := "Class " + st.Class
+	if st.Section != "" {
+		class += " - Section " + st.Section
+	}
+	c.Details = append(c.Details, class)
+	if st.Roll != 0 {
+		c.Details = append(c.Details, "Roll "+strconv.Itoa(st.Roll))
+	}
+	for _, contact := range [][2]string{
+		{st.GuardianName, st.GuardianPhone},
+		{st.FatherName, st.FatherPhone},
+		{st.MotherName, st.MotherPhone},
+	} {
+		if contact[1] != "" {
+			c.EmergencyName, c.EmergencyPhone = contact[0], contact[1]
+			break
+		}
+	}
+	return c
+}
+
+// StaffCard fills a card from a staff member.
+func StaffCard(st *models.Staff) Card {
+	c := Card{Holder: "Staff", ID: st.ID, Name: st.Name, Role: st.RoleName, DOB: st.DOB, EmergencyPhone: st.EmergencyPhone}
+	if c.Role == "" {
+		c.Role = "Staff"
+	}
+	if st.Department != "" {
+		c.Details = append(c.Details, st.Department)
+	}
+	if !st.JoinDate.IsZero() {
+		c.Details = append(c.Details, "Since "+st.JoinDate.Format("Jan 2006"))
+	}
+	return c
+}
+
+type Service interface {
+	StudentCards(ctx context.Context, ids []int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	StaffCards(ctx context.Context, ids []int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	ClassCards(ctx context.Context, class, section string, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService draws text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, fonts: fonts, branding: branding, signer: signer}
+}
+
+func (s *service) StudentCards(ctx context.Context, ids []int, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	cards, err := fetchCards(ctx, ids, func(ctx context.Context, id int) (Card, error) {
+		st, err := s.backend.GetStudentByID(ctx, id, authCookies)
+		if err != nil {
+			return Card{}, err
+		}
+		return StudentCard(st), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.render(cards, opts, "Student ID cards"), nil
+}
+
+func (s *service) StaffCards(ctx context.Context, ids []int, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	cards, err := fetchCards(ctx, ids, func(ctx context.Context, id int) (Card, error) {
+		st, err := s.backend.GetStaffByID(ctx, id, authCookies)
+		if err != nil {
+			return Card{}, err
+		}
+		return StaffCard(st), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.render(cards, opts, "Staff ID cards"), nil
+}
+
+// ClassCards prints the cards of every student of a class section, in roll
+// order.
+func (s *service) ClassCards(ctx context.Context, class, section string, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	list, err := s.backend.ListStudents(ctx, models.StudentFilter{ClassName: class, Section: section}, authCookies)
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return nil, ErrNoCards
+	} else if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(list))
+	for _, st := range list {
+		ids = append(ids, st.ID)
+	}
+
+	var (
+		mu    sync.Mutex
+		rolls = map[int]int{}
+	)
+	cards, err := fetchCards(ctx, ids, func(ctx context.Context, id int) (Card, error) {
+		st, err := s.backend.GetStudentByID(ctx, id, authCookies)
+		if err != nil {
+			return Card{}, err
+		}
+		if !strings.EqualFold(st.Class, class) || !strings.EqualFold(st.Section, section) {
+			return Card{}, errSkip
+		}
+		mu.Lock()
+		rolls[id] = st.Roll
+		mu.Unlock()
+		return StudentCard(st), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(cards, func(i, j int) bool { return rolls[cards[i].ID] < rolls[cards[j].ID] })
+	return s.render(cards, opts, fmt.Sprintf("Class %s - Section %s ID cards", class, section)), nil
+}
+
+func (s *service) render(cards []Card, opts Options, title string) report.Writer {
+	pdf := renderCards(cards, opts, s.fonts, s.branding.Current())
+	pdf.SetTitle(title, true)
+	return s.signer.Wrap(pdf, pdfsign.Document{Kind: KindIDCard, Name: title})
+}
+
+// errSkip drops a card from fetchCards without failing the request.
+var errSkip = errors.New("skip card")
+
+// fetchCards builds the cards of ids with a bounded number of concurrent
+// lookups, in the order of ids. The first failed lookup fails the request.
+func fetchCards(ctx context.Context, ids []int, get func(context.Context, int) (Card, error)) ([]Card, error) {
+	if len(ids) == 0 {
+		return nil, ErrNoCards
+	}
+	if len(ids) > MaxCards {
+		return nil, ErrTooManyCards
+	}
+
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, workers)
+		cards = make([]Card, len(ids))
+		errs  = make([]error, len(ids))
+	)
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cards[i], errs[i] = get(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	kept := cards[:0]
+	for i, err := range errs {
+		switch {
+		case errors.Is(err, errSkip):
+		case err != nil:
+			return nil, fmt.Errorf("ID %d: %w", ids[i], err)
+		default:
+			kept = append(kept, cards[i])
+		}
+	}
+	if len(kept) == 0 {
+		return nil, ErrNoCards
+	}
+	return kept, nil
+}
@@ -0,0 +1,105 @@
+package issuance
+
+import (
+	"encoding/json"
+	"errors"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	store *Store
+	cache *report.Cache
+}
+
+// NewHandler serves the registry in s. Revoked serials are evicted from
+// cache, which may be nil.
+func NewHandler(s *Store, cache *report.Cache) *Handler {
+	return &Handler{store: s, cache: cache}
+}
+
+// AdminRoutes serves registry administration, mounted under
+// /api/v1/admin/reports behind the admin token.
+func (h *Handler) AdminRoutes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/{serial}/revoke", h.Revoke)
+	return r
+}
+
+// Verification is the answer to a serial lookup.
+type Verification struct {
+	// Valid is true when the serial was issued, is not revoked and, when a
+	// hash was supplied, the hash matches.
+	Valid     bool `json:"valid"`
+	Authentic bool `json:"authentic"`
+	Revoked   bool `json:"revoked"`
+	// ContentMatches compares the ?sha256= query value with the recorded
+	// content hash. It is omitted when no hash was supplied.
+	ContentMatches *bool   `json:"contentMatches,omitempty"`
+	Record         *Record `json:"record,omitempty"`
+}
+
+// Verify looks up the serial printed on, or encoded in the QR code of, a
+// report. It is served at /api/v1/reports/verify/{serial} next to the
+// signature check and like that check needs no session.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.Error(w, http.StatusNotFound, ErrUnknownSerial)
+		return
+	}
+	rec, err := h.store.Get(normalizeSerial(chi.URLParam(r, "serial")))
+	if err != nil {
+		if errors.Is(err, ErrUnknownSerial) {
+			response.Error(w, http.StatusNotFound, err)
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	v := Verification{Authentic: true, Revoked: rec.Revoked, Record: rec}
+	v.Valid = !rec.Revoked
+	if hash := r.URL.Query().Get("sha256"); hash != "" {
+		matches := strings.EqualFold(hash, rec.ContentHash)
+		v.ContentMatches = &matches
+		v.Valid = v.Valid && matches
+	}
+	response.JSON(w, http.StatusOK, v)
+}
+
+// Revoke marks a serial as revoked. The body may carry {"reason": "..."}.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		response.Error(w, http.StatusNotFound, ErrUnknownSerial)
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rec, err := h.store.Revoke(normalizeSerial(chi.URLParam(r, "serial")), body.Reason)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUnknownSerial):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrAlreadyRevoked):
+			status = http.StatusConflict
+		}
+		response.Error(w, status, err)
+		return
+	}
+	// A cached copy would keep handing out the revoked serial.
+	h.cache.EvictTag(rec.Serial)
+	response.JSON(w, http.StatusOK, rec)
+}
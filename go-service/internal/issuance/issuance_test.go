@@ -0,0 +1,146 @@
+package issuance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"goservice/internal/report"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := OpenStore(filepath.Join(t.TempDir(), "db", "issued.db"))
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+type staticWriter []byte
+
+func (s staticWriter) Output(w io.Writer) error {
+	_, err := w.Write(s)
+	return err
+}
+
+func TestIssuer_StampAndWrap(t *testing.T) {
+	store := openTestStore(t)
+	issuer := NewIssuer(store, "https://school.example/", "Springfield Public School")
+
+	stamp, err := issuer.Stamp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9A-Z]{5}-[0-9A-Z]{5}$`).MatchString(stamp.Serial) {
+		t.Errorf("unexpected serial %q", stamp.Serial)
+	}
+	if stamp.URL != "https://school.example/api/v1/reports/verify/"+stamp.Serial {
+		t.Errorf("unexpected url %q", stamp.URL)
+	}
+
+	// Nothing is recorded until the document is output.
+	w := issuer.Wrap(staticWriter("%PDF-1.3 report"), stamp, Subject{Kind: "student", StudentID: 7, Name: "Test Student"})
+	if _, err := store.Get(stamp.Serial); !errors.Is(err, ErrUnknownSerial) {
+		t.Errorf("expected no record before output, got %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := w.Output(buf); err != nil {
+		t.Fatalf("unexpected output error: %v", err)
+	}
+	if buf.String() != "%PDF-1.3 report" {
+		t.Errorf("unexpected output %q", buf)
+	}
+
+	rec, err := store.Get(stamp.Serial)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if rec.ContentHash != hex.EncodeToString(sum[:]) || rec.StudentID != 7 || rec.Issuer != "Springfield Public School" || rec.IssuedAt.IsZero() {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	// Serials are never issued twice.
+	if err := w.Output(io.Discard); !errors.Is(err, ErrSerialExists) {
+		t.Errorf("expected ErrSerialExists, got %v", err)
+	}
+
+	var nilIssuer *Issuer
+	if s, err := nilIssuer.Stamp(); s != nil || err != nil {
+		t.Errorf("expected no stamp from a nil issuer, got %v, %v", s, err)
+	}
+	inner := staticWriter("x")
+	if got := issuer.Wrap(inner, nil, Subject{}); !bytes.Equal(got.(staticWriter), inner) {
+		t.Error("expected documents without a stamp to be returned unchanged")
+	}
+}
+
+func TestHandler_VerifyAndRevoke(t *testing.T) {
+	store := openTestStore(t)
+	if err := store.Create(Record{Serial: "ABCDE-01234", Kind: "student", StudentID: 7, ContentHash: "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+	cache := report.NewCache(time.Minute, 1<<20)
+	cache.Put("report", []byte("%PDF"), "ABCDE-01234")
+	h := NewHandler(store, cache)
+	router := chi.NewRouter()
+	router.Get("/{serial}", h.Verify)
+
+	verify := func(target string) (int, Verification) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp struct {
+			Data Verification `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp.Data
+	}
+
+	// Serials typed from paper are normalised.
+	code, v := verify("/abcde-o1234")
+	if code != http.StatusOK || !v.Valid || !v.Authentic || v.Record.StudentID != 7 || v.ContentMatches != nil {
+		t.Errorf("unexpected verification %d %+v", code, v)
+	}
+	if _, v := verify("/ABCDE-01234?sha256=ABC123"); v.ContentMatches == nil || !*v.ContentMatches || !v.Valid {
+		t.Errorf("expected matching content, got %+v", v)
+	}
+	if _, v := verify("/ABCDE-01234?sha256=ffff"); v.ContentMatches == nil || *v.ContentMatches || v.Valid {
+		t.Errorf("expected mismatching content to be invalid, got %+v", v)
+	}
+	if code, _ := verify("/ZZZZZ-ZZZZZ"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown serial, got %d", code)
+	}
+
+	revoke := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/ABCDE-01234/revoke", strings.NewReader(`{"reason":"issued in error"}`))
+		h.AdminRoutes().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := revoke(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if _, ok := cache.Get("report"); ok {
+		t.Error("expected the cached report with the revoked serial to be evicted")
+	}
+	if code := revoke(); code != http.StatusConflict {
+		t.Errorf("expected 409 for a second revocation, got %d", code)
+	}
+	if _, v := verify("/ABCDE-01234"); v.Valid || !v.Revoked || v.Record.RevokeReason != "issued in error" || v.Record.RevokedAt == nil {
+		t.Errorf("expected a revoked record, got %+v", v)
+	}
+}
@@ -0,0 +1,124 @@
+package issuance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrUnknownSerial  = errors.New("unknown report serial")
+	ErrSerialExists   = errors.New("report serial already issued")
+	ErrAlreadyRevoked = errors.New("report already revoked")
+)
+
+var recordsBucket = []byte("issued_reports")
+
+// Record is the registry entry of one issued document.
+type Record struct {
+	Serial      string    `json:"serial"`
+	Kind        string    `json:"kind"`
+	StudentID   int       `json:"studentId,omitempty"`
+	Subject     string    `json:"subject,omitempty"`
+	ContentHash string    `json:"contentHash"`
+	Issuer      string    `json:"issuer,omitempty"`
+	IssuedAt    time.Time `json:"issuedAt"`
+
+	Revoked      bool       `json:"revoked"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	RevokeReason string     `json:"revokeReason,omitempty"`
+}
+
+// Store keeps issued records in a bbolt database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the registry database at path.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("report registry: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("report registry %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(recordsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Create adds a record. Serials are never reused.
+func (s *Store) Create(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(recordsBucket)
+		if b.Get([]byte(rec.Serial)) != nil {
+			return ErrSerialExists
+		}
+		return b.Put([]byte(rec.Serial), data)
+	})
+}
+
+// Get returns the record of serial.
+func (s *Store) Get(serial string) (*Record, error) {
+	var rec Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(recordsBucket).Get([]byte(serial))
+		if data == nil {
+			return ErrUnknownSerial
+		}
+		return json.Unmarshal(data, &rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Revoke marks serial as revoked and returns the updated record.
+func (s *Store) Revoke(serial, reason string) (*Record, error) {
+	var rec Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(recordsBucket)
+		data := b.Get([]byte(serial))
+		if data == nil {
+			return ErrUnknownSerial
+		}
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		if rec.Revoked {
+			return ErrAlreadyRevoked
+		}
+		now := time.Now().UTC()
+		rec.Revoked, rec.RevokedAt, rec.RevokeReason = true, &now, reason
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(serial), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
@@ -0,0 +1,87 @@
+package jobs
+
+// Event types streamed to job subscribers.
+const (
+	EventProgress = "progress"
+	EventWarning  = "warning"
+	EventComplete = "complete"
+)
+
+// Event is one entry of a job's event log. IDs increase by one per job and
+// let reconnecting clients resume after the last event they saw. The log is
+// kept in memory and rebuilt from the job state after a restart.
+type Event struct {
+	ID   int
+	Type string
+	Data any
+}
+
+// ProgressEvent reports the state and counters of a job.
+type ProgressEvent struct {
+	State State `json:"state"`
+	Progress
+}
+
+// CompleteEvent is the last event of a job.
+type CompleteEvent struct {
+	State       State  `json:"state"`
+	Error       string `json:"error,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// emit appends an event and wakes the subscribers. The caller holds m.mu.
+func (m *Manager) emit(e *entry, typ string, data any) {
+	e.events = append(e.events, Event{ID: len(e.events) + 1, Type: typ, Data: data})
+	if e.notify != nil {
+		close(e.notify)
+		e.notify = nil
+	}
+}
+
+// emitState emits the current progress and, for finished jobs, the
+// completion. The caller holds m.mu.
+func (m *Manager) emitState(e *entry) {
+	m.emit(e, EventProgress, ProgressEvent{State: e.job.State, Progress: e.job.Progress})
+	if !e.job.State.Finished() {
+		return
+	}
+	done := CompleteEvent{State: e.job.State, Error: e.job.Error}
+	if e.job.State == StateSucceeded {
+		done.FileName, done.DownloadURL = e.job.FileName, BasePath+e.job.ID+"/result"
+	}
+	m.emit(e, EventComplete, done)
+}
+
+// changed persists the job and tells subscribers about its new state. The
+// caller holds m.mu or owns e exclusively.
+func (m *Manager) changed(e *entry) {
+	m.save(e)
+	m.emitState(e)
+}
+
+// Events returns the events of a job after the event with ID after, a
+// channel that is closed when more arrive, and whether the job has finished
+// so that no more will. An ID the log does not know, such as one from
+// before a restart, replays the whole log. Only the session owner sees the
+// events.
+func (m *Manager) Events(id, owner string, after int) ([]Event, <-chan struct{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.lookup(id, owner)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if after < 0 || after > len(e.events) {
+		after = 0
+	}
+	events := append([]Event(nil), e.events[after:]...)
+	finished := e.job.State.Finished()
+	if finished {
+		return events, nil, true, nil
+	}
+	if e.notify == nil {
+		e.notify = make(chan struct{})
+	}
+	return events, e.notify, false, nil
+}
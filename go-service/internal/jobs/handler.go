@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	manager *Manager
+}
+
+func NewHandler(m *Manager) *Handler {
+	return &Handler{manager: m}
+}
+
+// Routes serves the job endpoints, mounted under /api/v1/report-jobs. Every
+// endpoint requires the backend session cookies, and a job is only visible
+// to the session that submitted it.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", h.Submit)
+	r.Get("/{id}", h.Get)
+	r.Delete("/{id}", h.Cancel)
+	r.Get("/{id}/result", h.Result)
+	r.Get("/{id}/events", h.Events)
+	return r
+}
+
+// errorStatus maps job errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidJob), errors.Is(err, report.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobNotReady), errors.Is(err, ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) Submit(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := h.manager.Submit(req, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", BasePath+job.ID)
+	response.JSON(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	job, err := h.manager.Get(chi.URLParam(r, "id"), ownerOf(cookies))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, job)
+}
+
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	job, err := h.manager.Cancel(chi.URLParam(r, "id"), ownerOf(cookies))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, job)
+}
+
+func (h *Handler) Result(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	f, job, err := h.manager.Result(chi.URLParam(r, "id"), ownerOf(cookies))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	defer f.Close()
+
+	// Large archives can take longer to download than the server's global
+	// write timeout allows.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", job.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": job.FileName}))
+	http.ServeContent(w, r, job.FileName, *job.FinishedAt, f)
+}
+
+// keepAliveInterval spaces the comments that keep idle event streams open
+// through proxies.
+const keepAliveInterval = 15 * time.Second
+
+// Events streams the job's progress as Server-Sent Events until the job
+// finishes or the client goes away. Clients reconnecting with Last-Event-ID
+// receive the events they missed.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	id, owner := chi.URLParam(r, "id"), ownerOf(cookies)
+	last, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	events, wait, finished, err := h.manager.Events(id, owner, last)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	// The stream stays open for as long as the job runs, which is usually
+	// longer than the server's global write timeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		for _, ev := range events {
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			last = ev.ID
+		}
+		if err := rc.Flush(); err != nil || finished {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.manager.ctx.Done():
+			// The manager is shutting down; streams are closed so that the
+			// server does not wait for them. Clients reconnect after the
+			// restart.
+			return
+		case <-wait:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if events, wait, finished, err = h.manager.Events(id, owner, last); err != nil {
+			// The job was purged while the stream was open.
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, ev Event) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"net/http"
+	"time"
+)
+
+// BasePath is where the job endpoints are mounted.
+const BasePath = "/api/v1/report-jobs/"
+
+// maxStudentIDs bounds the explicit student list of one job.
+const maxStudentIDs = 1000
+
+var (
+	ErrJobNotFound  = errors.New("report job not found")
+	ErrJobNotReady  = errors.New("report job has no result yet")
+	ErrJobFinished  = errors.New("report job already finished")
+	ErrQueueFull    = errors.New("report job queue is full, try again later")
+	ErrShuttingDown = errors.New("server is shutting down")
+	ErrInvalidJob   = errors.New("invalid report job")
+)
+
+// Kind selects what a job renders.
+type Kind string
+
+const (
+	// KindStudent renders one student report.
+	KindStudent Kind = "student"
+	// KindStudents renders the listed students into a ZIP archive.
+	KindStudents Kind = "students"
+	// KindClass renders every section of a class into a ZIP archive.
+	KindClass Kind = "class"
+	// KindSection renders one section of a class into a ZIP archive.
+	KindSection Kind = "section"
+)
+
+// State is the lifecycle stage of a job.
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// Finished reports whether the job will not change state any more.
+func (s State) Finished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCanceled
+}
+
+// Request describes the reports a job produces.
+type Request struct {
+	Kind       Kind          `json:"kind"`
+	StudentID  int           `json:"studentId,omitempty"`
+	StudentIDs []int         `json:"studentIds,omitempty"`
+	Class      string        `json:"class,omitempty"`
+	Section    string        `json:"section,omitempty"`
+	Template   string        `json:"template,omitempty"`
+	Format     report.Format `json:"format,omitempty"`
+	Encrypt    bool          `json:"encrypt,omitempty"`
+}
+
+// Validate checks that the fields required by the kind are set.
+func (r *Request) Validate() error {
+	switch r.Kind {
+	case KindStudent:
+		if r.StudentID <= 0 {
+			return fmt.Errorf("%w: studentId is required", ErrInvalidJob)
+		}
+	case KindStudents:
+		if len(r.StudentIDs) == 0 || len(r.StudentIDs) > maxStudentIDs {
+			return fmt.Errorf("%w: between 1 and %d studentIds are required", ErrInvalidJob, maxStudentIDs)
+		}
+	case KindClass:
+		if r.Class == "" {
+			return fmt.Errorf("%w: class is required", ErrInvalidJob)
+		}
+	case KindSection:
+		if r.Class == "" || r.Section == "" {
+			return fmt.Errorf("%w: class and section are required", ErrInvalidJob)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidJob, r.Kind)
+	}
+	if r.Format != "" {
+		format, err := report.ParseFormat(string(r.Format))
+		if err != nil {
+			return err
+		}
+		r.Format = format
+	}
+	return nil
+}
+
+// options converts the request into report options.
+func (r *Request) options() student.ReportOptions {
+	return student.ReportOptions{
+		Template:   r.Template,
+		Format:     r.Format,
+		Encryption: report.EncryptionRequest{Encrypt: r.Encrypt},
+	}
+}
+
+// Progress counts the students handled so far.
+type Progress struct {
+	Total  int `json:"total"`
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+	// Current is the student handled last.
+	Current string `json:"current,omitempty"`
+}
+
+// Job is the state of a report job as reported to callers.
+type Job struct {
+	ID          string                 `json:"id"`
+	Request     Request                `json:"request"`
+	State       State                  `json:"state"`
+	Progress    Progress               `json:"progress"`
+	Errors      []student.ArchiveEntry `json:"errors,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	FileName    string                 `json:"fileName,omitempty"`
+	ContentType string                 `json:"contentType,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	StartedAt   *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time             `json:"finishedAt,omitempty"`
+}
+
+// cloneCookies copies the caller's cookies so that the job does not share
+// them with the request they came from.
+func cloneCookies(in []*http.Cookie) []*http.Cookie {
+	out := make([]*http.Cookie, 0, len(in))
+	for _, c := range in {
+		cp := *c
+		out = append(out, &cp)
+	}
+	return out
+}
+
+// ownerOf identifies the session that submitted a job by a hash of its
+// refresh token, which stays the same while the access token is renewed.
+func ownerOf(cookies []*http.Cookie) string {
+	for _, c := range cookies {
+		if c.Name == client.RefreshTokenName && c.Value != "" {
+			sum := sha256.Sum256([]byte(c.Value))
+			return hex.EncodeToString(sum[:])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,540 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/student"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type writerFunc func(w io.Writer) error
+
+func (f writerFunc) Output(w io.Writer) error { return f(w) }
+
+// fakeService implements the report methods used by jobs. The remaining
+// methods of student.Service are not called.
+type fakeService struct {
+	student.Service
+	report func(ctx context.Context, id int, cookies []*http.Cookie) (student.ReportWriter, error)
+	batch  func(ctx context.Context, b student.Batch, progress student.BatchProgress) (student.ReportWriter, error)
+}
+
+func (f *fakeService) GenerateReport(ctx context.Context, id int, _ student.ReportOptions, cookies []*http.Cookie) (student.ReportWriter, error) {
+	return f.report(ctx, id, cookies)
+}
+
+func (f *fakeService) GenerateBatchReports(ctx context.Context, b student.Batch, _ student.ReportOptions, progress student.BatchProgress, _ []*http.Cookie) (student.ReportWriter, error) {
+	return f.batch(ctx, b, progress)
+}
+
+func newTestManager(t *testing.T, svc student.Service, queueSize int) *Manager {
+	t.Helper()
+	m, err := NewManager(svc, Options{Dir: t.TempDir(), Workers: 1, QueueSize: queueSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+func waitFor(t *testing.T, m *Manager, id string, state State) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id, testOwner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State == state {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := m.Get(id, testOwner)
+	t.Fatalf("job %s did not reach %s, last state %+v", id, state, job)
+	return Job{}
+}
+
+// testOwner is the owner key of jobs submitted with sessionCookies.
+var testOwner = ownerOf(sessionCookies())
+
+func sessionCookies() []*http.Cookie {
+	return []*http.Cookie{
+		{Name: client.AccesTokenName, Value: "access"},
+		{Name: client.RefreshTokenName, Value: "refresh"},
+		{Name: client.CSFRTokenName, Value: "csrf"},
+	}
+}
+
+func TestManager_StudentJob(t *testing.T) {
+	svc := &fakeService{report: func(_ context.Context, id int, cookies []*http.Cookie) (student.ReportWriter, error) {
+		if cookies[0].Value != "access" {
+			return nil, errors.New("cookies changed after submission")
+		}
+		return writerFunc(func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "report %d", id)
+			return err
+		}), nil
+	}}
+	m := newTestManager(t, svc, 4)
+
+	cookies := sessionCookies()
+	job, err := m.Submit(Request{Kind: KindStudent, StudentID: 7}, cookies)
+	if err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	// The job keeps its own copy of the caller's cookies.
+	cookies[0].Value = "changed"
+
+	job = waitFor(t, m, job.ID, StateSucceeded)
+	if job.FileName != "student_7_report.pdf" || job.ContentType != "application/pdf" || job.Progress.Done != 1 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	f, _, err := m.Result(job.ID, testOwner)
+	if err != nil {
+		t.Fatalf("unexpected result error: %v", err)
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "report 7" {
+		t.Errorf("unexpected result %q", data)
+	}
+}
+
+func TestManager_BatchProgressAndFailures(t *testing.T) {
+	svc := &fakeService{batch: func(_ context.Context, b student.Batch, progress student.BatchProgress) (student.ReportWriter, error) {
+		return writerFunc(func(w io.Writer) error {
+			for _, id := range b.StudentIDs {
+				e := student.ArchiveEntry{StudentID: id}
+				if id == 2 {
+					e.Error = "backend unavailable"
+				}
+				progress(e, len(b.StudentIDs))
+			}
+			_, err := w.Write([]byte("zip"))
+			return err
+		}), nil
+	}}
+	m := newTestManager(t, svc, 4)
+
+	job, err := m.Submit(Request{Kind: KindStudents, StudentIDs: []int{1, 2, 3}, Format: "CSV"}, sessionCookies())
+	if err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	job = waitFor(t, m, job.ID, StateSucceeded)
+	if job.Progress.Total != 3 || job.Progress.Done != 3 || job.Progress.Failed != 1 || len(job.Errors) != 1 || job.Errors[0].StudentID != 2 {
+		t.Errorf("unexpected progress %+v, errors %+v", job.Progress, job.Errors)
+	}
+	if job.Request.Format != "csv" || job.ContentType != "application/zip" {
+		t.Errorf("unexpected job %+v", job)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	svc := &fakeService{report: func(ctx context.Context, _ int, _ []*http.Cookie) (student.ReportWriter, error) {
+		return writerFunc(func(io.Writer) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}), nil
+	}}
+	m := newTestManager(t, svc, 1)
+
+	running, _ := m.Submit(Request{Kind: KindStudent, StudentID: 1}, sessionCookies())
+	<-started
+	queued, err := m.Submit(Request{Kind: KindStudent, StudentID: 2}, sessionCookies())
+	if err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	if _, err := m.Submit(Request{Kind: KindStudent, StudentID: 3}, sessionCookies()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	if job, err := m.Cancel(queued.ID, testOwner); err != nil || job.State != StateCanceled {
+		t.Errorf("expected the queued job to be canceled, got %+v, %v", job, err)
+	}
+	if _, err := m.Cancel(running.ID, testOwner); err != nil {
+		t.Fatalf("unexpected cancel error: %v", err)
+	}
+	waitFor(t, m, running.ID, StateCanceled)
+	if _, err := m.Cancel(running.ID, testOwner); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("expected ErrJobFinished, got %v", err)
+	}
+	if _, _, err := m.Result(running.ID, testOwner); !errors.Is(err, ErrJobNotReady) {
+		t.Errorf("expected ErrJobNotReady, got %v", err)
+	}
+}
+
+func TestRequest_Validate(t *testing.T) {
+	for name, req := range map[string]Request{
+		"unknown kind":    {Kind: "school"},
+		"missing student": {Kind: KindStudent},
+		"empty list":      {Kind: KindStudents},
+		"missing class":   {Kind: KindClass},
+		"missing section": {Kind: KindSection, Class: "10"},
+		"bad format":      {Kind: KindClass, Class: "10", Format: "doc"},
+	} {
+		if err := req.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	release := make(chan struct{})
+	svc := &fakeService{report: func(context.Context, int, []*http.Cookie) (student.ReportWriter, error) {
+		return writerFunc(func(w io.Writer) error {
+			<-release
+			_, err := w.Write([]byte("%PDF"))
+			return err
+		}), nil
+	}}
+	m := newTestManager(t, svc, 4)
+	router := NewHandler(m).Routes()
+
+	do := func(method, target, body string, withCookies bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if withCookies {
+			for _, c := range sessionCookies() {
+				req.AddCookie(c)
+			}
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/", `{"kind":"student","studentId":4}`, false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without session, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/", `{"kind":"class"}`, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid job, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/", `{"kind":"student","studentId":4}`, true)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data Job `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	id := resp.Data.ID
+	if rec.Header().Get("Location") != "/api/v1/report-jobs/"+id {
+		t.Errorf("unexpected location %q", rec.Header().Get("Location"))
+	}
+
+	if rec := do(http.MethodGet, "/"+id+"/result", "", true); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 before the job finished, got %d", rec.Code)
+	}
+	close(release)
+	waitFor(t, m, id, StateSucceeded)
+
+	rec = do(http.MethodGet, "/"+id+"/result", "", true)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), []byte("%PDF")) {
+		t.Errorf("unexpected result %d %q", rec.Code, rec.Body)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "student_4_report.pdf") {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	if rec := do(http.MethodGet, "/missing", "", true); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", rec.Code)
+	}
+
+	// Another session learning the job ID must not see the job.
+	for _, route := range []struct{ method, target string }{
+		{http.MethodGet, "/" + id},
+		{http.MethodGet, "/" + id + "/result"},
+		{http.MethodGet, "/" + id + "/events"},
+		{http.MethodDelete, "/" + id},
+	} {
+		req := httptest.NewRequest(route.method, route.target, nil)
+		for _, c := range sessionCookies() {
+			if c.Name == client.RefreshTokenName {
+				c.Value = "someone-else"
+			}
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404 for another session, got %d", route.method, route.target, rec.Code)
+		}
+	}
+}
+
+func TestManager_ResumeAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	opts := Options{Dir: filepath.Join(dir, "results"), Workers: 1, Store: store, CookieSecret: "secret"}
+
+	started := make(chan struct{}, 1)
+	blocking := &fakeService{report: func(ctx context.Context, _ int, _ []*http.Cookie) (student.ReportWriter, error) {
+		return writerFunc(func(io.Writer) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}), nil
+	}}
+	first, err := NewManager(blocking, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Start()
+	running, _ := first.Submit(Request{Kind: KindStudent, StudentID: 1}, sessionCookies())
+	<-started
+	queued, _ := first.Submit(Request{Kind: KindStudent, StudentID: 2}, sessionCookies())
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The running job was checkpointed instead of canceled.
+	if job, _ := first.Get(running.ID, testOwner); job.State != StateQueued {
+		t.Errorf("expected a checkpointed job, got %+v", job)
+	}
+
+	var resumed []int
+	working := &fakeService{report: func(_ context.Context, id int, cookies []*http.Cookie) (student.ReportWriter, error) {
+		if len(cookies) != 3 || cookies[0].Value != "access" {
+			return nil, errors.New("session cookies were not restored")
+		}
+		resumed = append(resumed, id)
+		return writerFunc(func(w io.Writer) error {
+			_, err := w.Write([]byte("done"))
+			return err
+		}), nil
+	}}
+	second, err := NewManager(working, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Start()
+	waitFor(t, second, running.ID, StateSucceeded)
+	waitFor(t, second, queued.ID, StateSucceeded)
+	second.Shutdown(context.Background())
+	if len(resumed) != 2 || resumed[0] != 1 {
+		t.Errorf("expected both jobs to resume in order, got %v", resumed)
+	}
+
+	// Finished results stay downloadable after another restart, and the
+	// session cookies are no longer stored.
+	third, err := NewManager(working, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, job, err := third.Result(running.ID, testOwner)
+	if err != nil {
+		t.Fatalf("unexpected result error: %v", err)
+	}
+	f.Close()
+	if job.FileName != "student_1_report.pdf" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	recs, _ := store.all()
+	for _, rec := range recs {
+		if rec.Cookies != nil {
+			t.Errorf("job %s still stores session cookies", rec.Job.ID)
+		}
+	}
+}
+
+func TestManager_ResumeWithoutSecret(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	opts := Options{Dir: dir, Store: store}
+
+	first, _ := NewManager(&fakeService{}, opts)
+	job, err := first.Submit(Request{Kind: KindStudent, StudentID: 1}, sessionCookies())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Shutdown(context.Background())
+
+	second, err := NewManager(&fakeService{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := second.Get(job.ID, testOwner); job.State != StateFailed || job.Error != ErrSessionLost.Error() {
+		t.Errorf("expected ErrSessionLost, got %+v", job)
+	}
+}
+
+func TestManager_Purge(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	svc := &fakeService{report: func(context.Context, int, []*http.Cookie) (student.ReportWriter, error) {
+		return writerFunc(func(w io.Writer) error {
+			_, err := w.Write([]byte("done"))
+			return err
+		}), nil
+	}}
+	m, err := NewManager(svc, Options{Dir: dir, Store: store, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+	defer m.Shutdown(context.Background())
+	job, _ := m.Submit(Request{Kind: KindStudent, StudentID: 1}, sessionCookies())
+	waitFor(t, m, job.ID, StateSucceeded)
+
+	m.Purge(time.Now())
+	if _, err := m.Get(job.ID, testOwner); err != nil {
+		t.Errorf("expected a recent job to be kept, got %v", err)
+	}
+	m.Purge(time.Now().Add(2 * time.Hour))
+	if _, err := m.Get(job.ID, testOwner); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected the job to be purged, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, job.ID)); !os.IsNotExist(err) {
+		t.Errorf("expected the result file to be removed, got %v", err)
+	}
+	if recs, _ := store.all(); len(recs) != 0 {
+		t.Errorf("expected no stored jobs, got %d", len(recs))
+	}
+}
+
+type sseEvent struct {
+	id, typ, data string
+}
+
+// readEvents reads Server-Sent Events until the stream ends.
+func readEvents(t *testing.T, url string, lastID string) []sseEvent {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for _, c := range sessionCookies() {
+		req.AddCookie(c)
+	}
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var events []sseEvent
+	var cur sseEvent
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if cur.typ != "" {
+				events = append(events, cur)
+			}
+			cur = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur.typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+func TestHandler_Events(t *testing.T) {
+	release := make(chan struct{})
+	svc := &fakeService{batch: func(_ context.Context, b student.Batch, progress student.BatchProgress) (student.ReportWriter, error) {
+		return writerFunc(func(w io.Writer) error {
+			<-release
+			progress(student.ArchiveEntry{StudentID: 1, Name: "Ann"}, 2)
+			progress(student.ArchiveEntry{StudentID: 2, Name: "Bob", Error: "not found"}, 2)
+			_, err := w.Write([]byte("zip"))
+			return err
+		}), nil
+	}}
+	m := newTestManager(t, svc, 4)
+	srv := httptest.NewServer(NewHandler(m).Routes())
+	defer srv.Close()
+
+	job, err := m.Submit(Request{Kind: KindSection, Class: "10", Section: "A"}, sessionCookies())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, m, job.ID, StateRunning)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	events := readEvents(t, srv.URL+"/"+job.ID+"/events", "")
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.typ)
+	}
+	want := "progress progress progress warning progress progress complete"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("expected events %q, got %q", want, got)
+	}
+	var done CompleteEvent
+	json.Unmarshal([]byte(events[len(events)-1].data), &done)
+	if done.State != StateSucceeded || done.DownloadURL != BasePath+job.ID+"/result" {
+		t.Errorf("unexpected completion %+v", done)
+	}
+	var progress ProgressEvent
+	json.Unmarshal([]byte(events[4].data), &progress)
+	if progress.Done != 2 || progress.Total != 2 || progress.Failed != 1 || progress.Current != "Bob" {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+
+	// Reconnecting replays only the events after Last-Event-ID.
+	resumed := readEvents(t, srv.URL+"/"+job.ID+"/events", events[4].id)
+	if len(resumed) != 2 || resumed[0].id != events[5].id || resumed[1].typ != EventComplete {
+		t.Errorf("unexpected resumed events %+v", resumed)
+	}
+}
+
+func TestHandler_EventsEndOnShutdown(t *testing.T) {
+	svc := &fakeService{report: func(ctx context.Context, _ int, _ []*http.Cookie) (student.ReportWriter, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	m := newTestManager(t, svc, 4)
+	srv := httptest.NewServer(NewHandler(m).Routes())
+	defer srv.Close()
+
+	job, _ := m.Submit(Request{Kind: KindStudent, StudentID: 1}, sessionCookies())
+	waitFor(t, m, job.ID, StateRunning)
+
+	done := make(chan []sseEvent)
+	go func() { done <- readEvents(t, srv.URL+"/"+job.ID+"/events", "") }()
+	time.Sleep(50 * time.Millisecond)
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the event stream to end when the manager shuts down")
+	}
+}
@@ -0,0 +1,536 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers   = 2
+	defaultQueueSize = 64
+)
+
+// Options configure a Manager.
+type Options struct {
+	// Dir holds the result files, a temporary directory when empty.
+	Dir string
+	// Workers and QueueSize bound running and waiting jobs. Non-positive
+	// values use defaults.
+	Workers   int
+	QueueSize int
+	// Store persists jobs across restarts. Without it jobs live in memory.
+	Store *Store
+	// Retention is how long finished jobs and their results are kept. Zero
+	// keeps them forever.
+	Retention time.Duration
+	// CookieSecret encrypts the session cookies of unfinished jobs in the
+	// store so that they can resume after a restart. Without it such jobs
+	// fail with ErrSessionLost after a restart.
+	CookieSecret string
+	// Notify is called with every job that finished, whatever the outcome.
+	// It is called with the manager's lock held and must not block.
+	Notify func(Job)
+}
+
+// entry is the manager's view of a job. The caller's cookies and the cancel
+// function only live in memory and are dropped once the job has finished.
+// Only the session in owner may see the job.
+type entry struct {
+	job      Job
+	owner    string
+	cookies  []*http.Cookie
+	sealed   []byte
+	cancel   context.CancelFunc
+	canceled bool
+	events   []Event
+	notify   chan struct{}
+}
+
+// Manager queues report jobs and runs them on a bounded pool of workers.
+// Results are written to files in the result directory.
+type Manager struct {
+	service   student.Service
+	dir       string
+	workers   int
+	queue     chan *entry
+	store     *Store
+	sealer    *cookieSealer
+	retention time.Duration
+	notify    func(Job)
+
+	ctx    context.Context
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	jobs   map[string]*entry
+	closed bool
+}
+
+// NewManager creates the result directory and reloads the jobs of the
+// store. Unfinished jobs are queued again ahead of new submissions.
+func NewManager(svc student.Service, opts Options) (*Manager, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(os.TempDir(), "report-jobs")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("report jobs: %v", err)
+	}
+	sealer, err := newCookieSealer(opts.CookieSecret)
+	if err != nil {
+		return nil, fmt.Errorf("report jobs: %v", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		service:   svc,
+		dir:       opts.Dir,
+		workers:   opts.Workers,
+		store:     opts.Store,
+		sealer:    sealer,
+		retention: opts.Retention,
+		notify:    opts.Notify,
+		ctx:       ctx,
+		stop:      stop,
+		jobs:      map[string]*entry{},
+	}
+
+	pending, err := m.load()
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("report jobs: %v", err)
+	}
+	m.queue = make(chan *entry, opts.QueueSize+len(pending))
+	for _, e := range pending {
+		m.queue <- e
+	}
+	return m, nil
+}
+
+// load restores the stored jobs and returns the unfinished ones in
+// submission order. Partial results of interrupted runs are removed.
+func (m *Manager) load() ([]*entry, error) {
+	parts, _ := filepath.Glob(filepath.Join(m.dir, "*.part"))
+	for _, p := range parts {
+		os.Remove(p)
+	}
+	if m.store == nil {
+		return nil, nil
+	}
+	recs, err := m.store.all()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*entry
+	for _, rec := range recs {
+		e := &entry{job: rec.Job, owner: rec.Owner, sealed: rec.Cookies}
+		m.jobs[e.job.ID] = e
+		switch {
+		case e.job.State == StateSucceeded:
+			if _, err := os.Stat(m.resultPath(e.job.ID)); err != nil {
+				e.job.State, e.job.Error = StateFailed, "result file is missing"
+				m.save(e)
+			}
+			m.emitState(e)
+		case e.job.State.Finished():
+			m.emitState(e)
+		default:
+			// Jobs interrupted by a crash are started over like checkpointed
+			// ones.
+			resetForResume(e)
+			cookies, err := m.sealer.open(e.sealed)
+			if err != nil {
+				m.finish(e, StateFailed, err)
+				m.changed(e)
+				continue
+			}
+			e.cookies = cookies
+			m.changed(e)
+			pending = append(pending, e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].job.CreatedAt.Before(pending[j].job.CreatedAt) })
+	return pending, nil
+}
+
+// resetForResume puts a job back into the queue state. Runs start over, so
+// progress is discarded.
+func resetForResume(e *entry) {
+	e.job.State, e.job.StartedAt = StateQueued, nil
+	e.job.Progress, e.job.Errors, e.job.Error = Progress{}, nil, ""
+}
+
+// save persists the job when a store is configured. The caller holds m.mu
+// or owns e exclusively.
+func (m *Manager) save(e *entry) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.put(storedJob{Job: e.job, Owner: e.owner, Cookies: e.sealed}); err != nil {
+		log.Printf("report job %s: saving state: %v", e.job.ID, err)
+	}
+}
+
+// Start launches the workers and the purge of expired results.
+func (m *Manager) Start() {
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for {
+				select {
+				case <-m.ctx.Done():
+					return
+				case e := <-m.queue:
+					m.run(e)
+				}
+			}
+		}()
+	}
+
+	if m.retention > 0 {
+		m.Purge(time.Now())
+		interval := min(max(m.retention/4, time.Minute), time.Hour)
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-m.ctx.Done():
+					return
+				case now := <-ticker.C:
+					m.Purge(now)
+				}
+			}
+		}()
+	}
+}
+
+// Shutdown stops accepting jobs and checkpoints the running ones, which are
+// stored as queued to resume after a restart. It waits for the workers to
+// return or ctx to expire.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	m.stop()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Purge removes finished jobs, and their results, that finished more than
+// the retention period before now.
+func (m *Manager) Purge(now time.Time) {
+	if m.retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-m.retention)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, e := range m.jobs {
+		if !e.job.State.Finished() || e.job.FinishedAt == nil || e.job.FinishedAt.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(m.resultPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("report job %s: removing result: %v", id, err)
+			continue
+		}
+		if m.store != nil {
+			if err := m.store.delete(id); err != nil {
+				log.Printf("report job %s: purging: %v", id, err)
+				continue
+			}
+		}
+		delete(m.jobs, id)
+	}
+}
+
+// Submit queues a job run with a copy of the caller's cookies. The job
+// belongs to the caller's session from then on.
+func (m *Manager) Submit(req Request, cookies []*http.Cookie) (Job, error) {
+	if err := req.Validate(); err != nil {
+		return Job{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	e := &entry{
+		job:     Job{ID: id, Request: req, State: StateQueued, CreatedAt: time.Now().UTC()},
+		owner:   ownerOf(cookies),
+		cookies: cloneCookies(cookies),
+	}
+	if m.store != nil {
+		if e.sealed, err = m.sealer.seal(e.cookies); err != nil {
+			return Job{}, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrShuttingDown
+	}
+	if len(m.queue) == cap(m.queue) {
+		return Job{}, ErrQueueFull
+	}
+	// The job is stored before it can be picked up so that a worker never
+	// saves a state older than the submission.
+	if m.store != nil {
+		if err := m.store.put(storedJob{Job: e.job, Owner: e.owner, Cookies: e.sealed}); err != nil {
+			return Job{}, err
+		}
+	}
+	m.jobs[id] = e
+	m.emitState(e)
+	// Only Submit sends while holding m.mu, so the checked slot is free.
+	m.queue <- e
+	return e.job, nil
+}
+
+// lookup finds a job of the session owner. Jobs of other sessions are
+// reported as not found. The caller holds m.mu.
+func (m *Manager) lookup(id, owner string) (*entry, error) {
+	e, ok := m.jobs[id]
+	if !ok || e.owner == "" || subtle.ConstantTimeCompare([]byte(e.owner), []byte(owner)) != 1 {
+		return nil, ErrJobNotFound
+	}
+	return e, nil
+}
+
+// Get returns a snapshot of a job of the session owner.
+func (m *Manager) Get(id, owner string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.lookup(id, owner)
+	if err != nil {
+		return Job{}, err
+	}
+	return snapshot(e), nil
+}
+
+// Cancel stops a queued or running job of the session owner.
+func (m *Manager) Cancel(id, owner string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, err := m.lookup(id, owner)
+	if err != nil {
+		return Job{}, err
+	}
+	switch {
+	case e.job.State.Finished():
+		return Job{}, ErrJobFinished
+	case e.job.State == StateQueued:
+		// The worker skips it when it comes up.
+		m.finish(e, StateCanceled, context.Canceled)
+		m.changed(e)
+	default:
+		e.canceled = true
+		e.cancel()
+	}
+	return snapshot(e), nil
+}
+
+// Result opens the output of a succeeded job of the session owner.
+func (m *Manager) Result(id, owner string) (*os.File, Job, error) {
+	job, err := m.Get(id, owner)
+	if err != nil {
+		return nil, Job{}, err
+	}
+	if job.State != StateSucceeded {
+		return nil, job, ErrJobNotReady
+	}
+	f, err := os.Open(m.resultPath(id))
+	if err != nil {
+		return nil, job, err
+	}
+	return f, job, nil
+}
+
+func (m *Manager) resultPath(id string) string {
+	return filepath.Join(m.dir, id)
+}
+
+func (m *Manager) run(e *entry) {
+	m.mu.Lock()
+	if e.job.State != StateQueued || m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	e.job.State, e.job.StartedAt, e.cancel = StateRunning, &now, cancel
+	req, cookies := e.job.Request, e.cookies
+	m.changed(e)
+	m.mu.Unlock()
+
+	name, contentType, err := m.execute(ctx, e, req, cookies)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case err == nil:
+		e.job.FileName, e.job.ContentType = name, contentType
+		m.finish(e, StateSucceeded, nil)
+	case e.canceled:
+		m.finish(e, StateCanceled, err)
+	case m.ctx.Err() != nil:
+		// Checkpoint: the job keeps its stored cookies and runs again after
+		// the restart.
+		resetForResume(e)
+		e.cancel = nil
+	default:
+		m.finish(e, StateFailed, err)
+	}
+	m.changed(e)
+}
+
+// finish records the final state and forgets the caller's cookies. The
+// caller holds m.mu.
+func (m *Manager) finish(e *entry, state State, err error) {
+	now := time.Now().UTC()
+	e.job.State, e.job.FinishedAt = state, &now
+	if err != nil {
+		e.job.Error = err.Error()
+	}
+	e.cookies, e.sealed, e.cancel = nil, nil, nil
+	if m.notify != nil {
+		m.notify(snapshot(e))
+	}
+}
+
+// execute renders the job into its result file and returns the download
+// name and content type.
+func (m *Manager) execute(ctx context.Context, e *entry, req Request, cookies []*http.Cookie) (string, string, error) {
+	opts := req.options()
+	format := opts.Format
+	if format == "" {
+		format = report.FormatPDF
+	}
+
+	var (
+		rep         student.ReportWriter
+		name        string
+		contentType string
+		err         error
+	)
+	if req.Kind == KindStudent {
+		m.setProgress(e, Progress{Total: 1})
+		rep, err = m.service.GenerateReport(ctx, req.StudentID, opts, cookies)
+		name, contentType = fmt.Sprintf("student_%d_report.%s", req.StudentID, format.Extension()), format.ContentType()
+	} else {
+		batch := student.Batch{StudentIDs: req.StudentIDs, Class: req.Class, Section: req.Section}
+		rep, err = m.service.GenerateBatchReports(ctx, batch, opts, m.progress(e), cookies)
+		name, contentType = archiveName(req), "application/zip"
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// The result is written next to its final name and renamed once
+	// complete, so a download never sees a partial file.
+	path := m.resultPath(e.job.ID)
+	f, err := os.OpenFile(path+".part", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", "", err
+	}
+	err = rep.Output(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		os.Remove(path + ".part")
+		return "", "", err
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return "", "", err
+	}
+	if req.Kind == KindStudent {
+		m.setProgress(e, Progress{Total: 1, Done: 1})
+	}
+	return name, contentType, nil
+}
+
+func (m *Manager) setProgress(e *entry, p Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.job.Progress = p
+	m.emit(e, EventProgress, ProgressEvent{State: e.job.State, Progress: p})
+}
+
+// progress records per-student outcomes of archive jobs.
+func (m *Manager) progress(e *entry) student.BatchProgress {
+	return func(res student.ArchiveEntry, total int) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		e.job.Progress.Total = total
+		e.job.Progress.Done++
+		e.job.Progress.Current = res.Name
+		if res.Error != "" {
+			e.job.Progress.Failed++
+			e.job.Errors = append(e.job.Errors, res)
+			m.emit(e, EventWarning, res)
+		}
+		m.emit(e, EventProgress, ProgressEvent{State: e.job.State, Progress: e.job.Progress})
+	}
+}
+
+func archiveName(req Request) string {
+	switch req.Kind {
+	case KindClass:
+		return fmt.Sprintf("class_%s_reports.zip", req.Class)
+	case KindSection:
+		return fmt.Sprintf("class_%s_section_%s_reports.zip", req.Class, req.Section)
+	}
+	return "student_reports.zip"
+}
+
+// snapshot copies the job so that callers can use it without the lock.
+func snapshot(e *entry) Job {
+	job := e.job
+	job.Errors = append([]student.ArchiveEntry(nil), e.job.Errors...)
+	return job
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrSessionLost = errors.New("the session of this job was not kept across a restart, submit it again")
+)
+
+var jobsBucket = []byte("report_jobs")
+
+// storedJob is what is persisted per job. The caller's cookies are only kept,
+// encrypted, until the job has finished; the owner key is kept for good.
+type storedJob struct {
+	Job     Job    `json:"job"`
+	Owner   string `json:"owner"`
+	Cookies []byte `json:"cookies,omitempty"`
+}
+
+// Store persists jobs in a bbolt database file so that they survive
+// restarts.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the job database at path.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("report job store: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("report job store %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) put(rec storedJob) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(rec.Job.ID), data)
+	})
+}
+
+func (s *Store) delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	})
+}
+
+func (s *Store) all() ([]storedJob, error) {
+	var recs []storedJob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, v []byte) error {
+			var rec storedJob
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	return recs, err
+}
+
+// cookieSealer encrypts session cookies with AES-GCM before they are
+// written to disk.
+type cookieSealer struct {
+	aead cipher.AEAD
+}
+
+// newCookieSealer derives the key from secret. Without a secret cookies are
+// not persisted.
+func newCookieSealer(secret string) (*cookieSealer, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &cookieSealer{aead: aead}, nil
+}
+
+type sealedCookie struct {
+	Name  string `json:"n"`
+	Value string `json:"v"`
+}
+
+func (s *cookieSealer) seal(cookies []*http.Cookie) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	plain := make([]sealedCookie, 0, len(cookies))
+	for _, c := range cookies {
+		plain = append(plain, sealedCookie{Name: c.Name, Value: c.Value})
+	}
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (s *cookieSealer) open(sealed []byte) ([]*http.Cookie, error) {
+	if s == nil || len(sealed) < s.aead.NonceSize() {
+		return nil, ErrSessionLost
+	}
+	n := s.aead.NonceSize()
+	data, err := s.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, ErrSessionLost
+	}
+	var plain []sealedCookie
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, ErrSessionLost
+	}
+	cookies := make([]*http.Cookie, 0, len(plain))
+	for _, c := range plain {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies, nil
+}
@@ -0,0 +1,98 @@
+package leave
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves leave history reports, mounted under /api/v1/reports/leave.
+// Reports on anyone but the caller need an admin session on the backend.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/users/{id}", h.UserReport)
+	r.Get("/departments/{id}", h.DepartmentReport)
+	return r
+}
+
+// errorStatus maps leave report errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, report.ErrInvalidPeriod), errors.Is(err, report.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) UserReport(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, ScopeUser, h.service.GenerateUserReport)
+}
+
+func (h *Handler) DepartmentReport(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, ScopeDepartment, h.service.GenerateDepartmentReport)
+}
+
+type generateFunc func(ctx context.Context, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, scope Scope, generate generateFunc) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	period, err := ParsePeriod(r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	format, err := report.NegotiateFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"), report.FormatPDF, report.FormatCSV)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := generate(r.Context(), id, Options{Period: period, Format: format}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	name := fmt.Sprintf("leave_%s_%d_%s_%s.%s", scope, id, period.From.Format("20060102"), period.To.Format("20060102"), format.Extension())
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
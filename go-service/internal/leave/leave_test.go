@@ -0,0 +1,165 @@
+package leave
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func sampleLeaves() []models.Leave {
+	return []models.Leave{
+		{ID: 1, UserID: 4, User: "Dana", DepartmentID: 2, Department: "Science", Policy: "Sick", From: day("2024-03-04"), To: day("2024-03-06"), StatusID: models.LeaveApproved, Status: "Approved", Approver: "Walter"},
+		{ID: 2, UserID: 4, User: "Dana", DepartmentID: 2, Department: "Science", Policy: "Annual", From: day("2024-12-30"), To: day("2025-01-03"), StatusID: models.LeaveOnReview, Status: "On Review"},
+		{ID: 3, UserID: 4, User: "Dana", DepartmentID: 2, Department: "Science", Policy: "Sick", From: day("2024-05-01"), To: day("2024-05-01"), StatusID: models.LeaveCanceled, Status: "Cancelled"},
+		{ID: 4, UserID: 4, User: "Dana", DepartmentID: 2, Department: "Science", Policy: "Annual", From: day("2023-12-20"), To: day("2023-12-22"), StatusID: models.LeaveApproved, Status: "Approved"},
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	p, err := ParsePeriod("", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.From.Format(time.DateOnly) != "2024-01-01" || p.To.Format(time.DateOnly) != "2024-12-31" {
+		t.Errorf("unexpected default period %v", p)
+	}
+	for _, tc := range [][2]string{{"2024-13-01", ""}, {"", "yesterday"}, {"2024-05-01", "2024-04-30"}} {
+		if _, err := ParsePeriod(tc[0], tc[1], now); !errors.Is(err, report.ErrInvalidPeriod) {
+			t.Errorf("ParsePeriod(%q, %q): expected ErrInvalidPeriod, got %v", tc[0], tc[1], err)
+		}
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	period, _ := ParsePeriod("2024-01-01", "2024-12-31", time.Now())
+	h, err := buildHistory(ScopeUser, 4, period, sampleLeaves())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Subject != "Dana" {
+		t.Errorf("unexpected subject %q", h.Subject)
+	}
+	// The December 2023 leave lies outside the period.
+	if len(h.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(h.Entries))
+	}
+	if e := h.Entries[2]; e.ID != 2 || e.Days != 5 || e.Counted != 2 {
+		t.Errorf("expected the year-end leave to count 2 of 5 days, got %+v", e)
+	}
+	want := []PolicyTotal{
+		{Policy: "Annual", Requests: 1, Approved: 0, Pending: 2},
+		{Policy: "Sick", Requests: 2, Approved: 3, Pending: 0},
+	}
+	if len(h.Totals) != len(want) {
+		t.Fatalf("unexpected totals %+v", h.Totals)
+	}
+	for i := range want {
+		if h.Totals[i] != want[i] {
+			t.Errorf("total %d: expected %+v, got %+v", i, want[i], h.Totals[i])
+		}
+	}
+
+	if _, err := buildHistory(ScopeUser, 5, period, sampleLeaves()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for another user's leave, got %v", err)
+	}
+	if _, err := buildHistory(ScopeDepartment, 3, period, sampleLeaves()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for another department's leave, got %v", err)
+	}
+	if h, _ := buildHistory(ScopeDepartment, 3, period, nil); h.Subject != "department 3" {
+		t.Errorf("unexpected subject of an empty report %q", h.Subject)
+	}
+}
+
+type fakeBackend struct {
+	client.IBackend
+	filters []models.LeaveFilter
+}
+
+// ListLeaves answers like the backend for a user without admin rights: user
+// 4's own leave, whatever was asked for.
+func (f *fakeBackend) ListLeaves(ctx context.Context, filter models.LeaveFilter, cookies []*http.Cookie) ([]models.Leave, error) {
+	f.filters = append(f.filters, filter)
+	return sampleLeaves(), nil
+}
+
+func TestHandler(t *testing.T) {
+	backend := &fakeBackend{}
+	routes := NewHandler(NewService(backend, nil, nil, nil)).Routes()
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+		prefix string
+	}{
+		{"pdf", "/users/4?from=2024-01-01&to=2024-12-31", http.StatusOK, "%PDF"},
+		{"csv", "/users/4?from=2024-01-01&to=2024-12-31&format=csv", http.StatusOK, "row,id,userId"},
+		{"department", "/departments/2?from=2024-01-01&to=2024-12-31", http.StatusOK, "%PDF"},
+		{"other user", "/users/5", http.StatusForbidden, ""},
+		{"xlsx", "/users/4?format=xlsx", http.StatusBadRequest, ""},
+		{"bad period", "/users/4?from=2024-02-30", http.StatusBadRequest, ""},
+		{"bad id", "/departments/science", http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+			continue
+		}
+		if !strings.HasPrefix(rec.Body.String(), tc.prefix) {
+			t.Errorf("%s: unexpected body %.40q", tc.name, rec.Body)
+		}
+	}
+
+	f := backend.filters[2]
+	if f.DepartmentID != 2 || f.UserID != 0 || f.From.Format(time.DateOnly) != "2024-01-01" {
+		t.Errorf("unexpected department filter %+v", f)
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/users/4?from=2024-01-01&to=2024-12-31&format=csv", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=leave_user_4_20240101_20241231.csv" {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(rec.Body.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+3+2 {
+		t.Fatalf("expected a header, 3 leave and 2 total rows, got %d", len(rows))
+	}
+	if got := rows[1]; got[0] != "leave" || got[5] != "Sick" || got[6] != "2024-03-04" || got[9] != "3" || got[11] != "Walter" {
+		t.Errorf("unexpected leave row %q", got)
+	}
+	if got := rows[5]; got[0] != "total" || got[5] != "Sick" || got[12] != "2" || got[13] != "3" {
+		t.Errorf("unexpected total row %q", got)
+	}
+
+	// Accept naming a format the report lacks falls back to the PDF.
+	req := testutil.AuthedRequest(http.MethodGet, "/users/4?from=2024-01-01&to=2024-12-31", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("expected the PDF, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
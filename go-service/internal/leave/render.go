@@ -0,0 +1,153 @@
+package leave
+
+import (
+	"encoding/csv"
+	"fmt"
+	"goservice/internal/report"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const dayLayout = "02 Jan 2006"
+
+var (
+	titleFont  = report.Font{Style: "B", Size: 16}
+	headFont   = report.Font{Style: "B", Size: 12}
+	textFont   = report.Font{Size: 10}
+	tableHead  = report.Font{Style: "B", Size: 9}
+	tableFont  = report.Font{Size: 9}
+	rowHeight  = 6.0
+	staffWidth = 34.0
+)
+
+// column is a table column of the PDF. Department reports add a staff
+// column in front.
+type column struct {
+	title string
+	width float64
+	align string
+	value func(Entry) string
+}
+
+var columns = []column{
+	{"Policy", 36, "L", func(e Entry) string { return e.Policy }},
+	{"From", 24, "L", func(e Entry) string { return e.From.Format(dayLayout) }},
+	{"To", 24, "L", func(e Entry) string { return e.To.Format(dayLayout) }},
+	{"Days", 13, "R", func(e Entry) string { return strconv.Itoa(e.Days) }},
+	{"Counted", 17, "R", func(e Entry) string { return strconv.Itoa(e.Counted) }},
+	{"Status", 24, "L", func(e Entry) string { return e.Status }},
+	{"Approver", 52, "L", func(e Entry) string { return e.Approver }},
+}
+
+// renderPDF lays out the history as a title block, one table row per leave
+// and a table of totals per policy.
+func renderPDF(h *History, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	fonts.Register(pdf)
+	pdf.SetTitle("Leave History - "+h.Subject, true)
+	brand.Apply(pdf, fonts, nil)
+	pdf.AddPage()
+
+	cols := columns
+	if h.Scope == ScopeDepartment {
+		staff := column{"Staff", staffWidth, "L", func(e Entry) string { return e.User }}
+		cols = append([]column{staff}, columns...)
+		// The approver column gives up the room of the staff column.
+		cols[len(cols)-1].width -= staffWidth
+	}
+
+	fonts.Cell(pdf, titleFont, 0, 10, "Leave History", "", 1, "", 0)
+	subject := h.Subject
+	if h.Scope == ScopeDepartment {
+		subject = "Department: " + subject
+	} else if len(h.Entries) > 0 && h.Entries[0].Department != "" {
+		subject += " - " + h.Entries[0].Department
+	}
+	fonts.Cell(pdf, headFont, 0, 7, subject, "", 1, "", 0)
+	fonts.Cell(pdf, textFont, 0, 6, fmt.Sprintf("Period: %s to %s", h.Period.From.Format(dayLayout), h.Period.To.Format(dayLayout)), "", 1, "", 0)
+	pdf.Ln(4)
+
+	if len(h.Entries) == 0 {
+		fonts.Cell(pdf, textFont, 0, 7, "No leave in this period.", "", 1, "", 0)
+		return pdf
+	}
+
+	header := func() {
+		for _, c := range cols {
+			fonts.Cell(pdf, tableHead, c.width, rowHeight+1, c.title, "1", 0, c.align, 0)
+		}
+		pdf.Ln(-1)
+	}
+	header()
+	_, pageH := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	for _, e := range h.Entries {
+		// Start a new page ahead of gofpdf so the header can be repeated.
+		if pdf.GetY()+rowHeight > pageH-max(bottom, 20) {
+			pdf.AddPage()
+			header()
+		}
+		for _, c := range cols {
+			fonts.Cell(pdf, tableFont, c.width, rowHeight, fonts.Fit(pdf, tableFont, c.value(e), c.width-2), "1", 0, c.align, 0)
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.Ln(6)
+	fonts.Cell(pdf, headFont, 0, 8, "Totals per policy", "", 1, "", 0)
+	totalCols := []struct {
+		title string
+		width float64
+	}{{"Policy", 70}, {"Requests", 30}, {"Approved days", 35}, {"Pending days", 35}}
+	for _, c := range totalCols {
+		fonts.Cell(pdf, tableHead, c.width, rowHeight+1, c.title, "1", 0, "L", 0)
+	}
+	pdf.Ln(-1)
+	var sum PolicyTotal
+	for _, t := range h.Totals {
+		totalRow(pdf, fonts, tableFont, t.Policy, t)
+		sum.Requests += t.Requests
+		sum.Approved += t.Approved
+		sum.Pending += t.Pending
+	}
+	totalRow(pdf, fonts, tableHead, "All policies", sum)
+	return pdf
+}
+
+func totalRow(pdf *gofpdf.Fpdf, fonts *report.FontSet, f report.Font, label string, t PolicyTotal) {
+	fonts.Cell(pdf, f, 70, rowHeight, fonts.Fit(pdf, f, label, 68), "1", 0, "L", 0)
+	fonts.Cell(pdf, f, 30, rowHeight, strconv.Itoa(t.Requests), "1", 0, "R", 0)
+	fonts.Cell(pdf, f, 35, rowHeight, strconv.Itoa(t.Approved), "1", 0, "R", 0)
+	fonts.Cell(pdf, f, 35, rowHeight, strconv.Itoa(t.Pending), "1", 1, "R", 0)
+}
+
+// csvWriter emits one "leave" row per leave followed by one "total" row per
+// policy, which only fills the policy and the last three columns.
+type csvWriter struct {
+	history *History
+}
+
+func (c *csvWriter) Output(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"row", "id", "userId", "user", "department", "policy", "from", "to", "days", "counted", "status", "approver", "requests", "approvedDays", "pendingDays"}}
+	for _, e := range c.history.Entries {
+		rows = append(rows, []string{
+			"leave", strconv.Itoa(e.ID), strconv.Itoa(e.UserID), e.User, e.Department, e.Policy,
+			e.From.Format(time.DateOnly), e.To.Format(time.DateOnly),
+			strconv.Itoa(e.Days), strconv.Itoa(e.Counted), e.Status, e.Approver, "", "", "",
+		})
+	}
+	for _, t := range c.history.Totals {
+		rows = append(rows, []string{
+			"total", "", "", "", "", t.Policy, "", "", "", "", "", "",
+			strconv.Itoa(t.Requests), strconv.Itoa(t.Approved), strconv.Itoa(t.Pending),
+		})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,215 @@
+// Package leave renders leave history reports per user and per department
+// from the backend's leave requests.
+package leave
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// KindLeave identifies leave reports in signatures.
+const KindLeave = "leave"
+
+var (
+	ErrForbidden = errors.New("reports on other users' leave need an admin session")
+)
+
+// Scope is what a leave report covers.
+type Scope string
+
+const (
+	ScopeUser       Scope = "user"
+	ScopeDepartment Scope = "department"
+)
+
+// ParsePeriod reads a period from YYYY-MM-DD bounds. An empty bound defaults
+// to the start or end of the current year.
+func ParsePeriod(from, to string, now time.Time) (report.Period, error) {
+	start, err := report.ParseDay("from", from, time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return report.Period{}, err
+	}
+	end, err := report.ParseDay("to", to, time.Date(now.Year(), time.December, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return report.Period{}, err
+	}
+	return report.NewPeriod(start, end)
+}
+
+// Entry is a leave request with its length and the part of it that falls
+// in the report period, in days.
+type Entry struct {
+	models.Leave
+	Days    int `json:"days"`
+	Counted int `json:"counted"`
+}
+
+// PolicyTotal sums the counted days of one leave policy. Cancelled requests
+// are listed but not counted.
+type PolicyTotal struct {
+	Policy   string `json:"policy"`
+	Requests int    `json:"requests"`
+	Approved int    `json:"approved"`
+	Pending  int    `json:"pending"`
+}
+
+// History is the content of a leave report.
+type History struct {
+	Scope       Scope         `json:"scope"`
+	ID          int           `json:"id"`
+	Subject     string        `json:"subject"`
+	Period      report.Period `json:"period"`
+	Entries     []Entry       `json:"entries"`
+	Totals      []PolicyTotal `json:"totals"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+}
+
+// Options are the per-request choices of a leave report.
+type Options struct {
+	Period report.Period
+	// Format is pdf or csv, empty means PDF.
+	Format report.Format
+}
+
+type Service interface {
+	GenerateUserReport(ctx context.Context, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	GenerateDepartmentReport(ctx context.Context, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService draws PDF text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, fonts: fonts, branding: branding, signer: signer}
+}
+
+func (s *service) GenerateUserReport(ctx context.Context, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	return s.generate(ctx, ScopeUser, id, opts, authCookies)
+}
+
+func (s *service) GenerateDepartmentReport(ctx context.Context, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	return s.generate(ctx, ScopeDepartment, id, opts, authCookies)
+}
+
+func (s *service) generate(ctx context.Context, scope Scope, id int, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	if opts.Format != "" && opts.Format != report.FormatPDF && opts.Format != report.FormatCSV {
+		return nil, fmt.Errorf("%w: leave reports are pdf or csv", report.ErrUnsupportedFormat)
+	}
+
+	filter := models.LeaveFilter{From: opts.Period.From, To: opts.Period.To}
+	if scope == ScopeUser {
+		filter.UserID = id
+	} else {
+		filter.DepartmentID = id
+	}
+	leaves, err := s.backend.ListLeaves(ctx, filter, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	h, err := buildHistory(scope, id, opts.Period, leaves)
+	if err != nil {
+		return nil, err
+	}
+	h.GeneratedAt = time.Now()
+
+	if opts.Format == report.FormatCSV {
+		return &csvWriter{history: h}, nil
+	}
+	pdf := renderPDF(h, s.fonts, s.branding.Current())
+	doc := pdfsign.Document{Kind: KindLeave, ID: id, Name: h.Subject}
+	return s.signer.Wrap(pdf, doc), nil
+}
+
+// buildHistory checks that every leave belongs to the requested user or
+// department and counts the days of each one within the period. The backend
+// answers users without admin rights with their own leave whatever they
+// asked for, so a mismatch means the caller may not see the report.
+func buildHistory(scope Scope, id int, period report.Period, leaves []models.Leave) (*History, error) {
+	h := &History{Scope: scope, ID: id, Period: period, Entries: make([]Entry, 0, len(leaves))}
+	totals := map[string]*PolicyTotal{}
+	for _, l := range leaves {
+		if (scope == ScopeUser && l.UserID != id) || (scope == ScopeDepartment && l.DepartmentID != id) {
+			return nil, ErrForbidden
+		}
+		from, to := report.BackendDay(l.From), report.BackendDay(l.To)
+		e := Entry{Leave: l, Days: daysBetween(from, to)}
+		e.From, e.To = from, to
+		if start, end := later(from, period.From), earlier(to, period.To); !end.Before(start) {
+			e.Counted = daysBetween(start, end)
+		}
+		if e.Counted == 0 {
+			continue
+		}
+		h.Entries = append(h.Entries, e)
+
+		t := totals[l.Policy]
+		if t == nil {
+			t = &PolicyTotal{Policy: l.Policy}
+			totals[l.Policy] = t
+		}
+		t.Requests++
+		switch l.StatusID {
+		case models.LeaveApproved:
+			t.Approved += e.Counted
+		case models.LeaveOnReview:
+			t.Pending += e.Counted
+		}
+	}
+
+	sort.SliceStable(h.Entries, func(i, j int) bool {
+		a, b := h.Entries[i], h.Entries[j]
+		if !a.From.Equal(b.From) {
+			return a.From.Before(b.From)
+		}
+		return a.User < b.User
+	})
+	for _, t := range totals {
+		h.Totals = append(h.Totals, *t)
+	}
+	sort.Slice(h.Totals, func(i, j int) bool { return h.Totals[i].Policy < h.Totals[j].Policy })
+
+	switch {
+	case len(h.Entries) > 0 && scope == ScopeUser:
+		h.Subject = h.Entries[0].User
+	case len(h.Entries) > 0 && h.Entries[0].Department != "":
+		h.Subject = h.Entries[0].Department
+	default:
+		h.Subject = fmt.Sprintf("%s %d", scope, id)
+	}
+	return h, nil
+}
+
+// daysBetween counts the days from from to to, both included.
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
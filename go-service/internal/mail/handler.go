@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"goservice/internal/response"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeliveriesPath is where the delivery log is mounted.
+const DeliveriesPath = "/api/v1/admin/deliveries/"
+
+type Handler struct {
+	outbox *Outbox
+}
+
+func NewHandler(o *Outbox) *Handler {
+	return &Handler{outbox: o}
+}
+
+// Routes serves the delivery log, mounted under /api/v1/admin/deliveries
+// behind the admin token.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	return r
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if h.outbox == nil {
+		response.JSON(w, http.StatusOK, []Delivery{})
+		return
+	}
+	response.JSON(w, http.StatusOK, h.outbox.List())
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if h.outbox == nil {
+		response.Error(w, http.StatusNotFound, ErrDeliveryUnknown)
+		return
+	}
+	d, err := h.outbox.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, d)
+}
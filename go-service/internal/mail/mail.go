@@ -0,0 +1,82 @@
+// Package mail sends generated reports by email. Mailers deliver one
+// message; the Outbox composes messages from templates, retries transient
+// failures and keeps a delivery log.
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/textproto"
+)
+
+var (
+	ErrInvalidAddress  = errors.New("invalid email address")
+	ErrNoRecipient     = errors.New("no email recipient")
+	ErrDeliveryUnknown = errors.New("email delivery not found")
+	ErrShuttingDown    = errors.New("mail outbox is shutting down")
+	// ErrPermanent marks failures that retrying cannot fix.
+	ErrPermanent = errors.New("permanent mail failure")
+)
+
+// maxRecipients bounds the addresses of one message.
+const maxRecipients = 10
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is one email. From and To hold RFC 5322 addresses.
+type Message struct {
+	From        string
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ParseRecipients validates and normalizes a list of addresses.
+func ParseRecipients(to []string) ([]string, error) {
+	if len(to) == 0 {
+		return nil, ErrNoRecipient
+	}
+	if len(to) > maxRecipients {
+		return nil, errors.Join(ErrInvalidAddress, errors.New("too many recipients"))
+	}
+	out := make([]string, 0, len(to))
+	for _, s := range to {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidAddress, err)
+		}
+		out = append(out, addr.String())
+	}
+	return out, nil
+}
+
+// transient reports whether a failed delivery may succeed when retried:
+// network errors and SMTP 4xx replies are, SMTP 5xx replies and errors
+// wrapping ErrPermanent are not.
+func transient(err error) bool {
+	if errors.Is(err, ErrPermanent) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return !errors.Is(err, ErrInvalidAddress)
+}
@@ -0,0 +1,288 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testMessage() Message {
+	return Message{
+		From:        "School <reports@school.example>",
+		To:          []string{"parent@example.com"},
+		Subject:     "Report of Zoë\r\nBcc: evil@example.com",
+		Body:        "Dear parent,\nsee attached.",
+		Attachments: []Attachment{{Name: "report.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4 report")}},
+	}
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	data, err := testMessage().Bytes(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("expected the subject not to inject headers")
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Report of Zoë Bcc: evil@example.com" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(body)
+	if string(text) != "Dear parent,\r\nsee attached." {
+		t.Errorf("unexpected body %q", text)
+	}
+	att, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, att))
+	if att.FileName() != "report.pdf" || string(content) != "%PDF-1.4 report" {
+		t.Errorf("unexpected attachment %q: %q", att.FileName(), content)
+	}
+
+	if _, err := ParseRecipients([]string{"not an address"}); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("expected ErrInvalidAddress, got %v", err)
+	}
+	if _, err := ParseRecipients(nil); !errors.Is(err, ErrNoRecipient) {
+		t.Errorf("expected ErrNoRecipient, got %v", err)
+	}
+}
+
+// fakeSMTP serves one plain text SMTP conversation per connection and
+// answers RCPT with the next reply of rcpt.
+type fakeSMTP struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	rcpt []string
+	data []string
+}
+
+func newFakeSMTP(t *testing.T, rcpt ...string) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, rcpt: rcpt}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+		case "EHLO", "HELO", "MAIL":
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			reply := "250 ok"
+			if len(s.rcpt) > 0 {
+				reply, s.rcpt = s.rcpt[0], s.rcpt[1:]
+			}
+			s.mu.Unlock()
+			tp.PrintfLine("%s", reply)
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, _ := tp.ReadDotBytes()
+			s.mu.Lock()
+			s.data = append(s.data, string(data))
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) mailer(t *testing.T) *SMTPMailer {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	m, err := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: addr.Port, TLS: TLSNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := newFakeSMTP(t, "451 try again later", "250 ok", "550 no such user")
+	m := server.mailer(t)
+
+	err := m.Send(context.Background(), testMessage())
+	if err == nil || !transient(err) {
+		t.Fatalf("expected a transient failure, got %v", err)
+	}
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(server.data) != 1 || !strings.Contains(server.data[0], "Content-Disposition: attachment") {
+		t.Errorf("expected the message with its attachment, got %q", server.data)
+	}
+	if err := m.Send(context.Background(), testMessage()); err == nil || transient(err) {
+		t.Errorf("expected a permanent failure, got %v", err)
+	}
+
+	// STARTTLS is required unless disabled.
+	addr := server.ln.Addr().(*net.TCPAddr)
+	strict, _ := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: addr.Port})
+	if err := strict.Send(context.Background(), testMessage()); !errors.Is(err, ErrPermanent) {
+		t.Errorf("expected the missing STARTTLS to be permanent, got %v", err)
+	}
+	if _, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example", TLS: "ssl"}); err == nil {
+		t.Error("expected an unknown tls mode to be rejected")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if _, err := mail.ReadMessage(strings.NewReader(string(data))); err != nil {
+		t.Errorf("expected a readable message: %v", err)
+	}
+}
+
+// mailerFunc adapts a function to Mailer.
+type mailerFunc func(ctx context.Context, msg Message) error
+
+func (f mailerFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+func waitForDelivery(t *testing.T, o *Outbox, id string) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		d, err := o.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.State == StateSent || d.State == StateFailed {
+			return d
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("delivery %s did not finish", id)
+	return Delivery{}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "mail.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	attempts := 0
+	flaky := mailerFunc(func(_ context.Context, msg Message) error {
+		attempts++
+		if msg.To[0] == "<bounce@example.com>" {
+			return &textproto.Error{Code: 550, Msg: "no such user"}
+		}
+		if attempts < 3 {
+			return &textproto.Error{Code: 421, Msg: "busy"}
+		}
+		return nil
+	})
+	o, err := NewOutbox(flaky, Options{
+		From:    "reports@school.example",
+		Subject: "Report of {{.Name}}",
+		Body:    "Attached.",
+		Backoff: time.Millisecond,
+		Store:   store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := o.Compose(struct{ Name string }{"Asha"}, []string{"parent@example.com"}, Attachment{Name: "r.pdf"})
+	if err != nil || msg.Subject != "Report of Asha" {
+		t.Fatalf("unexpected message %+v, %v", msg, err)
+	}
+	d, err := o.Send(msg, Reference{Kind: "student", StudentID: 4})
+	if err != nil || d.State != StateQueued || d.Attachment != "r.pdf" {
+		t.Fatalf("unexpected delivery %+v, %v", d, err)
+	}
+	if d = waitForDelivery(t, o, d.ID); d.State != StateSent || d.Attempts != 3 || d.SentAt == nil {
+		t.Errorf("expected delivery after two retries, got %+v", d)
+	}
+
+	msg.To = []string{"<bounce@example.com>"}
+	bounced, _ := o.Send(msg, Reference{Kind: "student", StudentID: 5})
+	if bounced = waitForDelivery(t, o, bounced.ID); bounced.State != StateFailed || bounced.Attempts != 1 {
+		t.Errorf("expected a permanent failure without retry, got %+v", bounced)
+	}
+	if list := o.List(); len(list) != 2 || list[0].ID != bounced.ID {
+		t.Errorf("expected the log newest first, got %+v", list)
+	}
+	if _, err := o.Compose(struct{}{}, []string{"parent@example.com"}); err == nil {
+		t.Error("expected a template error for missing data")
+	}
+	o.Shutdown(context.Background())
+
+	// Deliveries still pending when the server stopped are failed on the
+	// next start, the attachment is gone.
+	store.put(Delivery{ID: "pending", State: StateRetrying, CreatedAt: time.Now()})
+	o, err = NewOutbox(flaky, Options{From: "reports@school.example", Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := o.Get("pending"); d.State != StateFailed || d.Error != errInterrupted {
+		t.Errorf("expected the pending delivery to be failed, got %+v", d)
+	}
+	if len(o.List()) != 3 {
+		t.Errorf("expected the log to survive the restart, got %d entries", len(o.List()))
+	}
+}
@@ -0,0 +1,327 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 30 * time.Second
+
+	defaultSubject = `Report of {{.Student.Name}}`
+	defaultBody    = `Dear parent or guardian,
+
+please find attached the report of {{.Student.Name}}, class {{.Student.Class}} {{.Student.Section}}.
+
+{{.School}}
+`
+)
+
+// Options configure an Outbox.
+type Options struct {
+	// From is the sender address of every message.
+	From string
+	// Subject and Body are text/template sources rendered with the data
+	// passed to Compose. Empty values use a generic report message.
+	Subject string
+	Body    string
+	// MaxAttempts bounds the tries per message and Backoff is the wait
+	// before the first retry, doubled after each one. Non-positive values
+	// use defaults.
+	MaxAttempts int
+	Backoff     time.Duration
+	// Store keeps the delivery log across restarts.
+	Store *Store
+}
+
+// State is the stage of a delivery.
+type State string
+
+const (
+	StateQueued   State = "queued"
+	StateRetrying State = "retrying"
+	StateSent     State = "sent"
+	StateFailed   State = "failed"
+)
+
+// Delivery is the log entry of one message.
+type Delivery struct {
+	ID            string     `json:"id"`
+	Kind          string     `json:"kind"`
+	StudentID     int        `json:"studentId,omitempty"`
+	To            []string   `json:"to"`
+	Subject       string     `json:"subject"`
+	Attachment    string     `json:"attachment,omitempty"`
+	State         State      `json:"state"`
+	Attempts      int        `json:"attempts"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+}
+
+// Reference tells what a message is about, for the delivery log.
+type Reference struct {
+	Kind      string
+	StudentID int
+}
+
+// errInterrupted is recorded for deliveries cut short by a restart. The
+// attachment is only held in memory, so they cannot be resumed.
+const errInterrupted = "interrupted by a server restart, send the report again"
+
+// Outbox sends messages in the background, retrying transient failures with
+// exponential backoff, and logs every delivery.
+type Outbox struct {
+	mailer      Mailer
+	from        string
+	subject     *template.Template
+	body        *template.Template
+	maxAttempts int
+	backoff     time.Duration
+	store       *Store
+
+	ctx        context.Context
+	stop       context.CancelFunc
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+	closed     bool
+}
+
+// NewOutbox validates the sender and templates and reloads the delivery
+// log.
+func NewOutbox(m Mailer, opts Options) (*Outbox, error) {
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("mail from address: %v", err)
+	}
+	if opts.Subject == "" {
+		opts.Subject = defaultSubject
+	}
+	if opts.Body == "" {
+		opts.Body = defaultBody
+	}
+	subject, err := template.New("subject").Option("missingkey=error").Parse(opts.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("mail subject template: %v", err)
+	}
+	body, err := template.New("body").Option("missingkey=error").Parse(opts.Body)
+	if err != nil {
+		return nil, fmt.Errorf("mail body template: %v", err)
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	o := &Outbox{
+		mailer:      m,
+		from:        from.String(),
+		subject:     subject,
+		body:        body,
+		maxAttempts: opts.MaxAttempts,
+		backoff:     opts.Backoff,
+		store:       opts.Store,
+		ctx:         ctx,
+		stop:        stop,
+		deliveries:  map[string]*Delivery{},
+	}
+	if err := o.load(); err != nil {
+		stop()
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Outbox) load() error {
+	if o.store == nil {
+		return nil
+	}
+	all, err := o.store.all()
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		d := &all[i]
+		if d.State == StateQueued || d.State == StateRetrying {
+			d.State, d.Error, d.NextAttemptAt = StateFailed, errInterrupted, nil
+			o.save(d)
+		}
+		o.deliveries[d.ID] = d
+	}
+	return nil
+}
+
+// Compose renders the subject and body templates with data and addresses
+// the message to the validated recipients.
+func (o *Outbox) Compose(data any, to []string, attachments ...Attachment) (Message, error) {
+	rcpts, err := ParseRecipients(to)
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, body strings.Builder
+	if err := o.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("rendering mail subject: %v", err)
+	}
+	if err := o.body.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("rendering mail body: %v", err)
+	}
+	return Message{From: o.from, To: rcpts, Subject: oneLine(subject.String()), Body: body.String(), Attachments: attachments}, nil
+}
+
+// Send queues msg and returns its log entry. Delivery happens in the
+// background.
+func (o *Outbox) Send(msg Message, ref Reference) (Delivery, error) {
+	id, err := newID()
+	if err != nil {
+		return Delivery{}, err
+	}
+	d := &Delivery{
+		ID:        id,
+		Kind:      ref.Kind,
+		StudentID: ref.StudentID,
+		To:        msg.To,
+		Subject:   msg.Subject,
+		State:     StateQueued,
+		CreatedAt: time.Now(),
+	}
+	if len(msg.Attachments) > 0 {
+		d.Attachment = msg.Attachments[0].Name
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return Delivery{}, ErrShuttingDown
+	}
+	o.deliveries[id] = d
+	o.save(d)
+	o.wg.Add(1)
+	go o.deliver(d, msg)
+	return snapshot(d), nil
+}
+
+func (o *Outbox) deliver(d *Delivery, msg Message) {
+	defer o.wg.Done()
+	for {
+		err := o.mailer.Send(o.ctx, msg)
+
+		o.mu.Lock()
+		d.Attempts++
+		d.NextAttemptAt = nil
+		now := time.Now()
+		switch {
+		case err == nil:
+			d.State, d.Error, d.SentAt = StateSent, "", &now
+		case o.ctx.Err() != nil:
+			d.State, d.Error = StateFailed, errInterrupted
+		case !transient(err) || d.Attempts >= o.maxAttempts:
+			d.State, d.Error = StateFailed, err.Error()
+		default:
+			next := now.Add(o.backoff << (d.Attempts - 1))
+			d.State, d.Error, d.NextAttemptAt = StateRetrying, err.Error(), &next
+		}
+		o.save(d)
+		state, attempts := d.State, d.Attempts
+		o.mu.Unlock()
+
+		if state != StateRetrying {
+			if state == StateFailed {
+				log.Printf("mail delivery %s failed after %d attempts: %v", d.ID, attempts, err)
+			}
+			return
+		}
+		timer := time.NewTimer(o.backoff << (attempts - 1))
+		select {
+		case <-timer.C:
+		case <-o.ctx.Done():
+			timer.Stop()
+			o.mu.Lock()
+			d.State, d.Error, d.NextAttemptAt = StateFailed, errInterrupted, nil
+			o.save(d)
+			o.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Shutdown stops retrying and waits for sends in progress until ctx ends.
+func (o *Outbox) Shutdown(ctx context.Context) error {
+	o.mu.Lock()
+	o.closed = true
+	o.mu.Unlock()
+	o.stop()
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns one delivery.
+func (o *Outbox) Get(id string) (Delivery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	d, ok := o.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryUnknown
+	}
+	return snapshot(d), nil
+}
+
+// List returns the delivery log, newest first.
+func (o *Outbox) List() []Delivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]Delivery, 0, len(o.deliveries))
+	for _, d := range o.deliveries {
+		out = append(out, snapshot(d))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// save persists the delivery. The caller holds o.mu.
+func (o *Outbox) save(d *Delivery) {
+	if o.store == nil {
+		return
+	}
+	if err := o.store.put(*d); err != nil {
+		log.Printf("mail delivery %s not logged: %v", d.ID, err)
+	}
+}
+
+func snapshot(d *Delivery) Delivery {
+	c := *d
+	c.To = append([]string(nil), d.To...)
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// TLS modes of the SMTP connection.
+const (
+	// TLSStartTLS upgrades a plain connection with STARTTLS, usually on
+	// port 587. The server must offer it.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects with TLS right away, usually on port 465.
+	TLSImplicit = "tls"
+	// TLSNone sends in clear text, only for relays on a trusted network.
+	TLSNone = "none"
+)
+
+// sendTimeout bounds one SMTP conversation when the context has no deadline.
+const sendTimeout = time.Minute
+
+// SMTPConfig addresses an SMTP server. Username enables PLAIN
+// authentication, which net/smtp only performs over TLS or to localhost.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string
+}
+
+// SMTPMailer delivers messages to an SMTP server.
+type SMTPMailer struct {
+	cfg SMTPConfig
+	// tlsConfig is replaced in tests to trust a local server.
+	tlsConfig *tls.Config
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp: host is required")
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("smtp: unknown tls mode %q", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == TLSImplicit {
+			cfg.Port = 465
+		}
+	}
+	return &SMTPMailer{cfg: cfg, tlsConfig: &tls.Config{ServerName: cfg.Host}}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(msg.From)
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	conn.SetDeadline(deadline)
+	if m.cfg.TLS == TLSImplicit {
+		conn = tls.Client(conn, m.tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if m.cfg.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%w: %s does not offer STARTTLS", ErrPermanent, m.cfg.Host)
+		}
+		if err := c.StartTLS(m.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		rcpt, _ := mail.ParseAddress(to)
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
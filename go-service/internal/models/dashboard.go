@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Dashboard is the backend's GET /api/v1/dashboard. The head counts are
+// only computed for admins; other users get zeros.
+type Dashboard struct {
+	Students      HeadCount          `json:"students"`
+	Teachers      HeadCount          `json:"teachers"`
+	Parents       HeadCount          `json:"parents"`
+	Notices       []DashboardNotice  `json:"notices"`
+	LeavePolicies []LeavePolicyUsage `json:"leavePolicies"`
+	LeaveHistory  []DashboardLeave   `json:"leaveHistory"`
+	Celebrations  []Celebration      `json:"celebrations"`
+	OneMonthLeave []Absence          `json:"oneMonthLeave"`
+}
+
+// HeadCount counts the users admitted or joined this calendar year and the
+// change from the year before.
+type HeadCount struct {
+	CurrentYear   int     `json:"totalNumberCurrentYear"`
+	Change        int     `json:"totalNumberValueInComparisonFromPrevYear"`
+	ChangePercent float64 `json:"totalNumberPercInComparisonFromPrevYear"`
+}
+
+// PreviousYear is the count of the year before.
+func (h HeadCount) PreviousYear() int {
+	return h.CurrentYear - h.Change
+}
+
+// DashboardNotice is one of the latest notices the caller can see.
+type DashboardNotice struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Author       string `json:"author"`
+	CreatedDate  DBTime `json:"createdDate"`
+	ReviewedDate DBTime `json:"reviewedDate"`
+	Status       string `json:"status"`
+	StatusID     int    `json:"statusId"`
+}
+
+// LeavePolicyUsage is a leave policy of the caller with the approved days
+// taken under it.
+type LeavePolicyUsage struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	TotalDaysUsed float64 `json:"totalDaysUsed"`
+}
+
+// DashboardLeave is one of the latest leave requests, of everybody for
+// admins and of the caller otherwise.
+type DashboardLeave struct {
+	ID        int    `json:"id"`
+	User      string `json:"user"`
+	Policy    string `json:"policy"`
+	From      DBTime `json:"from"`
+	To        DBTime `json:"to"`
+	Status    string `json:"status"`
+	Submitted DBTime `json:"submitted"`
+}
+
+// Celebration is a birthday or admission/joining anniversary in the next 90
+// days. EventDate is the original date, not the upcoming one.
+type Celebration struct {
+	UserID    int    `json:"userId"`
+	User      string `json:"user"`
+	Event     string `json:"event"`
+	EventDate DBTime `json:"eventDate"`
+}
+
+// Absence is approved leave overlapping the next 30 days.
+type Absence struct {
+	UserID    int    `json:"userId"`
+	User      string `json:"user"`
+	FromDate  DBTime `json:"fromDate"`
+	ToDate    DBTime `json:"toDate"`
+	LeaveType string `json:"leaveType"`
+}
+
+// DBTime is a date or timestamp serialised by PostgreSQL's JSON functions,
+// which write the database's wall clock without a time zone. It is read as
+// UTC; null is the zero time.
+type DBTime struct {
+	time.Time
+}
+
+var dbTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", time.DateOnly}
+
+func (t *DBTime) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range dbTimeLayouts {
+		if parsed, err := time.Parse(layout, *s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid date %q", *s)
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Leave statuses as numbered in the backend's leave_status table.
+const (
+	LeaveOnReview = 1
+	LeaveApproved = 2
+	LeaveCanceled = 3
+)
+
+// Leave is one leave request from the backend's GET /api/v1/leave/request.
+// From and To are whole days; the backend's own day count is not used
+// because it drops whole months.
+type Leave struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"userId"`
+	User         string     `json:"user"`
+	DepartmentID int        `json:"departmentId"`
+	Department   string     `json:"department"`
+	PolicyID     int        `json:"policyId"`
+	Policy       string     `json:"policy"`
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	Note         string     `json:"note"`
+	StatusID     int        `json:"statusId"`
+	Status       string     `json:"status"`
+	Submitted    time.Time  `json:"submitted"`
+	Approved     *time.Time `json:"approved"`
+	Approver     string     `json:"approver"`
+}
+
+// LeaveFilter mirrors the query filters of GET /api/v1/leave/request. Zero
+// fields are not sent. The backend only honours UserID and DepartmentID for
+// admins and answers everybody else with their own leave.
+type LeaveFilter struct {
+	UserID       int
+	DepartmentID int
+	From         time.Time
+	To           time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// NoticeApproved is the approved status of the backend's notice_status table.
+const NoticeApproved = 5
+
+// Recipient types of a notice: everyone, or a role narrowed by FirstField to
+// a department (teachers) or a class (students).
+const (
+	NoticeForEveryone = "EV"
+	NoticeForSpecific = "SP"
+)
+
+// Notice is a notice from the backend's GET /api/v1/notices. The recipient
+// fields are only sent by GET /api/v1/notices/{id}, which in turn lacks the
+// reviewer.
+type Notice struct {
+	ID            int        `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	AuthorID      int        `json:"authorId"`
+	Author        string     `json:"author"`
+	CreatedDate   time.Time  `json:"createdDate"`
+	UpdatedDate   *time.Time `json:"updatedDate"`
+	ReviewerName  string     `json:"reviewerName"`
+	ReviewedDate  *time.Time `json:"reviewedDate"`
+	Status        string     `json:"status"`
+	StatusID      int        `json:"statusId"`
+	RecipientType string     `json:"recipientType"`
+	RecipientRole int        `json:"recipientRole"`
+	FirstField    string     `json:"firstField"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Role is a role from the backend's GET /api/v1/roles. The backend names
+// its active flag "status".
+type Role struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Active bool   `json:"status"`
+}
+
+// Permission is an access control granted to a role, from GET
+// /api/v1/roles/{id}/permissions. The admin role is granted every access
+// control.
+type Permission struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RoleUser is a user holding a role, from GET /api/v1/roles/{id}/users.
+type RoleUser struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	LastLogin *time.Time `json:"lastLogin"`
+}
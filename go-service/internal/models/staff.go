@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Staff is the profile served by the backend's GET /api/v1/staffs/:id.
+type Staff struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	Email            string    `json:"email"`
+	SystemAccess     bool      `json:"systemAccess"`
+	Role             int       `json:"role"`
+	RoleName         string    `json:"roleName"`
+	Department       string    `json:"department"`
+	ReporterID       int       `json:"reporterId"`
+	ReporterName     string    `json:"reporterName"`
+	Gender           string    `json:"gender"`
+	MaritalStatus    string    `json:"maritalStatus"`
+	JoinDate         time.Time `json:"joinDate"`
+	Qualification    string    `json:"qualification"`
+	Experience       string    `json:"experience"`
+	DOB              time.Time `json:"dob"`
+	Phone            string    `json:"phone"`
+	FatherName       string    `json:"fatherName"`
+	MotherName       string    `json:"motherName"`
+	EmergencyPhone   string    `json:"emergencyPhone"`
+	CurrentAddress   string    `json:"currentAddress"`
+	PermanentAddress string    `json:"permanentAddress"`
+}
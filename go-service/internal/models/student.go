@@ -25,3 +25,12 @@ type Student struct {
 	AdmissionDate      time.Time `json:"admissionDate"`
 	ReporterName       string    `json:"reporterName"`
 }
+
+// StudentFilter mirrors the query filters accepted by the backend's
+// GET /api/v1/students endpoint. Empty fields are not sent.
+type StudentFilter struct {
+	Name      string
+	ClassName string
+	Section   string
+	Roll      int
+}
@@ -0,0 +1,111 @@
+package notice
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves notice board prints, mounted under /api/v1/reports/notices.
+// Only notices the caller can see in the web app are printed.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/bulletin", h.Bulletin)
+	r.Get("/{id}/poster", h.Poster)
+	return r
+}
+
+// errorStatus maps notice print errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, report.ErrInvalidPeriod), errors.Is(err, ErrInvalidAudience):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNoticeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotApproved):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) Bulletin(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	q := r.URL.Query()
+	period, err := ParsePeriod(q.Get("from"), q.Get("to"), time.Now())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	audience, err := ParseAudience(q.Get("recipients"), q.Get("group"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pdf, err := h.service.GenerateBulletin(r.Context(), Options{Period: period, Audience: audience}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	name := fmt.Sprintf("notices_%s_%s.pdf", period.From.Format("20060102"), period.To.Format("20060102"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+	if err := pdf.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+func (h *Handler) Poster(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	pdf, err := h.service.GeneratePoster(r.Context(), id, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("notice_%d_poster.pdf", id)}))
+	w.WriteHeader(http.StatusOK)
+	if err := pdf.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
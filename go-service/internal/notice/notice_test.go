@@ -0,0 +1,202 @@
+package notice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(s string) *time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func sampleNotices() []models.Notice {
+	return []models.Notice{
+		{ID: 1, Title: "Sports day", Description: "All classes meet on the field.", Author: "John", CreatedDate: *at("2024-05-01 08:00"), ReviewerName: "Walter", ReviewedDate: at("2024-05-02 09:00"), StatusID: models.NoticeApproved},
+		{ID: 2, Title: "Staff meeting", Description: "Science department, room 4.", Author: "Dana", CreatedDate: *at("2024-05-03 10:00"), ReviewedDate: at("2024-05-06 11:00"), StatusID: models.NoticeApproved},
+		{ID: 3, Title: "Draft", Description: "Not ready.", Author: "Dana", CreatedDate: *at("2024-05-04 10:00"), StatusID: 2},
+		{ID: 4, Title: "Grade 5 trip", Description: "Bring a packed lunch.", Author: "John", CreatedDate: *at("2024-05-10 08:00"), StatusID: models.NoticeApproved},
+		{ID: 5, Title: "Last year", Description: "Old news.", Author: "John", CreatedDate: *at("2023-05-10 08:00"), ReviewedDate: at("2023-05-10 09:00"), StatusID: models.NoticeApproved},
+	}
+}
+
+type fakeBackend struct {
+	client.IBackend
+	notices []models.Notice
+	details int
+}
+
+func (f *fakeBackend) ListNotices(ctx context.Context, cookies []*http.Cookie) ([]models.Notice, error) {
+	return f.notices, nil
+}
+
+// GetNoticeByID addresses notice 2 to the science department (3), notice 4
+// to class Grade 5 and the others to everyone.
+func (f *fakeBackend) GetNoticeByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Notice, error) {
+	f.details++
+	n := models.Notice{ID: id, RecipientType: models.NoticeForEveryone}
+	switch id {
+	case 2:
+		n.RecipientType, n.RecipientRole, n.FirstField = models.NoticeForSpecific, 2, "3"
+	case 4:
+		n.RecipientType, n.RecipientRole, n.FirstField = models.NoticeForSpecific, 3, "Grade 5"
+	}
+	return &n, nil
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2024, 5, 20, 15, 0, 0, 0, time.UTC)
+	p, err := ParsePeriod("", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.From.Format(time.DateOnly) != "2024-04-21" || p.To.Format(time.DateOnly) != "2024-05-20" {
+		t.Errorf("unexpected default period %v", p)
+	}
+	if p, _ = ParsePeriod("", "2024-03-31", now); p.From.Format(time.DateOnly) != "2024-03-02" {
+		t.Errorf("unexpected default start %v", p.From)
+	}
+	for _, bad := range [][2]string{{"2024-13-01", ""}, {"", "yesterday"}, {"2024-05-02", "2024-05-01"}} {
+		if _, err := ParsePeriod(bad[0], bad[1], now); !errors.Is(err, report.ErrInvalidPeriod) {
+			t.Errorf("%q: expected ErrInvalidPeriod, got %v", bad, err)
+		}
+	}
+}
+
+func TestAudience(t *testing.T) {
+	for _, bad := range [][2]string{{"", "3"}, {"everyone", "3"}, {"parents", ""}} {
+		if _, err := ParseAudience(bad[0], bad[1]); !errors.Is(err, ErrInvalidAudience) {
+			t.Errorf("%q: expected ErrInvalidAudience, got %v", bad, err)
+		}
+	}
+
+	everyone := models.Notice{RecipientType: models.NoticeForEveryone}
+	science := models.Notice{RecipientType: models.NoticeForSpecific, RecipientRole: 2, FirstField: "3"}
+	teachers := models.Notice{RecipientType: models.NoticeForSpecific, RecipientRole: 2}
+	grade5 := models.Notice{RecipientType: models.NoticeForSpecific, RecipientRole: 3, FirstField: "Grade 5"}
+	for _, tc := range []struct {
+		recipients, group string
+		want              [4]bool
+	}{
+		{"", "", [4]bool{true, true, true, true}},
+		{"everyone", "", [4]bool{true, false, false, false}},
+		{"Teachers", "", [4]bool{true, true, true, false}},
+		{"teachers", "4", [4]bool{true, false, true, false}},
+		{"students", "Grade 5", [4]bool{true, false, false, true}},
+	} {
+		a, err := ParseAudience(tc.recipients, tc.group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, n := range []models.Notice{everyone, science, teachers, grade5} {
+			if got := a.Includes(n); got != tc.want[i] {
+				t.Errorf("%s/%s: notice %d included %v, want %v", tc.recipients, tc.group, i, got, tc.want[i])
+			}
+		}
+	}
+	if a, _ := ParseAudience("teachers", "3"); a.Label() != "Teachers - 3" {
+		t.Errorf("unexpected label %q", a.Label())
+	}
+}
+
+func TestCollect(t *testing.T) {
+	backend := &fakeBackend{notices: sampleNotices()}
+	s := NewService(backend, nil, nil, nil).(*service)
+	period := report.Period{From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)}
+
+	b, err := s.collect(context.Background(), Options{Period: period}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, n := range b.Notices {
+		ids = append(ids, n.ID)
+	}
+	if fmt.Sprint(ids) != "[4 2 1]" {
+		t.Errorf("expected approved notices of May newest first, got %v", ids)
+	}
+	if backend.details != 0 {
+		t.Errorf("expected no detail lookups without an audience, got %d", backend.details)
+	}
+
+	b, err = s.collect(context.Background(), Options{Period: period, Audience: Audience{Recipients: "students", Group: "Grade 5"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Notices) != 2 || b.Notices[0].ID != 4 || b.Notices[1].ID != 1 {
+		t.Errorf("unexpected notices for Grade 5 %+v", b.Notices)
+	}
+}
+
+func TestRenderBulletin_Columns(t *testing.T) {
+	var notices []models.Notice
+	for i := range 40 {
+		notices = append(notices, models.Notice{
+			ID: i, Title: fmt.Sprintf("Notice %d", i), Author: "John", CreatedDate: *at("2024-05-01 08:00"),
+			Description: strings.Repeat("Lorem ipsum dolor sit amet. ", 10),
+		})
+	}
+	s := NewService(&fakeBackend{}, nil, nil, nil).(*service)
+	pdf := renderBulletin(&Bulletin{Notices: notices}, s.fonts, nil)
+	if pdf.Err() {
+		t.Fatal(pdf.Error())
+	}
+	if pdf.PageNo() < 2 {
+		t.Errorf("expected 40 notices to fill more than a page, got %d", pdf.PageNo())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	routes := NewHandler(NewService(&fakeBackend{notices: sampleNotices()}, nil, nil, nil)).Routes()
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"bulletin", "/bulletin?from=2024-05-01&to=2024-05-31", http.StatusOK},
+		{"group", "/bulletin?from=2024-05-01&to=2024-05-31&recipients=teachers&group=3", http.StatusOK},
+		{"empty", "/bulletin?from=2020-01-01&to=2020-01-31", http.StatusOK},
+		{"bad period", "/bulletin?from=2024-02-30", http.StatusBadRequest},
+		{"bad recipients", "/bulletin?recipients=parents", http.StatusBadRequest},
+		{"poster", "/1/poster", http.StatusOK},
+		{"unapproved poster", "/3/poster", http.StatusConflict},
+		{"unknown poster", "/9/poster", http.StatusNotFound},
+		{"bad id", "/first/poster", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+			continue
+		}
+		if tc.status == http.StatusOK && !strings.HasPrefix(rec.Body.String(), "%PDF") {
+			t.Errorf("%s: unexpected body %.40q", tc.name, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/bulletin?from=2024-05-01&to=2024-05-31", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=notices_20240501_20240531.pdf" {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/1/poster", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a session, got %d", rec.Code)
+	}
+}
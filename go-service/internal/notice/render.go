@@ -0,0 +1,150 @@
+package notice
+
+import (
+	"goservice/internal/models"
+	"goservice/internal/report"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	dayLayout = "02 Jan 2006"
+	columns   = 2
+	gutter    = 8.0
+)
+
+var (
+	titleFont   = report.Font{Style: "B", Size: 18}
+	textFont    = report.Font{Size: 10}
+	noticeTitle = report.Font{Style: "B", Size: 12}
+	metaFont    = report.Font{Style: "I", Size: 8}
+	bodyFont    = report.Font{Size: 10}
+
+	posterLabel = report.Font{Style: "B", Size: 28}
+	posterTitle = report.Font{Style: "B", Size: 40}
+	posterBody  = report.Font{Size: 22}
+	posterMeta  = report.Font{Size: 14}
+)
+
+// renderBulletin lays the notices out newspaper style: down the first column,
+// then the next, then onto a new page. A notice is never split across
+// columns.
+func renderBulletin(b *Bulletin, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	fonts.Register(pdf)
+	pdf.SetTitle("Notice Bulletin", true)
+	brand.Apply(pdf, fonts, nil)
+	pdf.AddPage()
+
+	fonts.Cell(pdf, titleFont, 0, 10, "Notice Board", "", 1, "C", 0)
+	sub := "Notices published " + b.Period.From.Format(dayLayout) + " to " + b.Period.To.Format(dayLayout)
+	if label := b.Audience.Label(); label != "" {
+		sub += " for " + label
+	}
+	fonts.Cell(pdf, textFont, 0, 6, sub, "", 1, "C", 0)
+	pdf.Ln(4)
+
+	if len(b.Notices) == 0 {
+		fonts.Cell(pdf, textFont, 0, 7, "No approved notices in this period.", "", 1, "C", 0)
+		return pdf
+	}
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, pageH := pdf.GetPageSize()
+	_, breakMargin := pdf.GetAutoPageBreak()
+	bottom := pageH - breakMargin
+	width := (pageW - left - right - gutter*(columns-1)) / columns
+
+	top, col := pdf.GetY(), 0
+	y := top
+	for _, n := range b.Notices {
+		h := noticeHeight(pdf, fonts, n, width)
+		if y+h > bottom && y > top {
+			col++
+			if col == columns {
+				pdf.AddPage()
+				top, col = pdf.GetY(), 0
+			}
+			y = top
+		}
+		x := left + float64(col)*(width+gutter)
+		pdf.SetXY(x, y)
+		drawNotice(pdf, fonts, n, x, width)
+		y = pdf.GetY()
+	}
+	return pdf
+}
+
+// noticeHeight is the height drawNotice takes for n.
+func noticeHeight(pdf *gofpdf.Fpdf, fonts *report.FontSet, n models.Notice, width float64) float64 {
+	lines := func(f report.Font, text string) float64 {
+		fonts.SetFont(pdf, f, text)
+		return float64(max(len(pdf.SplitText(text, width)), 1))
+	}
+	return lines(noticeTitle, n.Title)*6 + 5 + lines(bodyFont, n.Description)*5 + 8
+}
+
+// drawNotice draws the title, author and date, and description of a notice
+// at x, followed by a separating rule.
+func drawNotice(pdf *gofpdf.Fpdf, fonts *report.FontSet, n models.Notice, x, width float64) {
+	fonts.MultiCell(pdf, noticeTitle, width, 6, n.Title, "L")
+	pdf.SetX(x)
+	pdf.SetTextColor(90, 90, 90)
+	fonts.Cell(pdf, metaFont, width, 5, byline(n), "", 1, "L", 0)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetX(x)
+	fonts.MultiCell(pdf, bodyFont, width, 5, n.Description, "L")
+
+	y := pdf.GetY() + 3
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.Line(x, y, x+width, y)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetY(y + 5)
+}
+
+func byline(n models.Notice) string {
+	date := report.BackendDay(Published(n)).Format(dayLayout)
+	if n.Author == "" {
+		return date
+	}
+	return n.Author + " - " + date
+}
+
+// renderPoster prints a single notice in large type on an A3 page.
+func renderPoster(n *models.Notice, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A3", "")
+	pdf.SetMargins(20, 20, 20)
+	fonts.Register(pdf)
+	pdf.SetTitle(n.Title, true)
+	brand.Apply(pdf, fonts, nil)
+	pdf.AddPage()
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+
+	pdf.Ln(10)
+	fonts.Cell(pdf, posterLabel, 0, 14, "NOTICE", "", 1, "C", 0)
+	y := pdf.GetY() + 4
+	pdf.SetLineWidth(1)
+	pdf.Line(left, y, pageW-right, y)
+	pdf.SetLineWidth(0.2)
+	pdf.SetY(y + 12)
+
+	fonts.MultiCell(pdf, posterTitle, 0, 17, n.Title, "C")
+	pdf.Ln(12)
+	fonts.MultiCell(pdf, posterBody, 0, 10, n.Description, "L")
+	pdf.Ln(16)
+
+	pdf.SetTextColor(90, 90, 90)
+	if n.Author != "" {
+		fonts.Cell(pdf, posterMeta, 0, 8, "Posted by "+n.Author, "", 1, "R", 0)
+	}
+	day := report.BackendDay(Published(*n)).Format(dayLayout)
+	approved := "Published " + day
+	if n.ReviewerName != "" {
+		approved = "Approved by " + n.ReviewerName + " - " + day
+	}
+	fonts.Cell(pdf, posterMeta, 0, 8, approved, "", 1, "R", 0)
+	pdf.SetTextColor(0, 0, 0)
+	return pdf
+}
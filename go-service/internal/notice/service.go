@@ -0,0 +1,202 @@
+// Package notice prints approved notices from the backend for physical
+// notice boards: a bulletin of several notices laid out in columns, or an
+// A3 poster of a single one.
+package notice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KindNotice identifies notice bulletins and posters in signatures.
+const KindNotice = "notice"
+
+var (
+	ErrNoticeNotFound  = errors.New("notice not found")
+	ErrNotApproved     = errors.New("notice is not approved")
+	ErrInvalidAudience = errors.New("invalid recipient group")
+)
+
+// defaultDays is the length of a bulletin period without a start.
+const defaultDays = 30
+
+// ParsePeriod reads a period from YYYY-MM-DD bounds. An empty to defaults to
+// today and an empty from to 30 days before to.
+func ParsePeriod(from, to string, now time.Time) (report.Period, error) {
+	end, err := report.ParseDay("to", to, report.Day(now))
+	if err != nil {
+		return report.Period{}, err
+	}
+	start, err := report.ParseDay("from", from, end.AddDate(0, 0, -(defaultDays-1)))
+	if err != nil {
+		return report.Period{}, err
+	}
+	return report.NewPeriod(start, end)
+}
+
+// Audience names the recipient group a bulletin is for. Teachers can be
+// narrowed to a department id and students to a class name, as notices are
+// addressed in the backend. Notices for everyone are on every bulletin.
+type Audience struct {
+	// Recipients is everyone, teachers or students; empty means no filter.
+	Recipients string
+	Group      string
+}
+
+// Backend roles notices are addressed to.
+var audienceRoles = map[string]int{"teachers": 2, "students": 3}
+
+// ParseAudience validates the recipients and group query parameters.
+func ParseAudience(recipients, group string) (Audience, error) {
+	a := Audience{Recipients: strings.ToLower(strings.TrimSpace(recipients)), Group: strings.TrimSpace(group)}
+	switch {
+	case a.Recipients == "" && a.Group != "":
+		return Audience{}, fmt.Errorf("%w: group needs recipients", ErrInvalidAudience)
+	case a.Recipients == "everyone" && a.Group != "":
+		return Audience{}, fmt.Errorf("%w: notices for everyone have no group", ErrInvalidAudience)
+	case a.Recipients == "", a.Recipients == "everyone":
+	case audienceRoles[a.Recipients] == 0:
+		return Audience{}, fmt.Errorf("%w: recipients must be everyone, teachers or students", ErrInvalidAudience)
+	}
+	return a, nil
+}
+
+// Includes reports whether a notice with the recipients of n reaches the
+// audience, mirroring the backend's own visibility rules.
+func (a Audience) Includes(n models.Notice) bool {
+	switch {
+	case a.Recipients == "":
+		return true
+	case n.RecipientType == models.NoticeForEveryone:
+		return true
+	case a.Recipients == "everyone" || n.RecipientType != models.NoticeForSpecific:
+		return false
+	case n.RecipientRole != audienceRoles[a.Recipients]:
+		return false
+	}
+	return n.FirstField == "" || a.Group == "" || n.FirstField == a.Group
+}
+
+// Label describes the audience on the bulletin.
+func (a Audience) Label() string {
+	if a.Recipients == "" {
+		return ""
+	}
+	label := strings.ToUpper(a.Recipients[:1]) + a.Recipients[1:]
+	if a.Group != "" {
+		label += " - " + a.Group
+	}
+	return label
+}
+
+// Bulletin is the content of a notice bulletin, newest notice first.
+type Bulletin struct {
+	Period      report.Period   `json:"period"`
+	Audience    Audience        `json:"audience"`
+	Notices     []models.Notice `json:"notices"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+}
+
+// Options are the per-request choices of a bulletin.
+type Options struct {
+	Period   report.Period
+	Audience Audience
+}
+
+type Service interface {
+	GenerateBulletin(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+	GeneratePoster(ctx context.Context, id int, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService draws PDF text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, fonts: fonts, branding: branding, signer: signer}
+}
+
+// GenerateBulletin prints the approved notices the caller can see that were
+// published in the period and reach the audience.
+func (s *service) GenerateBulletin(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	b, err := s.collect(ctx, opts, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	pdf := renderBulletin(b, s.fonts, s.branding.Current())
+	doc := pdfsign.Document{Kind: KindNotice, Name: "Notice bulletin " + opts.Period.From.Format(time.DateOnly) + " to " + opts.Period.To.Format(time.DateOnly)}
+	return s.signer.Wrap(pdf, doc), nil
+}
+
+func (s *service) collect(ctx context.Context, opts Options, authCookies []*http.Cookie) (*Bulletin, error) {
+	notices, err := s.backend.ListNotices(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bulletin{Period: opts.Period, Audience: opts.Audience, Notices: []models.Notice{}, GeneratedAt: time.Now()}
+	for _, n := range notices {
+		if n.StatusID != models.NoticeApproved || !opts.Period.Contains(Published(n)) {
+			continue
+		}
+		if opts.Audience.Recipients != "" {
+			// Only the detail tells who a notice is for.
+			detail, err := s.backend.GetNoticeByID(ctx, n.ID, authCookies)
+			if err != nil {
+				return nil, err
+			}
+			n.RecipientType, n.RecipientRole, n.FirstField = detail.RecipientType, detail.RecipientRole, detail.FirstField
+			if !opts.Audience.Includes(n) {
+				continue
+			}
+		}
+		b.Notices = append(b.Notices, n)
+	}
+	sort.SliceStable(b.Notices, func(i, j int) bool { return Published(b.Notices[i]).After(Published(b.Notices[j])) })
+	return b, nil
+}
+
+// GeneratePoster prints one approved notice on A3. The notice is looked up
+// in the caller's list so that posters respect who may see a notice.
+func (s *service) GeneratePoster(ctx context.Context, id int, authCookies []*http.Cookie) (report.Writer, error) {
+	notices, err := s.backend.ListNotices(ctx, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notices {
+		if n.ID != id {
+			continue
+		}
+		if n.StatusID != models.NoticeApproved {
+			return nil, ErrNotApproved
+		}
+		pdf := renderPoster(&n, s.fonts, s.branding.Current())
+		return s.signer.Wrap(pdf, pdfsign.Document{Kind: KindNotice, ID: id, Name: n.Title}), nil
+	}
+	return nil, ErrNoticeNotFound
+}
+
+// Published is the day a notice went up: its approval, or its creation for
+// notices approved before reviews were recorded.
+func Published(n models.Notice) time.Time {
+	if n.ReviewedDate != nil {
+		return *n.ReviewedDate
+	}
+	return n.CreatedDate
+}
@@ -0,0 +1,135 @@
+package pdfsign
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Strings added to an encrypted document have to be encrypted with the
+// document key like every other string. Only the standard security handler
+// revision 2 (40-bit RC4) written by gofpdf is supported.
+
+var (
+	ErrWrongPassword = errors.New("wrong pdf password")
+)
+
+var (
+	passwordPadding = []byte{
+		0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+		0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+		0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+		0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+	}
+	reRevision = regexp.MustCompile(`/R\s+(\d+)`)
+	rePerms    = regexp.MustCompile(`/P\s+(-?\d+)`)
+)
+
+// encryptionKey derives the document key from the user password and checks
+// it against the /U entry. Unencrypted documents have a nil key.
+func (d *document) encryptionKey(password string) ([]byte, error) {
+	if d.encrypt == 0 {
+		return nil, nil
+	}
+	dict, err := d.object(d.encrypt)
+	if err != nil {
+		return nil, err
+	}
+	m := reRevision.FindSubmatch(dict)
+	if m == nil || string(m[1]) != "2" {
+		return nil, fmt.Errorf("%w: unsupported encryption", ErrMalformedPDF)
+	}
+	o, okO := literalEntry(dict, "/O")
+	u, okU := literalEntry(dict, "/U")
+	pm := rePerms.FindSubmatch(dict)
+	if !okO || !okU || pm == nil {
+		return nil, fmt.Errorf("%w: incomplete encryption dictionary", ErrMalformedPDF)
+	}
+	p, _ := strconv.ParseInt(string(pm[1]), 10, 32)
+
+	buf := append([]byte(password), passwordPadding...)[:32]
+	buf = append(buf, o...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(int32(p)))
+	sum := md5.Sum(buf)
+	key := sum[:5]
+
+	check := append([]byte{}, passwordPadding...)
+	c, _ := rc4.NewCipher(key)
+	c.XORKeyStream(check, check)
+	if !bytes.Equal(check, u) {
+		return nil, ErrWrongPassword
+	}
+	return key, nil
+}
+
+// rc4Crypt encrypts or decrypts buf in place with the key of object num.
+func rc4Crypt(key []byte, num int, buf []byte) {
+	b := append(append([]byte{}, key...), byte(num), byte(num>>8), byte(num>>16), 0, 0)
+	sum := md5.Sum(b)
+	c, _ := rc4.NewCipher(sum[:min(len(key)+5, 16)])
+	c.XORKeyStream(buf, buf)
+}
+
+// literalEntry reads the literal string value of key in dict.
+func literalEntry(dict []byte, key string) ([]byte, bool) {
+	i := bytes.Index(dict, []byte(key+" ("))
+	if i < 0 {
+		return nil, false
+	}
+	return readLiteral(dict[i+len(key)+2:])
+}
+
+// readLiteral decodes a literal string up to its closing parenthesis.
+func readLiteral(b []byte) ([]byte, bool) {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '\\':
+			i++
+			if i >= len(b) {
+				return nil, false
+			}
+			switch e := b[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\n':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v, n := 0, 0
+				for ; n < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7'; n, i = n+1, i+1 {
+					v = v*8 + int(b[i]-'0')
+				}
+				i--
+				out = append(out, byte(v))
+			default:
+				out = append(out, e)
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			if depth == 0 {
+				return out, true
+			}
+			depth--
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package pdfsign
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/response"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxUploadSize bounds the PDFs accepted for verification.
+	maxUploadSize = 20 << 20
+
+	// PasswordHeader carries the user password of an encrypted report.
+	PasswordHeader = "X-Report-Password"
+)
+
+var (
+	ErrNoFile = errors.New("a pdf file is required")
+)
+
+type Handler struct {
+	signer *Signer
+}
+
+func NewHandler(s *Signer) *Handler {
+	return &Handler{signer: s}
+}
+
+// Verify accepts a PDF either as the "file" field of a multipart form or as
+// the raw request body. The password of an encrypted report may be sent in
+// the X-Report-Password header or the "password" form field. It is served at
+// /api/v1/reports/verify without a session so that third parties can check
+// reports they receive.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	pdf, err := readUpload(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	password := r.Header.Get(PasswordHeader)
+	if password == "" {
+		password = r.FormValue("password")
+	}
+
+	result, err := h.signer.Verify(pdf, password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotSigned) || errors.Is(err, ErrMalformedPDF) || errors.Is(err, ErrWrongPassword) {
+			status = http.StatusUnprocessableEntity
+		}
+		response.Error(w, status, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, result)
+}
+
+func readUpload(r *http.Request) ([]byte, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNoFile, err)
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrNoFile
+	}
+	return data, nil
+}
@@ -0,0 +1,294 @@
+package pdfsign
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// document gives access to the objects of a PDF with a classic cross
+// reference table, which is what gofpdf writes. Only what is needed to append
+// an incremental update is parsed.
+type document struct {
+	data    []byte
+	xref    int
+	size    int
+	root    int
+	info    int
+	encrypt int
+	id      []byte
+	offsets map[int]int
+}
+
+var (
+	reStartXref = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	reSize      = regexp.MustCompile(`/Size\s+(\d+)`)
+	reRoot      = regexp.MustCompile(`/Root\s+(\d+)\s+0\s+R`)
+	reInfo      = regexp.MustCompile(`/Info\s+(\d+)\s+0\s+R`)
+	rePrev      = regexp.MustCompile(`/Prev\s+(\d+)`)
+	reEncrypt   = regexp.MustCompile(`/Encrypt\s+(\d+)\s+0\s+R`)
+	reID        = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	rePages     = regexp.MustCompile(`/Pages\s+(\d+)\s+0\s+R`)
+	reKids      = regexp.MustCompile(`/Kids\s*\[\s*(\d+)\s+0\s+R`)
+	reTypePages = regexp.MustCompile(`/Type\s*/Pages\b`)
+)
+
+func parseDocument(data []byte) (*document, error) {
+	m := reStartXref.FindSubmatch(data)
+	if m == nil {
+		return nil, fmt.Errorf("%w: missing startxref", ErrMalformedPDF)
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	doc := &document{data: data, xref: xref, offsets: map[int]int{}}
+
+	// Later sections override earlier ones, so the chain is read from the
+	// newest section back and only unseen objects are recorded.
+	for offset, first := xref, true; ; first = false {
+		trailer, err := doc.readXref(offset)
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			if m := reSize.FindSubmatch(trailer); m != nil {
+				doc.size, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := reRoot.FindSubmatch(trailer); m != nil {
+				doc.root, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := reInfo.FindSubmatch(trailer); m != nil {
+				doc.info, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := reEncrypt.FindSubmatch(trailer); m != nil {
+				doc.encrypt, _ = strconv.Atoi(string(m[1]))
+			}
+			doc.id = reID.Find(trailer)
+		}
+		m := rePrev.FindSubmatch(trailer)
+		if m == nil {
+			break
+		}
+		offset, _ = strconv.Atoi(string(m[1]))
+	}
+	if doc.size == 0 || doc.root == 0 {
+		return nil, fmt.Errorf("%w: incomplete trailer", ErrMalformedPDF)
+	}
+	return doc, nil
+}
+
+// readXref records the entries of the cross reference section at offset and
+// returns its trailer dictionary.
+func (d *document) readXref(offset int) ([]byte, error) {
+	if offset < 0 || offset >= len(d.data) || !bytes.HasPrefix(d.data[offset:], []byte("xref")) {
+		return nil, fmt.Errorf("%w: cross reference streams are not supported", ErrMalformedPDF)
+	}
+	lines := bytes.Split(d.data[offset+len("xref"):], []byte("\n"))
+	i := 0
+	for i < len(lines) {
+		line := bytes.TrimSpace(lines[i])
+		i++
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("trailer")) {
+			end := bytes.Index(d.data[offset:], []byte("startxref"))
+			start := bytes.Index(d.data[offset:], []byte("trailer"))
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated trailer", ErrMalformedPDF)
+			}
+			return d.data[offset+start : offset+end], nil
+		}
+		var first, count int
+		if _, err := fmt.Sscanf(string(line), "%d %d", &first, &count); err != nil {
+			return nil, fmt.Errorf("%w: bad cross reference subsection", ErrMalformedPDF)
+		}
+		for n := 0; n < count && i < len(lines); n, i = n+1, i+1 {
+			var off, gen int
+			var kind string
+			if _, err := fmt.Sscanf(string(lines[i]), "%d %d %s", &off, &gen, &kind); err != nil {
+				return nil, fmt.Errorf("%w: bad cross reference entry", ErrMalformedPDF)
+			}
+			if _, seen := d.offsets[first+n]; !seen && kind == "n" {
+				d.offsets[first+n] = off
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: missing trailer", ErrMalformedPDF)
+}
+
+// object returns the body of object num between "obj" and "endobj".
+func (d *document) object(num int) ([]byte, error) {
+	off, ok := d.offsets[num]
+	if !ok || off >= len(d.data) {
+		return nil, fmt.Errorf("%w: object %d not found", ErrMalformedPDF, num)
+	}
+	rest := d.data[off:]
+	start := bytes.Index(rest, []byte("obj"))
+	end := bytes.Index(rest, []byte("endobj"))
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: object %d is truncated", ErrMalformedPDF, num)
+	}
+	return bytes.TrimSpace(rest[start+len("obj") : end]), nil
+}
+
+// firstPage follows the page tree from the catalog to the first page.
+func (d *document) firstPage() (int, error) {
+	catalog, err := d.object(d.root)
+	if err != nil {
+		return 0, err
+	}
+	m := rePages.FindSubmatch(catalog)
+	if m == nil {
+		return 0, fmt.Errorf("%w: catalog has no pages", ErrMalformedPDF)
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	for depth := 0; depth < 32; depth++ {
+		node, err := d.object(num)
+		if err != nil {
+			return 0, err
+		}
+		if !reTypePages.Match(node) {
+			return num, nil
+		}
+		m := reKids.FindSubmatch(node)
+		if m == nil {
+			return 0, fmt.Errorf("%w: empty page tree", ErrMalformedPDF)
+		}
+		num, _ = strconv.Atoi(string(m[1]))
+	}
+	return 0, fmt.Errorf("%w: page tree too deep", ErrMalformedPDF)
+}
+
+// extendDict inserts entries before the closing ">>" of a dictionary.
+func extendDict(dict []byte, entries string) ([]byte, error) {
+	end := bytes.LastIndex(dict, []byte(">>"))
+	if !bytes.HasPrefix(dict, []byte("<<")) || end < 0 {
+		return nil, fmt.Errorf("%w: expected a dictionary", ErrMalformedPDF)
+	}
+	out := append([]byte{}, dict[:end]...)
+	out = append(out, '\n')
+	out = append(out, entries...)
+	out = append(out, '\n')
+	return append(out, dict[end:]...), nil
+}
+
+// update is an incremental update appended after the original document.
+type update struct {
+	buf     bytes.Buffer
+	base    int
+	offsets map[int]int
+}
+
+func (u *update) addObject(num int, body []byte) {
+	u.offsets[num] = u.base + u.buf.Len()
+	fmt.Fprintf(&u.buf, "%d 0 obj\n", num)
+	u.buf.Write(body)
+	u.buf.WriteString("\nendobj\n")
+}
+
+// finish writes the cross reference section and trailer of the update.
+func (u *update) finish(d *document, size int) {
+	nums := make([]int, 0, len(u.offsets))
+	for n := range u.offsets {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	xref := u.base + u.buf.Len()
+	u.buf.WriteString("xref\n")
+	for i := 0; i < len(nums); {
+		j := i + 1
+		for j < len(nums) && nums[j] == nums[j-1]+1 {
+			j++
+		}
+		fmt.Fprintf(&u.buf, "%d %d\n", nums[i], j-i)
+		for _, n := range nums[i:j] {
+			fmt.Fprintf(&u.buf, "%010d 00000 n \n", u.offsets[n])
+		}
+		i = j
+	}
+	fmt.Fprintf(&u.buf, "trailer\n<<\n/Size %d\n/Root %d 0 R\n", size, d.root)
+	if d.info != 0 {
+		fmt.Fprintf(&u.buf, "/Info %d 0 R\n", d.info)
+	}
+	if d.encrypt != 0 {
+		fmt.Fprintf(&u.buf, "/Encrypt %d 0 R\n", d.encrypt)
+	}
+	if d.id != nil {
+		fmt.Fprintf(&u.buf, "%s\n", d.id)
+	}
+	fmt.Fprintf(&u.buf, "/Prev %d\n>>\nstartxref\n%d\n%%%%EOF\n", d.xref, xref)
+}
+
+// textString encodes s as a PDF hex text string in UTF-16 with a byte order
+// mark, encrypted for object num when the document is encrypted.
+func textString(s string, key []byte, num int) string {
+	raw := []byte{0xFE, 0xFF}
+	for _, r := range utf16.Encode([]rune(s)) {
+		raw = append(raw, byte(r>>8), byte(r))
+	}
+	if key != nil {
+		rc4Crypt(key, num, raw)
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(raw)) + ">"
+}
+
+// decodeTextString reverses textString. Hex strings without a byte order
+// mark are read as Latin-1.
+func decodeTextString(hexStr string, key []byte, num int) string {
+	raw, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return ""
+	}
+	if key != nil {
+		rc4Crypt(key, num, raw)
+	}
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// pdfName encodes s as a PDF name, escaping everything but letters, digits
+// and a few punctuation characters.
+func pdfName(s string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "#%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// decodeName reverses pdfName for a name without its leading slash.
+func decodeName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if v, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package pdfsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+var (
+	ErrMalformedPDF = errors.New("malformed pdf")
+	ErrNotSigned    = errors.New("pdf is not signed")
+)
+
+// signatureSize is the space reserved for the CMS signature on top of the
+// certificates it embeds.
+const signatureSize = 4096
+
+// Document describes what a signed report covers. It is stored in the
+// signature dictionary so that it is protected by the signature.
+type Document struct {
+	Kind string `json:"kind"`
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Password is the user password of an encrypted document, needed to
+	// encrypt the strings of the signature.
+	Password string `json:"-"`
+}
+
+// Signer applies detached PKCS#7 signatures to PDF documents with a locally
+// configured certificate and key.
+type Signer struct {
+	cert     *x509.Certificate
+	chain    []*x509.Certificate
+	key      crypto.PrivateKey
+	roots    *x509.CertPool
+	reason   string
+	location string
+}
+
+// NewSigner loads a PEM certificate chain, leaf first, and its PEM private
+// key (PKCS#1, PKCS#8 or SEC 1).
+func NewSigner(certFile, keyFile, reason, location string) (*Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing certificate: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %v", err)
+	}
+
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing signing certificate: %v", err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("signing certificate file has no certificate")
+	}
+
+	key, err := parseKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported signing key type")
+	}
+	if pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(certs[0].PublicKey) {
+		return nil, errors.New("signing key does not match the certificate")
+	}
+
+	roots := x509.NewCertPool()
+	for _, c := range certs {
+		roots.AddCert(c)
+	}
+	return &Signer{cert: certs[0], chain: certs[1:], key: key, roots: roots, reason: reason, location: location}, nil
+}
+
+func parseKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key file has no PEM block")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported signing key format")
+}
+
+// Sign appends an incremental update to pdf holding an invisible signature
+// field on the first page and a detached CMS signature over the whole file.
+func (s *Signer) Sign(pdf []byte, doc Document) ([]byte, error) {
+	d, err := parseDocument(pdf)
+	if err != nil {
+		return nil, err
+	}
+	key, err := d.encryptionKey(doc.Password)
+	if err != nil {
+		return nil, err
+	}
+	page, err := d.firstPage()
+	if err != nil {
+		return nil, err
+	}
+	pageDict, err := d.object(page)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := d.object(d.root)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(catalog, []byte("/AcroForm")) {
+		return nil, fmt.Errorf("%w: document already has a form", ErrMalformedPDF)
+	}
+
+	sigNum, fieldNum := d.size, d.size+1
+	if i := bytes.Index(pageDict, []byte("/Annots [")); i >= 0 {
+		at := i + len("/Annots [")
+		pageDict = append(pageDict[:at:at], append([]byte(fmt.Sprintf("%d 0 R ", fieldNum)), pageDict[at:]...)...)
+	} else if pageDict, err = extendDict(pageDict, fmt.Sprintf("/Annots [%d 0 R]", fieldNum)); err != nil {
+		return nil, err
+	}
+	if catalog, err = extendDict(catalog, fmt.Sprintf("/AcroForm << /Fields [%d 0 R] /SigFlags 3 >>", fieldNum)); err != nil {
+		return nil, err
+	}
+
+	size := signatureSize
+	for _, c := range append([]*x509.Certificate{s.cert}, s.chain...) {
+		size += len(c.Raw)
+	}
+
+	u := &update{base: len(pdf), offsets: map[int]int{}}
+	u.buf.WriteString("\n")
+	u.addObject(page, pageDict)
+	u.addObject(d.root, catalog)
+
+	// The byte range and contents are placeholders of fixed width that are
+	// filled in once the final layout is known.
+	var sig bytes.Buffer
+	sig.WriteString("<<\n/Type /Sig\n/Filter /Adobe.PPKLite\n/SubFilter /adbe.pkcs7.detached\n")
+	sig.WriteString("/ByteRange [0 0000000000 0000000000 0000000000]\n")
+	sig.WriteString("/Contents <")
+	sig.Write(bytes.Repeat([]byte("0"), size*2))
+	sig.WriteString(">\n")
+	fmt.Fprintf(&sig, "/M %s\n", textString("D:"+time.Now().UTC().Format("20060102150405Z"), key, sigNum))
+	fmt.Fprintf(&sig, "/Name %s\n", textString(s.cert.Subject.CommonName, key, sigNum))
+	if s.reason != "" {
+		fmt.Fprintf(&sig, "/Reason %s\n", textString(s.reason, key, sigNum))
+	}
+	if s.location != "" {
+		fmt.Fprintf(&sig, "/Location %s\n", textString(s.location, key, sigNum))
+	}
+	// The kind and id are not strings so that they stay readable without
+	// the password of an encrypted document.
+	fmt.Fprintf(&sig, "/ReportKind %s\n/ReportID %d\n/ReportName %s\n>>", pdfName(doc.Kind), doc.ID, textString(doc.Name, key, sigNum))
+	u.addObject(sigNum, sig.Bytes())
+
+	u.addObject(fieldNum, []byte(fmt.Sprintf(
+		"<<\n/Type /Annot\n/Subtype /Widget\n/FT /Sig\n/Rect [0 0 0 0]\n/F 132\n/T %s\n/V %d 0 R\n/P %d 0 R\n>>",
+		textString("Signature1", key, fieldNum), sigNum, page)))
+	u.finish(d, d.size+2)
+
+	out := append(append(make([]byte, 0, len(pdf)+u.buf.Len()), pdf...), u.buf.Bytes()...)
+	sigStart := bytes.LastIndex(out, []byte("/Contents <")) + len("/Contents ")
+	sigEnd := sigStart + size*2 + 2
+	byteRange := fmt.Sprintf("[0 %010d %010d %010d]", sigStart, sigEnd, len(out)-sigEnd)
+	brAt := bytes.LastIndex(out[:sigStart], []byte("/ByteRange ")) + len("/ByteRange ")
+	copy(out[brAt:], byteRange)
+
+	signed := append(append([]byte{}, out[:sigStart]...), out[sigEnd:]...)
+	cms, err := s.cms(signed)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) > size {
+		return nil, fmt.Errorf("signature of %d bytes exceeds the reserved %d bytes", len(cms), size)
+	}
+	copy(out[sigStart+1:], bytes.ToUpper([]byte(hex.EncodeToString(cms))))
+	return out, nil
+}
+
+func (s *Signer) cms(content []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSignerChain(s.cert, s.key, s.chain, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("signing report: %v", err)
+	}
+	sd.Detach()
+	return sd.Finish()
+}
+
+// Writer is a report that can be written out, see report.Writer.
+type Writer interface {
+	Output(w io.Writer) error
+}
+
+// Wrap returns a writer producing the signed form of the PDF written by w. A
+// nil signer returns w unchanged.
+func (s *Signer) Wrap(w Writer, doc Document) Writer {
+	if s == nil {
+		return w
+	}
+	return &signedWriter{signer: s, pdf: w, doc: doc}
+}
+
+type signedWriter struct {
+	signer *Signer
+	pdf    Writer
+	doc    Document
+}
+
+func (sw *signedWriter) Output(w io.Writer) error {
+	var buf bytes.Buffer
+	if err := sw.pdf.Output(&buf); err != nil {
+		return err
+	}
+	signed, err := sw.signer.Sign(buf.Bytes(), sw.doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(signed)
+	return err
+}
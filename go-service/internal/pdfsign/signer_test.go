@@ -0,0 +1,240 @@
+package pdfsign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// newTestSigner creates a self-signed certificate and key on disk.
+func newTestSigner(t *testing.T, cn string) *Signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	s, err := NewSigner(certFile, keyFile, "Issued by the school", "Springfield")
+	if err != nil {
+		t.Fatalf("unexpected signer error: %v", err)
+	}
+	return s
+}
+
+func samplePDF(t *testing.T) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 12)
+	pdf.CellFormat(40, 10, "Linked", "", 1, "", false, 0, "https://example.com")
+	pdf.AddPage()
+	buf := new(bytes.Buffer)
+	if err := pdf.Output(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer := newTestSigner(t, "Springfield Public School")
+	doc := Document{Kind: "student", ID: 7, Name: "Zoë Ελένη"}
+
+	signed, err := signer.Sign(samplePDF(t), doc)
+	if err != nil {
+		t.Fatalf("unexpected sign error: %v", err)
+	}
+
+	v, err := signer.Verify(signed, "")
+	if err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	if !v.Valid || v.Signer != "Springfield Public School" || v.SignedAt == nil {
+		t.Errorf("expected a valid signature, got %+v", v)
+	}
+	if v.Document == nil || *v.Document != doc {
+		t.Errorf("expected document %+v, got %+v", doc, v.Document)
+	}
+	if v.Reason != "Issued by the school" {
+		t.Errorf("unexpected reason %q", v.Reason)
+	}
+
+	// Changing a signed byte breaks the signature.
+	tampered := append([]byte{}, signed...)
+	i := bytes.Index(tampered, []byte("/MediaBox"))
+	tampered[i+1] = 'N'
+	if v, _ := signer.Verify(tampered, ""); v.Intact || v.Valid {
+		t.Errorf("expected tampered document to fail, got %+v", v)
+	}
+
+	// Content appended after signing is not covered.
+	appended := append(append([]byte{}, signed...), []byte("1 0 obj\n<<>>\nendobj\n")...)
+	if v, _ := signer.Verify(appended, ""); !v.Intact || v.CoversWholeDocument || v.Valid {
+		t.Errorf("expected appended document to be flagged, got %+v", v)
+	}
+
+	// A signature from another certificate is intact but not trusted.
+	if v, _ := newTestSigner(t, "Someone Else").Verify(signed, ""); !v.Intact || v.Trusted {
+		t.Errorf("expected untrusted signature, got %+v", v)
+	}
+
+	if _, err := signer.Verify(samplePDF(t), ""); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("expected ErrNotSigned, got %v", err)
+	}
+
+	// Byte ranges that overflow or run past the file are rejected.
+	var gap [2]int
+	fmt.Sscanf(string(reByteRange.Find(signed)), "/ByteRange [0 %d %d", &gap[0], &gap[1])
+	for _, br := range []string{
+		fmt.Sprintf("/ByteRange [0 %d %d 9223372036854775807]", gap[0], gap[1]),
+		fmt.Sprintf("/ByteRange [0 %d %d 99999999999999999999]", gap[0], gap[1]),
+		fmt.Sprintf("/ByteRange [0 %d %d %d]", gap[0], gap[1], len(signed)),
+	} {
+		forged := append(append([]byte{}, signed...), []byte(br)...)
+		if _, err := signer.Verify(forged, ""); !errors.Is(err, ErrMalformedPDF) {
+			t.Errorf("%s: expected ErrMalformedPDF, got %v", br, err)
+		}
+	}
+}
+
+func TestNewSigner_KeyMismatch(t *testing.T) {
+	a, b := newTestSigner(t, "a"), newTestSigner(t, "b")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(b.key)
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.cert.Raw}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	if _, err := NewSigner(certFile, keyFile, "", ""); err == nil {
+		t.Error("expected error for a key that does not match the certificate")
+	}
+}
+
+func TestHandler_Verify(t *testing.T) {
+	signer := newTestSigner(t, "School")
+	signed, err := signer.Sign(samplePDF(t), Document{Kind: "student", ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(signer)
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, _ := mw.CreateFormFile("file", "report.pdf")
+	part.Write(signed)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.Verify(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data Verification `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data.Valid || resp.Data.Document.ID != 1 {
+		t.Errorf("unexpected verification %+v", resp.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(samplePDF(t)))
+	req.Header.Set("Content-Type", "application/pdf")
+	rec = httptest.NewRecorder()
+	handler.Verify(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unsigned pdf, got %d", rec.Code)
+	}
+}
+
+func TestSigner_EncryptedDocument(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtection(gofpdf.CnProtectPrint, "02012000", "owner")
+	pdf.AddPage()
+	buf := new(bytes.Buffer)
+	if err := pdf.Output(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The derived key decrypts the strings gofpdf encrypted itself.
+	d, err := parseDocument(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := d.encryptionKey("02012000")
+	if err != nil {
+		t.Fatalf("unexpected key error: %v", err)
+	}
+	info, _ := d.object(d.info)
+	producer, ok := literalEntry(info, "/Producer")
+	if !ok {
+		t.Fatal("missing producer")
+	}
+	rc4Crypt(key, d.info, producer)
+	if !bytes.HasPrefix(producer, []byte{0xFE, 0xFF}) {
+		t.Errorf("expected a decrypted UTF-16 producer, got %q", producer)
+	}
+
+	signer := newTestSigner(t, "School")
+	doc := Document{Kind: "student", ID: 7, Name: "Test Student", Password: "02012000"}
+	if _, err := signer.Sign(buf.Bytes(), Document{Kind: "student", Password: "wrong"}); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+	signed, err := signer.Sign(buf.Bytes(), doc)
+	if err != nil {
+		t.Fatalf("unexpected sign error: %v", err)
+	}
+	if bytes.Contains(signed[buf.Len():], []byte("(D:")) {
+		t.Error("expected the signing time to be encrypted")
+	}
+
+	v, err := signer.Verify(signed, "02012000")
+	if err != nil || !v.Valid || v.Document.Name != "Test Student" || v.Reason != "Issued by the school" {
+		t.Errorf("expected a valid signature with readable name, got %+v, %v", v, err)
+	}
+	v, err = signer.Verify(signed, "")
+	if err != nil || !v.Valid || v.Document.ID != 7 || v.Document.Name != "" {
+		t.Errorf("expected kind and id without the password, got %+v, %v", v, err)
+	}
+}
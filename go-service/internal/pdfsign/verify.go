@@ -0,0 +1,144 @@
+package pdfsign
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// Verification is the outcome of checking the last signature of a PDF.
+type Verification struct {
+	// Valid is set when the signature is intact, trusted and covers the
+	// whole file.
+	Valid bool `json:"valid"`
+	// Intact means the signed bytes match the signature.
+	Intact bool `json:"intact"`
+	// Trusted means the signer chains to the configured certificate.
+	Trusted bool `json:"trusted"`
+	// CoversWholeDocument is false when content was appended after signing.
+	CoversWholeDocument bool       `json:"coversWholeDocument"`
+	Signer              string     `json:"signer,omitempty"`
+	SignedAt            *time.Time `json:"signedAt,omitempty"`
+	Document            *Document  `json:"document,omitempty"`
+	Reason              string     `json:"reason,omitempty"`
+}
+
+var (
+	reByteRange  = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+	reContents   = regexp.MustCompile(`/Contents\s*<([0-9A-Fa-f]*)>`)
+	reHexString  = regexp.MustCompile(`^<([0-9A-Fa-f]*)>$`)
+	reReportKind = regexp.MustCompile(`/ReportKind\s*/([^\s/<>\[\]()]*)`)
+	reObjStart   = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	reReportID   = regexp.MustCompile(`/ReportID\s+(\d+)`)
+	reReportName = regexp.MustCompile(`/ReportName\s*<([0-9A-Fa-f]*)>`)
+	reReason     = regexp.MustCompile(`/Reason\s*<([0-9A-Fa-f]*)>`)
+)
+
+// Verify checks the most recent signature of pdf. Trust is only established
+// when the signer is not nil. The password of an encrypted document is only
+// needed to read the name of what it covers. Documents that cannot be
+// checked at all return ErrNotSigned or ErrMalformedPDF.
+func (s *Signer) Verify(pdf []byte, password string) (*Verification, error) {
+	matches := reByteRange.FindAllSubmatchIndex(pdf, -1)
+	if len(matches) == 0 {
+		return nil, ErrNotSigned
+	}
+	m := matches[len(matches)-1]
+	var br [4]int
+	for i := range br {
+		n, err := strconv.Atoi(string(pdf[m[2+2*i]:m[3+2*i]]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid byte range", ErrMalformedPDF)
+		}
+		br[i] = n
+	}
+	// Comparing against the remaining length cannot overflow.
+	if br[0] != 0 || br[1] > br[2] || br[2] > len(pdf) || br[3] > len(pdf)-br[2] {
+		return nil, fmt.Errorf("%w: invalid byte range", ErrMalformedPDF)
+	}
+
+	dict, sigNum := signatureDict(pdf, m[0])
+	var key []byte
+	d, err := parseDocument(pdf)
+	encrypted := err == nil && d.encrypt != 0
+	if encrypted && password != "" {
+		if key, err = d.encryptionKey(password); err != nil {
+			return nil, err
+		}
+	}
+	cm := reHexString.FindSubmatch(pdf[br[1]:br[2]])
+	if cm == nil {
+		return nil, fmt.Errorf("%w: signature has no contents", ErrMalformedPDF)
+	}
+	der, err := hex.DecodeString(string(cm[1]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature contents: %v", ErrMalformedPDF, err)
+	}
+	// The contents are zero padded to the reserved size.
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature contents: %v", ErrMalformedPDF, err)
+	}
+	p7, err := pkcs7.Parse(der[:len(der)-len(rest)])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature contents: %v", ErrMalformedPDF, err)
+	}
+
+	p7.Content = append(append([]byte{}, pdf[:br[1]]...), pdf[br[2]:br[2]+br[3]]...)
+	v := &Verification{
+		Intact:              p7.Verify() == nil,
+		CoversWholeDocument: br[2]+br[3] == len(bytes.TrimRight(pdf, "\r\n")) || br[2]+br[3] == len(pdf),
+	}
+	if s != nil && v.Intact {
+		v.Trusted = p7.VerifyWithChain(s.roots) == nil
+	}
+	v.Valid = v.Intact && v.Trusted && v.CoversWholeDocument
+
+	if signer := p7.GetOnlySigner(); signer != nil {
+		v.Signer = signer.Subject.CommonName
+	}
+	var signedAt time.Time
+	if err := p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &signedAt); err == nil {
+		v.SignedAt = &signedAt
+	}
+	if m := reReportKind.FindSubmatch(dict); m != nil {
+		doc := &Document{Kind: decodeName(string(m[1]))}
+		if m := reReportID.FindSubmatch(dict); m != nil {
+			doc.ID, _ = strconv.Atoi(string(m[1]))
+		}
+		v.Document = doc
+	}
+
+	// Strings of encrypted documents can only be read with the password.
+	if !encrypted || key != nil {
+		if m := reReportName.FindSubmatch(dict); m != nil && v.Document != nil {
+			v.Document.Name = decodeTextString(string(m[1]), key, sigNum)
+		}
+		if m := reReason.FindSubmatch(dict); m != nil {
+			v.Reason = decodeTextString(string(m[1]), key, sigNum)
+		}
+	}
+	return v, nil
+}
+
+// signatureDict returns the object enclosing the byte range at offset and
+// its number, with the signature contents removed to keep the search small.
+func signatureDict(pdf []byte, offset int) ([]byte, int) {
+	head := pdf[max(0, offset-256):offset]
+	starts := reObjStart.FindAllSubmatchIndex(head, -1)
+	end := bytes.Index(pdf[offset:], []byte("endobj"))
+	if len(starts) == 0 || end < 0 {
+		return nil, 0
+	}
+	last := starts[len(starts)-1]
+	num, _ := strconv.Atoi(string(head[last[2]:last[3]]))
+	start := offset - len(head) + last[0]
+	return reContents.ReplaceAll(pdf[start:offset+end], nil), num
+}
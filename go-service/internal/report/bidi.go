@@ -0,0 +1,356 @@
+package report
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// PDF text is drawn glyph by glyph from left to right, so right-to-left
+// scripts have to be shaped and reordered before they reach gofpdf. The
+// functions below implement the parts of the Unicode bidirectional algorithm
+// and Arabic joining needed for single-direction labels and values such as
+// names and addresses.
+
+// arabicForm lists the isolated, final, initial and medial presentation forms
+// of an Arabic letter. Right-joining letters only have the first two.
+type arabicForm [4]rune
+
+const (
+	formIsolated = iota
+	formFinal
+	formInitial
+	formMedial
+)
+
+var arabicForms = map[rune]arabicForm{
+	0x0621: {0xFE80, 0, 0, 0},
+	0x0622: {0xFE81, 0xFE82, 0, 0},
+	0x0623: {0xFE83, 0xFE84, 0, 0},
+	0x0624: {0xFE85, 0xFE86, 0, 0},
+	0x0625: {0xFE87, 0xFE88, 0, 0},
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	0x0629: {0xFE93, 0xFE94, 0, 0},
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	0x0648: {0xFEED, 0xFEEE, 0, 0},
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59},
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D},
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91},
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95},
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF},
+}
+
+// lamAlef maps the alef following a lam to the isolated and final forms of
+// the mandatory ligature.
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+const (
+	arabicLam     = 0x0644
+	arabicTatweel = 0x0640
+)
+
+// isTransparent reports whether r is a combining mark that does not affect
+// Arabic joining.
+func isTransparent(r rune) bool {
+	return (r >= 0x064B && r <= 0x065F) || r == 0x0670
+}
+
+func joinsBefore(r rune) bool {
+	if r == arabicTatweel {
+		return true
+	}
+	f, ok := arabicForms[r]
+	return ok && f[formInitial] != 0
+}
+
+func joinsAfter(r rune) bool {
+	if r == arabicTatweel {
+		return true
+	}
+	f, ok := arabicForms[r]
+	return ok && f[formFinal] != 0
+}
+
+// shapeArabic replaces Arabic letters with their contextual presentation
+// forms and applies lam-alef ligatures. Text without Arabic is returned as is.
+func shapeArabic(s string) string {
+	runes := []rune(s)
+	hasArabic := false
+	for _, r := range runes {
+		if _, ok := arabicForms[r]; ok {
+			hasArabic = true
+			break
+		}
+	}
+	if !hasArabic {
+		return s
+	}
+
+	neighbour := func(i, step int) rune {
+		for j := i + step; j >= 0 && j < len(runes); j += step {
+			if !isTransparent(runes[j]) {
+				return runes[j]
+			}
+		}
+		return 0
+	}
+
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicForms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		prev := neighbour(i, -1)
+		joinPrev := prev != 0 && joinsBefore(prev)
+
+		if r == arabicLam {
+			if next := neighbour(i, 1); next != 0 {
+				if lig, ok := lamAlef[next]; ok {
+					if joinPrev {
+						out = append(out, lig[1])
+					} else {
+						out = append(out, lig[0])
+					}
+					// Skip to the alef, keeping any marks in between.
+					for i++; runes[i] != next; i++ {
+						out = append(out, runes[i])
+					}
+					continue
+				}
+			}
+		}
+
+		next := neighbour(i, 1)
+		joinNext := next != 0 && joinsAfter(next) && forms[formInitial] != 0
+
+		switch {
+		case joinPrev && joinNext:
+			out = append(out, forms[formMedial])
+		case joinPrev && forms[formFinal] != 0:
+			out = append(out, forms[formFinal])
+		case joinNext:
+			out = append(out, forms[formInitial])
+		default:
+			out = append(out, forms[formIsolated])
+		}
+	}
+	return string(out)
+}
+
+// reorderDevanagari moves the short i vowel sign in front of the consonant
+// cluster it follows, which is where it is displayed. Other conjunct shaping
+// is left to the font.
+func reorderDevanagari(s string) string {
+	const (
+		iMatra = 0x093F
+		virama = 0x094D
+	)
+	if !strings.ContainsRune(s, iMatra) {
+		return s
+	}
+	isConsonant := func(r rune) bool {
+		return (r >= 0x0915 && r <= 0x0939) || (r >= 0x0958 && r <= 0x095F)
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if r != iMatra || i == 0 || !isConsonant(runes[i-1]) {
+			continue
+		}
+		start := i - 1
+		for start >= 2 && runes[start-1] == virama && isConsonant(runes[start-2]) {
+			start -= 2
+		}
+		copy(runes[start+1:i+1], runes[start:i])
+		runes[start] = iMatra
+	}
+	return string(runes)
+}
+
+// visualOrder converts logical text to the left-to-right display order and
+// reports whether the paragraph direction is right-to-left. Text without
+// right-to-left characters is returned unchanged.
+func visualOrder(s string) (string, bool) {
+	runes := []rune(s)
+	classes := make([]bidi.Class, len(runes))
+	hasRTL := false
+	for i, r := range runes {
+		p, _ := bidi.LookupRune(r)
+		classes[i] = p.Class()
+		if classes[i] == bidi.R || classes[i] == bidi.AL {
+			hasRTL = true
+		}
+	}
+	if !hasRTL {
+		return s, false
+	}
+
+	const (
+		dirNeutral = iota
+		dirL
+		dirR
+		dirEN
+		dirAN
+	)
+
+	baseRTL := false
+	for _, c := range classes {
+		if c == bidi.L {
+			break
+		}
+		if c == bidi.R || c == bidi.AL {
+			baseRTL = true
+			break
+		}
+	}
+	baseDir := dirL
+	if baseRTL {
+		baseDir = dirR
+	}
+
+	// Resolve every character to a strong direction or number type.
+	dirs := make([]int, len(runes))
+	lastStrong := baseDir
+	for i, c := range classes {
+		switch c {
+		case bidi.L:
+			dirs[i], lastStrong = dirL, dirL
+		case bidi.R, bidi.AL:
+			dirs[i], lastStrong = dirR, dirR
+		case bidi.EN:
+			// European numbers after left-to-right text are left-to-right.
+			if lastStrong == dirL {
+				dirs[i] = dirL
+			} else {
+				dirs[i] = dirEN
+			}
+		case bidi.AN:
+			dirs[i] = dirAN
+		case bidi.NSM:
+			if i > 0 {
+				dirs[i] = dirs[i-1]
+			} else {
+				dirs[i] = baseDir
+			}
+		default:
+			dirs[i] = dirNeutral
+		}
+	}
+
+	// Neutrals take the direction of the surrounding text when both sides
+	// agree, numbers counting as right-to-left, and the base otherwise.
+	strongOf := func(d int) int {
+		if d == dirEN || d == dirAN {
+			return dirR
+		}
+		return d
+	}
+	for i := 0; i < len(dirs); {
+		if dirs[i] != dirNeutral {
+			i++
+			continue
+		}
+		j := i
+		for j < len(dirs) && dirs[j] == dirNeutral {
+			j++
+		}
+		before, after := baseDir, baseDir
+		if i > 0 {
+			before = strongOf(dirs[i-1])
+		}
+		if j < len(dirs) {
+			after = strongOf(dirs[j])
+		}
+		resolved := baseDir
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			dirs[k] = resolved
+		}
+		i = j
+	}
+
+	// Assign embedding levels and reverse every run at or above each odd
+	// level, from the highest level down.
+	levels := make([]int, len(runes))
+	maxLevel := 0
+	for i, d := range dirs {
+		switch {
+		case !baseRTL && d == dirR:
+			levels[i] = 1
+		case !baseRTL && (d == dirEN || d == dirAN):
+			levels[i] = 2
+		case baseRTL && d != dirR:
+			levels[i] = 2
+		case baseRTL:
+			levels[i] = 1
+		}
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+	for level := maxLevel; level >= 1; level-- {
+		for i := 0; i < len(runes); {
+			if levels[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runes) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runes[a], runes[b] = runes[b], runes[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+
+	// Brackets inside right-to-left runs are displayed mirrored.
+	for i, r := range runes {
+		if levels[i]%2 == 1 {
+			if p, _ := bidi.LookupRune(r); p.IsBracket() {
+				runes[i] = []rune(bidi.ReverseString(string(r)))[0]
+			}
+		}
+	}
+	return string(runes), baseRTL
+}
@@ -0,0 +1,233 @@
+package report
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	logoImageName = "brand-logo"
+	logoHeight    = 18.0
+	footerHeight  = 16.0
+)
+
+var defaultAccent = [3]int{60, 60, 60}
+
+// Branding is the school identity printed on PDF reports.
+type Branding struct {
+	SchoolName            string
+	Address               string
+	LogoPath              string
+	AccentColor           string
+	FooterText            string
+	ConfidentialityNotice string
+}
+
+// Brand is a validated Branding with its logo loaded, ready to be applied to
+// documents.
+type Brand struct {
+	Branding
+	accent    [3]int
+	logo      []byte
+	logoType  string
+	logoRatio float64
+	version   string
+}
+
+// LoadBrand validates the accent colour and reads the logo image so that a
+// broken configuration is reported once instead of on every report.
+func LoadBrand(b Branding) (*Brand, error) {
+	brand := &Brand{Branding: b, accent: defaultAccent}
+	if b.AccentColor != "" {
+		c, err := parseHexColor(b.AccentColor)
+		if err != nil {
+			return nil, err
+		}
+		brand.accent = c
+	}
+	if b.LogoPath != "" {
+		data, err := os.ReadFile(b.LogoPath)
+		if err != nil {
+			return nil, fmt.Errorf("branding logo: %v", err)
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("branding logo %s: %v", b.LogoPath, err)
+		}
+		if cfg.Height == 0 {
+			return nil, fmt.Errorf("branding logo %s: empty image", b.LogoPath)
+		}
+		brand.logo, brand.logoType = data, format
+		brand.logoRatio = float64(cfg.Width) / float64(cfg.Height)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n", b)
+	h.Write(brand.logo)
+	brand.version = hex.EncodeToString(h.Sum(nil))[:16]
+	return brand, nil
+}
+
+// Version identifies the branding and logo content so that output rendered
+// with an older brand can be told apart. A nil brand has an empty version.
+func (b *Brand) Version() string {
+	if b == nil {
+		return ""
+	}
+	return b.version
+}
+
+// Accent returns the accent colour as RGB, the default grey for a nil brand.
+func (b *Brand) Accent() [3]int {
+	if b == nil {
+		return defaultAccent
+	}
+	return b.accent
+}
+
+// DrawLogo draws the logo h millimetres high at x, y and returns its width,
+// zero when there is no logo.
+func (b *Brand) DrawLogo(pdf *gofpdf.Fpdf, x, y, h float64) float64 {
+	if b == nil || b.logo == nil {
+		return 0
+	}
+	opts := gofpdf.ImageOptions{ImageType: b.logoType}
+	if info := pdf.GetImageInfo(logoImageName); info == nil {
+		pdf.RegisterImageOptionsReader(logoImageName, opts, bytes.NewReader(b.logo))
+	}
+	w := h * b.logoRatio
+	pdf.ImageOptions(logoImageName, x, y, w, h, false, opts, 0, "")
+	return w
+}
+
+// parseHexColor parses "#RRGGBB" or "RRGGBB".
+func parseHexColor(s string) ([3]int, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return [3]int{}, fmt.Errorf("invalid accent colour %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return [3]int{}, fmt.Errorf("invalid accent colour %q", s)
+	}
+	return [3]int{int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff)}, nil
+}
+
+// Apply adds the letterhead to every page of pdf and a footer with the
+// footer text, generation time, page count and confidentiality notice.
+// label, when set, is printed before the page number. It must be called
+// right after the document is created, before the first page is added.
+func (b *Brand) Apply(pdf *gofpdf.Fpdf, fonts *FontSet, label func() string) {
+	if b == nil {
+		return
+	}
+	// The alias is set after the fonts are registered so their subsets keep
+	// the digits the page count is replaced with.
+	pdf.AliasNbPages("")
+	generated := time.Now().Format("2006-01-02 15:04")
+
+	if b.logo != nil {
+		pdf.RegisterImageOptionsReader(logoImageName, gofpdf.ImageOptions{ImageType: b.logoType}, bytes.NewReader(b.logo))
+	}
+	if b.SchoolName != "" || b.Address != "" || b.logo != nil {
+		pdf.SetHeaderFunc(func() { b.letterhead(pdf, fonts) })
+	}
+
+	_, _, _, bottom := pdf.GetMargins()
+	pdf.SetAutoPageBreak(true, max(bottom, footerHeight+4))
+	pdf.SetFooterFunc(func() {
+		left, _, right, _ := pdf.GetMargins()
+		pageW, _ := pdf.GetPageSize()
+		width := pageW - left - right
+
+		pdf.SetY(-footerHeight)
+		pdf.SetDrawColor(b.accent[0], b.accent[1], b.accent[2])
+		pdf.Line(left, pdf.GetY(), left+width, pdf.GetY())
+		pdf.SetTextColor(90, 90, 90)
+
+		small := Font{Size: 8}
+		page := fmt.Sprintf("Page %d of {nb}", pdf.PageNo())
+		if label != nil {
+			if l := label(); l != "" {
+				page = l + " - " + page
+			}
+		}
+		third := width / 3
+		fonts.Cell(pdf, small, third, 5, b.FooterText, "", 0, "L", 0)
+		fonts.Cell(pdf, small, third, 5, "Generated "+generated, "", 0, "C", 0)
+		fonts.Cell(pdf, small, third, 5, page, "", 1, "R", 0)
+		if b.ConfidentialityNotice != "" {
+			fonts.Cell(pdf, Font{Style: "I", Size: 7}, width, 4, b.ConfidentialityNotice, "", 1, "C", 0)
+		}
+		pdf.SetTextColor(0, 0, 0)
+	})
+}
+
+// letterhead draws the logo, school name and address at the top of the
+// page, followed by a rule in the accent colour.
+func (b *Brand) letterhead(pdf *gofpdf.Fpdf, fonts *FontSet) {
+	left, top, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+
+	textX := left
+	if b.logo != nil {
+		pdf.ImageOptions(logoImageName, left, top, logoHeight*b.logoRatio, logoHeight, false, gofpdf.ImageOptions{ImageType: b.logoType}, 0, "")
+		textX += logoHeight*b.logoRatio + 4
+	}
+	width := pageW - right - textX
+
+	pdf.SetXY(textX, top+1)
+	pdf.SetTextColor(b.accent[0], b.accent[1], b.accent[2])
+	fonts.Cell(pdf, Font{Style: "B", Size: 16}, width, 8, b.SchoolName, "", 2, "L", 0)
+	pdf.SetTextColor(90, 90, 90)
+	if b.Address != "" {
+		fonts.Cell(pdf, Font{Size: 9}, width, 5, b.Address, "", 2, "L", 0)
+	}
+	pdf.SetTextColor(0, 0, 0)
+
+	y := top + logoHeight + 2
+	pdf.SetDrawColor(b.accent[0], b.accent[1], b.accent[2])
+	pdf.SetLineWidth(0.6)
+	pdf.Line(left, y, pageW-right, y)
+	pdf.SetLineWidth(0.2)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetXY(left, y+4)
+}
+
+// BrandStore holds the current brand and lets it be swapped while reports
+// are being rendered.
+type BrandStore struct {
+	brand atomic.Pointer[Brand]
+}
+
+func NewBrandStore(b *Brand) *BrandStore {
+	s := &BrandStore{}
+	s.brand.Store(b)
+	return s
+}
+
+// Current returns the brand to apply to a new document. A nil store has no
+// brand.
+func (s *BrandStore) Current() *Brand {
+	if s == nil {
+		return nil
+	}
+	return s.brand.Load()
+}
+
+// Update replaces the brand used by reports generated from now on.
+func (s *BrandStore) Update(b *Brand) {
+	s.brand.Store(b)
+}
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+func writeLogo(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logo.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBrand(t *testing.T) {
+	brand, err := LoadBrand(Branding{SchoolName: "School", AccentColor: "#1F4E79", LogoPath: writeLogo(t)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if brand.accent != [3]int{0x1F, 0x4E, 0x79} || brand.logoType != "png" || brand.logoRatio != 2 {
+		t.Errorf("unexpected brand %+v", brand.accent)
+	}
+
+	for name, b := range map[string]Branding{
+		"bad colour":   {AccentColor: "blue"},
+		"missing logo": {LogoPath: filepath.Join(t.TempDir(), "none.png")},
+		"not an image": {LogoPath: "branding.go"},
+	} {
+		if _, err := LoadBrand(b); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRender_Branded(t *testing.T) {
+	brand, err := LoadBrand(Branding{
+		SchoolName:            "Springfield Public School",
+		Address:               "742 Evergreen Terrace",
+		LogoPath:              writeLogo(t),
+		FooterText:            "Student Records",
+		ConfidentialityNotice: "Confidential",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmpl, _ := (*Registry)(nil).Lookup(KindStudent, DefaultTemplate)
+
+	w, err := Render(FormatPDF, tmpl, sampleStudent(), PDFOptions{Brand: brand})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := w.Output(buf); err != nil {
+		t.Fatalf("output error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/Subtype /Image") {
+		t.Error("expected the logo to be embedded")
+	}
+}
+
+func TestBrand_DrawLogo(t *testing.T) {
+	var none *Brand
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	if none.Accent() != defaultAccent || none.DrawLogo(pdf, 0, 0, 10) != 0 {
+		t.Error("expected a nil brand to have the default accent and no logo")
+	}
+
+	brand, err := LoadBrand(Branding{AccentColor: "#1F4E79", LogoPath: writeLogo(t)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if brand.Accent() != [3]int{0x1F, 0x4E, 0x79} {
+		t.Errorf("unexpected accent %v", brand.Accent())
+	}
+	// The logo is registered once however often it is drawn.
+	for range 2 {
+		if w := brand.DrawLogo(pdf, 10, 10, 8); w != 16 {
+			t.Errorf("expected the logo 16mm wide, got %v", w)
+		}
+	}
+	if pdf.Err() {
+		t.Fatal(pdf.Error())
+	}
+}
+
+func TestBrandStore(t *testing.T) {
+	var nilStore *BrandStore
+	if nilStore.Current() != nil {
+		t.Error("expected no brand from a nil store")
+	}
+
+	first, _ := LoadBrand(Branding{SchoolName: "First"})
+	store := NewBrandStore(first)
+	second, _ := LoadBrand(Branding{SchoolName: "Second"})
+	store.Update(second)
+	if got := store.Current().SchoolName; got != "Second" {
+		t.Errorf("expected updated brand, got %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+package report
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Cache keeps rendered reports in memory, keyed by a hash of everything that
+// went into them. Entries expire after a TTL and the least recently used ones
+// are evicted once the total size exceeds the cap. A nil Cache stores
+// nothing.
+type Cache struct {
+	ttl      time.Duration
+	maxBytes int64
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	data    []byte
+	tags    []string
+	expires time.Time
+}
+
+// NewCache returns nil, a disabled cache, when ttl or maxBytes is not
+// positive.
+func NewCache(ttl time.Duration, maxBytes int64) *Cache {
+	if ttl <= 0 || maxBytes <= 0 {
+		return nil
+	}
+	return &Cache{ttl: ttl, maxBytes: maxBytes, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the cached report for key. The returned bytes must not be
+// modified.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.data, true
+}
+
+// Put stores a report. Reports larger than the cap are not cached. Tags,
+// such as the serial printed on the report, let EvictTag drop it early.
+func (c *Cache) Put(key string, data []byte, tags ...string) {
+	if c == nil || int64(len(data)) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, data: data, tags: tags, expires: time.Now().Add(c.ttl)})
+	c.size += int64(len(data))
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// EvictTag removes every report stored with tag.
+func (c *Cache) EvictTag(tag string) {
+	if c == nil || tag == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if slices.Contains(el.Value.(*cacheEntry).tags, tag) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// Len returns the number of cached reports.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.size -= int64(len(entry.data))
+}
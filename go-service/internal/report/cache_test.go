@@ -0,0 +1,52 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	if c := NewCache(0, 10); c != nil {
+		t.Fatal("expected a zero ttl to disable the cache")
+	}
+	var disabled *Cache
+	disabled.Put("a", []byte("x"))
+	if _, ok := disabled.Get("a"); ok {
+		t.Error("expected a nil cache to store nothing")
+	}
+
+	c := NewCache(time.Minute, 10)
+	c.Put("a", []byte("aaaa"))
+	c.Put("b", []byte("bbbb"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	// b is now the least recently used entry and makes room for c.
+	c.Put("c", []byte("cccc"))
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if data, ok := c.Get("a"); !ok || string(data) != "aaaa" {
+		t.Errorf("expected a to survive, got %q, %v", data, ok)
+	}
+	c.Put("big", make([]byte, 11))
+	if _, ok := c.Get("big"); ok || c.Len() != 2 {
+		t.Errorf("expected oversized entries to be skipped, have %d entries", c.Len())
+	}
+
+	c.Put("d", []byte("dd"), "SERIAL-1")
+	c.EvictTag("SERIAL-1")
+	if _, ok := c.Get("d"); ok {
+		t.Error("expected the tagged entry to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected untagged entries to be kept")
+	}
+
+	expiring := NewCache(time.Nanosecond, 10)
+	expiring.Put("a", []byte("aaaa"))
+	time.Sleep(time.Millisecond)
+	if _, ok := expiring.Get("a"); ok || expiring.Len() != 0 {
+		t.Error("expected the entry to expire")
+	}
+}
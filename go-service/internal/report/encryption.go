@@ -0,0 +1,205 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// KindClass identifies whole-class documents such as binders. It has no
+// templates of its own but can carry its own encryption policy.
+const KindClass = "class"
+
+var (
+	ErrPasswordRequired       = errors.New("a report password is required")
+	ErrRequestPasswordDenied  = errors.New("request passwords are not allowed for this report")
+	ErrEncryptionNotSupported = errors.New("encrypted reports are only available as pdf")
+)
+
+// permissions maps policy names to the PDF permission flags they grant.
+var permissions = map[string]byte{
+	"print":    gofpdf.CnProtectPrint,
+	"modify":   gofpdf.CnProtectModify,
+	"copy":     gofpdf.CnProtectCopy,
+	"annotate": gofpdf.CnProtectAnnotForms,
+}
+
+// EncryptionPolicy decides whether and how a report type is encrypted.
+type EncryptionPolicy struct {
+	// Enabled encrypts every report; otherwise only reports requested with
+	// encryption are.
+	Enabled bool
+	// UserPassword is a text/template rule evaluated against the report data,
+	// e.g. {{.DOB.Format "02012006"}} for the student's date of birth.
+	UserPassword string
+	// OwnerPassword unlocks all permissions. Empty uses a random password.
+	OwnerPassword string
+	// Permissions lists what readers may do: print, copy, modify, annotate.
+	Permissions []string
+	// AllowRequestPassword lets callers supply the user password, which
+	// takes precedence over the rule.
+	AllowRequestPassword bool
+}
+
+// EncryptionRequest carries the caller's encryption choices.
+type EncryptionRequest struct {
+	Encrypt  bool
+	Password string
+}
+
+// Encryption is the protection applied to one document.
+type Encryption struct {
+	UserPassword  string
+	OwnerPassword string
+	Permissions   byte
+}
+
+// Apply protects pdf. It must be called before the document is output.
+func (e *Encryption) Apply(pdf *gofpdf.Fpdf) {
+	if e == nil {
+		return
+	}
+	pdf.SetProtection(e.Permissions, e.UserPassword, e.OwnerPassword)
+}
+
+type encryptionPolicy struct {
+	EncryptionPolicy
+	rule  *template.Template
+	flags byte
+}
+
+// Protection holds the deployment encryption policy and its per report type
+// overrides.
+type Protection struct {
+	fallback *encryptionPolicy
+	byKind   map[string]*encryptionPolicy
+}
+
+// NewProtection validates the default policy and the overrides keyed by
+// report kind, which replace the default entirely for that kind.
+func NewProtection(def EncryptionPolicy, byKind map[string]EncryptionPolicy) (*Protection, error) {
+	p := &Protection{byKind: map[string]*encryptionPolicy{}}
+	var err error
+	if p.fallback, err = compilePolicy("default", def); err != nil {
+		return nil, err
+	}
+	for kind, policy := range byKind {
+		if p.byKind[kind], err = compilePolicy(kind, policy); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func compilePolicy(name string, policy EncryptionPolicy) (*encryptionPolicy, error) {
+	c := &encryptionPolicy{EncryptionPolicy: policy}
+	for _, perm := range policy.Permissions {
+		flag, ok := permissions[strings.ToLower(perm)]
+		if !ok {
+			return nil, fmt.Errorf("encryption policy %s: unknown permission %q", name, perm)
+		}
+		c.flags |= flag
+	}
+	if policy.UserPassword != "" {
+		rule, err := template.New(name).Option("missingkey=error").Parse(policy.UserPassword)
+		if err != nil {
+			return nil, fmt.Errorf("encryption policy %s: %v", name, err)
+		}
+		c.rule = rule
+	}
+	return c, nil
+}
+
+// Resolve returns the encryption for a report of kind over data, or nil when
+// it is not encrypted. A nil Protection encrypts nothing by default.
+func (p *Protection) Resolve(kind string, data any, req EncryptionRequest) (*Encryption, error) {
+	policy := &encryptionPolicy{}
+	if p != nil {
+		policy = p.fallback
+		if k, ok := p.byKind[kind]; ok {
+			policy = k
+		}
+	}
+	if !policy.Enabled && !req.Encrypt && req.Password == "" {
+		return nil, nil
+	}
+
+	enc := &Encryption{OwnerPassword: policy.OwnerPassword, Permissions: policy.flags}
+	switch {
+	case req.Password != "":
+		if !policy.AllowRequestPassword {
+			return nil, ErrRequestPasswordDenied
+		}
+		enc.UserPassword = req.Password
+	case policy.rule != nil:
+		if unset := unsetFields(policy.rule, data); len(unset) > 0 {
+			return nil, fmt.Errorf("%w: %s is not set", ErrPasswordRequired, strings.Join(unset, ", "))
+		}
+		var b strings.Builder
+		if err := policy.rule.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("evaluating report password rule: %v", err)
+		}
+		enc.UserPassword = b.String()
+	}
+	if enc.UserPassword == "" {
+		return nil, ErrPasswordRequired
+	}
+	return enc, nil
+}
+
+// unsetFields lists the fields of data read by the password rule that hold
+// their zero value, e.g. a missing date of birth that would otherwise become
+// the password 01010001.
+func unsetFields(rule *template.Template, data any) []string {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return nil
+	}
+	var unset []string
+	var walk func(n parse.Node)
+	walkBranch := func(b *parse.BranchNode) {
+		walk(b.Pipe)
+		walk(b.List)
+		walk(b.ElseList)
+	}
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walkBranch(&n.BranchNode)
+		case *parse.WithNode:
+			walkBranch(&n.BranchNode)
+		case *parse.RangeNode:
+			walkBranch(&n.BranchNode)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if f := v.FieldByName(n.Ident[0]); f.IsValid() && f.IsZero() && !slices.Contains(unset, n.Ident[0]) {
+				unset = append(unset, n.Ident[0])
+			}
+		}
+	}
+	walk(rule.Tree.Root)
+	return unset
+}
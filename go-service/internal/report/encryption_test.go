@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProtection_Resolve(t *testing.T) {
+	p, err := NewProtection(
+		EncryptionPolicy{Permissions: []string{"print"}},
+		map[string]EncryptionPolicy{
+			KindStudent: {UserPassword: `{{.DOB.Format "02012006"}}`, OwnerPassword: "owner", Permissions: []string{"print"}, AllowRequestPassword: true},
+			KindClass:   {Enabled: true},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st := sampleStudent()
+
+	if enc, err := p.Resolve(KindStudent, st, EncryptionRequest{}); enc != nil || err != nil {
+		t.Errorf("expected no encryption by default, got %+v, %v", enc, err)
+	}
+	enc, err := p.Resolve(KindStudent, st, EncryptionRequest{Encrypt: true})
+	if err != nil || enc.UserPassword != "02012000" || enc.OwnerPassword != "owner" || enc.Permissions != 4 {
+		t.Errorf("expected DOB password, got %+v, %v", enc, err)
+	}
+	if enc, _ := p.Resolve(KindStudent, st, EncryptionRequest{Password: "secret"}); enc == nil || enc.UserPassword != "secret" {
+		t.Errorf("expected request password, got %+v", enc)
+	}
+	if _, err := p.Resolve("other", st, EncryptionRequest{Password: "secret"}); !errors.Is(err, ErrRequestPasswordDenied) {
+		t.Errorf("expected ErrRequestPasswordDenied, got %v", err)
+	}
+	noDOB := sampleStudent()
+	noDOB.DOB = time.Time{}
+	if _, err := p.Resolve(KindStudent, noDOB, EncryptionRequest{Encrypt: true}); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("expected ErrPasswordRequired for a missing date of birth, got %v", err)
+	}
+	if _, err := p.Resolve(KindClass, nil, EncryptionRequest{}); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("expected ErrPasswordRequired, got %v", err)
+	}
+
+	if _, err := NewProtection(EncryptionPolicy{Permissions: []string{"fly"}}, nil); err == nil {
+		t.Error("expected error for an unknown permission")
+	}
+	if _, err := NewProtection(EncryptionPolicy{UserPassword: "{{"}, nil); err == nil {
+		t.Error("expected error for an invalid password rule")
+	}
+}
+
+func TestRender_Encrypted(t *testing.T) {
+	tmpl, _ := (*Registry)(nil).Lookup(KindStudent, DefaultTemplate)
+	enc := &Encryption{UserPassword: "02012000"}
+
+	w, err := Render(FormatPDF, tmpl, sampleStudent(), PDFOptions{Encryption: enc})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := w.Output(buf); err != nil {
+		t.Fatalf("output error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Encrypt")) {
+		t.Error("expected an encrypted document")
+	}
+
+	if _, err := Render(FormatCSV, tmpl, sampleStudent(), PDFOptions{Encryption: enc}); !errors.Is(err, ErrEncryptionNotSupported) {
+		t.Errorf("expected ErrEncryptionNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/sfnt"
+)
+
+const (
+	// BundledFontFamily is the Unicode font embedded in the binary. It covers
+	// Latin, Greek, Cyrillic, Hebrew and Arabic and follows any configured
+	// fonts in every fallback chain.
+	BundledFontFamily = "DejaVu"
+
+	// DevanagariFontFamily follows the bundled font in the default chain,
+	// since DejaVu has no Devanagari glyphs.
+	DevanagariFontFamily = "NotoSansDevanagari"
+)
+
+//go:embed fonts/*.ttf
+var fontFS embed.FS
+
+// FontFile points at the TrueType files of one configured font family.
+// Missing styles fall back to the regular face.
+type FontFile struct {
+	Family     string
+	Regular    string
+	Bold       string
+	Italic     string
+	BoldItalic string
+}
+
+// fontFace is a loaded font family with the bytes of every style and the
+// parsed regular face used to check glyph coverage.
+type fontFace struct {
+	family string
+	styles map[string][]byte
+	cover  *sfnt.Font
+}
+
+// FontSet is an ordered fallback chain of UTF-8 fonts. Text is drawn with
+// the first family that has a glyph for every character.
+type FontSet struct {
+	faces []*fontFace
+}
+
+var bundledFonts = mustLoadBundledFonts()
+
+// LoadFonts builds a fallback chain from the configured fonts, in order,
+// followed by the bundled font.
+func LoadFonts(files []FontFile) (*FontSet, error) {
+	fs := &FontSet{}
+	for _, f := range files {
+		face, err := loadFontFile(f)
+		if err != nil {
+			return nil, err
+		}
+		fs.faces = append(fs.faces, face)
+	}
+	fs.faces = append(fs.faces, bundledFonts.faces...)
+	return fs, nil
+}
+
+func loadFontFile(f FontFile) (*fontFace, error) {
+	if f.Family == "" || f.Regular == "" {
+		return nil, fmt.Errorf("font %q: family and regular file are required", f.Family)
+	}
+	paths := map[string]string{"": f.Regular, "B": f.Bold, "I": f.Italic, "BI": f.BoldItalic}
+	styles := map[string][]byte{}
+	for style, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("font %s: %v", f.Family, err)
+		}
+		styles[style] = data
+	}
+	return newFontFace(f.Family, styles)
+}
+
+func newFontFace(family string, styles map[string][]byte) (*fontFace, error) {
+	cover, err := sfnt.Parse(styles[""])
+	if err != nil {
+		return nil, fmt.Errorf("font %s: %v", family, err)
+	}
+	for _, style := range []string{"B", "I", "BI"} {
+		if styles[style] == nil {
+			styles[style] = styles[""]
+		}
+	}
+	return &fontFace{family: family, styles: styles, cover: cover}, nil
+}
+
+func mustLoadBundledFonts() *FontSet {
+	face, err := loadEmbeddedFace(BundledFontFamily, map[string]string{
+		"":   "fonts/DejaVuSansCondensed.ttf",
+		"B":  "fonts/DejaVuSansCondensed-Bold.ttf",
+		"I":  "fonts/DejaVuSansCondensed-Oblique.ttf",
+		"BI": "fonts/DejaVuSansCondensed-BoldOblique.ttf",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fs := &FontSet{faces: []*fontFace{face}}
+
+	// The Devanagari face is only part of the chain when its files were
+	// added to fonts/ before the build.
+	if _, err := fontFS.Open("fonts/NotoSansDevanagari-Regular.ttf"); err == nil {
+		face, err := loadEmbeddedFace(DevanagariFontFamily, map[string]string{
+			"":  "fonts/NotoSansDevanagari-Regular.ttf",
+			"B": "fonts/NotoSansDevanagari-Bold.ttf",
+		})
+		if err != nil {
+			panic(err)
+		}
+		fs.faces = append(fs.faces, face)
+	}
+	return fs
+}
+
+// loadEmbeddedFace reads the styles of a bundled family. Missing styles other
+// than the regular one fall back to it.
+func loadEmbeddedFace(family string, files map[string]string) (*fontFace, error) {
+	styles := map[string][]byte{}
+	for style, name := range files {
+		data, err := fontFS.ReadFile(name)
+		if err != nil {
+			if style != "" {
+				continue
+			}
+			return nil, fmt.Errorf("missing bundled font %s: %v", name, err)
+		}
+		styles[style] = data
+	}
+	return newFontFace(family, styles)
+}
+
+// Register adds every family of the chain to pdf. It must be called before
+// any text is drawn with the set.
+func (fs *FontSet) Register(pdf *gofpdf.Fpdf) {
+	for _, face := range fs.faces {
+		for style, data := range face.styles {
+			// gofpdf pads font tables in place while subsetting, so every
+			// document gets its own copy to render concurrently.
+			pdf.AddUTF8FontFromBytes(face.family, style, bytes.Clone(data))
+		}
+	}
+}
+
+// familyFor picks the first family covering every rune of text, or the one
+// covering the most runes when none covers all of them.
+func (fs *FontSet) familyFor(text string) string {
+	var buf sfnt.Buffer
+	best, bestCovered := fs.faces[0].family, -1
+	for _, face := range fs.faces {
+		covered, total := 0, 0
+		for _, r := range text {
+			if r == ' ' || r < 0x20 {
+				continue
+			}
+			total++
+			if g, err := face.cover.GlyphIndex(&buf, r); err == nil && g != 0 {
+				covered++
+			}
+		}
+		if covered == total {
+			return face.family
+		}
+		if covered > bestCovered {
+			best, bestCovered = face.family, covered
+		}
+	}
+	return best
+}
+
+// shapeText applies script shaping to logical text. Characters outside the
+// Basic Multilingual Plane are replaced since gofpdf only measures the BMP.
+func shapeText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return '\uFFFD'
+		}
+		return r
+	}, text)
+	return reorderDevanagari(shapeArabic(text))
+}
+
+// prepare shapes text for display and selects the font family for it. The
+// returned text is in visual order.
+func (fs *FontSet) prepare(text string) (visual, family string, rtl bool) {
+	shaped := shapeText(text)
+	family = fs.familyFor(shaped)
+	visual, rtl = visualOrder(shaped)
+	return visual, family, rtl
+}
+
+// SetFont selects the family of the chain suitable for text with the style
+// and size of f.
+func (fs *FontSet) SetFont(pdf *gofpdf.Fpdf, f Font, text string) {
+	_, family, _ := fs.prepare(text)
+	pdf.SetFont(family, f.Style, f.Size)
+}
+
+// Cell draws a single line of Unicode text, see gofpdf's CellFormat.
+func (fs *FontSet) Cell(pdf *gofpdf.Fpdf, f Font, w, h float64, text, border string, ln int, align string, link int) {
+	visual, family, _ := fs.prepare(text)
+	pdf.SetFont(family, f.Style, f.Size)
+	pdf.CellFormat(w, h, visual, border, ln, align, false, link, "")
+}
+
+// MultiCell draws wrapped Unicode text. Lines are broken in logical order
+// and reordered individually, right-to-left paragraphs are right aligned.
+func (fs *FontSet) MultiCell(pdf *gofpdf.Fpdf, f Font, w, h float64, text, align string) {
+	shaped := shapeText(text)
+	family := fs.familyFor(shaped)
+	pdf.SetFont(family, f.Style, f.Size)
+
+	if w == 0 {
+		_, _, right, _ := pdf.GetMargins()
+		pageW, _ := pdf.GetPageSize()
+		w = pageW - right - pdf.GetX()
+	}
+	x := pdf.GetX()
+	lines := pdf.SplitText(shaped, w)
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	for _, line := range lines {
+		visual, rtl := visualOrder(line)
+		lineAlign := align
+		if rtl && (align == "" || align == "L") {
+			lineAlign = "R"
+		}
+		pdf.SetX(x)
+		pdf.CellFormat(w, h, visual, "", 1, lineAlign, false, 0, "")
+	}
+}
+
+// Fit shortens text with an ellipsis until it fits in width with font f.
+func (fs *FontSet) Fit(pdf *gofpdf.Fpdf, f Font, text string, width float64) string {
+	fs.SetFont(pdf, f, text)
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		short := string(runes) + "…"
+		fs.SetFont(pdf, f, short)
+		if pdf.GetStringWidth(short) <= width {
+			return short
+		}
+	}
+	return ""
+}
+
+// Bookmark adds an outline entry. Viewers shape and order outline text
+// themselves, so it is stored in logical order as UTF-16.
+func (fs *FontSet) Bookmark(pdf *gofpdf.Fpdf, text string, level int, y float64) {
+	size, _ := pdf.GetFontSize()
+	pdf.SetFont(fs.faces[0].family, "", size)
+	pdf.Bookmark(text, level, y)
+}
+
+// Families lists the font families of the chain in fallback order.
+func (fs *FontSet) Families() []string {
+	names := make([]string, 0, len(fs.faces))
+	for _, face := range fs.faces {
+		names = append(names, face.family)
+	}
+	return names
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestVisualOrder(t *testing.T) {
+	cases := []struct {
+		in, want string
+		rtl      bool
+	}{
+		{"Test Student", "Test Student", false},
+		// Hebrew is reversed while the number keeps its digit order.
+		{"שלום 12", "12 םולש", true},
+		{"Name: שלום", "Name: םולש", false},
+		// Brackets in right-to-left text are mirrored.
+		{"שלום (א)", "(א) םולש", true},
+	}
+	for _, c := range cases {
+		got, rtl := visualOrder(c.in)
+		if got != c.want || rtl != c.rtl {
+			t.Errorf("visualOrder(%q) = %q, %v; expected %q, %v", c.in, got, rtl, c.want, c.rtl)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	// Beh between two letters takes its medial form, lam-alef is ligated.
+	if got, want := shapeArabic("ببب"), "ﺑﺒﺐ"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := shapeArabic("لا"), "ﻻ"; got != want {
+		t.Errorf("expected lam-alef ligature %q, got %q", want, got)
+	}
+	if got := shapeArabic("Latin"); got != "Latin" {
+		t.Errorf("expected non-Arabic text unchanged, got %q", got)
+	}
+}
+
+func TestReorderDevanagari(t *testing.T) {
+	// कि is displayed with the i matra first.
+	if got, want := reorderDevanagari("कि"), "िक"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFontSet_FamilyFor(t *testing.T) {
+	bundled, _ := os.ReadFile(filepath.Join("fonts", "DejaVuSansCondensed.ttf"))
+	path := filepath.Join(t.TempDir(), "custom.ttf")
+	if err := os.WriteFile(path, bundled, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := LoadFonts([]FontFile{{Family: "Custom", Regular: path}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := strings.Join(fs.Families(), ","), strings.Join(append([]string{"Custom"}, bundledFonts.Families()...), ","); got != want {
+		t.Errorf("unexpected chain %s", got)
+	}
+	if got := fs.familyFor("Ελένη"); got != "Custom" {
+		t.Errorf("expected first covering family, got %s", got)
+	}
+
+	if _, err := LoadFonts([]FontFile{{Family: "Missing", Regular: filepath.Join(t.TempDir(), "nope.ttf")}}); err == nil {
+		t.Error("expected error for missing font file")
+	}
+}
+
+func TestFontSet_Fit(t *testing.T) {
+	fs, _ := LoadFonts(nil)
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	fs.Register(pdf)
+	f := Font{Size: 10}
+
+	if got := fs.Fit(pdf, f, "Short", 40); got != "Short" {
+		t.Errorf("expected text that fits unchanged, got %q", got)
+	}
+	got := fs.Fit(pdf, f, strings.Repeat("Long name ", 10), 40)
+	if !strings.HasSuffix(got, "…") || pdf.GetStringWidth(got) > 40 {
+		t.Errorf("expected text shortened to 40mm with an ellipsis, got %q", got)
+	}
+	if got := fs.Fit(pdf, f, "Anything", 0); got != "" {
+		t.Errorf("expected nothing to fit in no room, got %q", got)
+	}
+}
+
+func TestTemplate_RenderPDF_Unicode(t *testing.T) {
+	tmpl, _ := (*Registry)(nil).Lookup(KindStudent, DefaultTemplate)
+	st := sampleStudent()
+	st.Name = "Zoë Ελένη Иван محمد علي"
+	st.CurrentAddress = "רחוב הרצל 12, תל אביב 😀"
+
+	pdf := tmpl.NewPDF()
+	pdf.AddPage()
+	if err := tmpl.RenderPDF(pdf, st); err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := pdf.Output(buf); err != nil {
+		t.Fatalf("output error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/FontFile2") {
+		t.Error("expected an embedded TrueType font")
+	}
+}
+
+func TestBundledFonts_Devanagari(t *testing.T) {
+	if _, err := fontFS.Open("fonts/NotoSansDevanagari-Regular.ttf"); err != nil {
+		t.Skip("Noto Sans Devanagari is not bundled")
+	}
+	if got := bundledFonts.familyFor(shapeText("राम")); got != DevanagariFontFamily {
+		t.Errorf("expected %s for Devanagari text, got %s", DevanagariFontFamily, got)
+	}
+	if got := bundledFonts.familyFor("Ελένη"); got != BundledFontFamily {
+		t.Errorf("expected %s for Greek text, got %s", BundledFontFamily, got)
+	}
+}
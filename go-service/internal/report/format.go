@@ -0,0 +1,171 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported report format")
+)
+
+// Format is an output format for rendered reports.
+type Format string
+
+const (
+	FormatPDF  Format = "pdf"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatHTML Format = "html"
+	FormatJSON Format = "json"
+)
+
+// Writer is implemented by every rendered report.
+type Writer interface {
+	Output(w io.Writer) error
+}
+
+// formats lists the supported formats in server preference order, which is
+// used to break ties during content negotiation.
+var formats = []struct {
+	format      Format
+	contentType string
+}{
+	{FormatPDF, "application/pdf"},
+	{FormatCSV, "text/csv"},
+	{FormatXLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	{FormatHTML, "text/html"},
+	{FormatJSON, "application/json"},
+}
+
+// ContentType returns the media type served for the format.
+func (f Format) ContentType() string {
+	for _, e := range formats {
+		if e.format == f {
+			if f == FormatCSV || f == FormatHTML {
+				return e.contentType + "; charset=utf-8"
+			}
+			return e.contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// Extension returns the file extension, without the dot, for the format.
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ParseFormat validates a format name such as "pdf" or "xlsx".
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, e := range formats {
+		if string(e.format) == name {
+			return e.format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+}
+
+// NegotiateFormat picks the output format from an explicit ?format= value or,
+// when that is empty, from the Accept header. PDF is returned when neither
+// selects a supported format. When allowed is not empty only those formats
+// are supported: an explicit value outside them is an error, while Accept
+// entries outside them are skipped.
+func NegotiateFormat(query, accept string, allowed ...Format) (Format, error) {
+	if query != "" {
+		f, err := ParseFormat(query)
+		if err == nil && len(allowed) > 0 && !slices.Contains(allowed, f) {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, f)
+		}
+		return f, err
+	}
+
+	type candidate struct {
+		format Format
+		q      float64
+		order  int
+	}
+	var candidates []candidate
+	// Browser navigation advertises text/html next to application/xhtml+xml;
+	// such requests keep receiving the PDF download they always did.
+	browser := strings.Contains(accept, "application/xhtml+xml")
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		for i, e := range formats {
+			supported := len(allowed) == 0 || slices.Contains(allowed, e.format)
+			if mediaType == e.contentType && q > 0 && supported && !(browser && e.format == FormatHTML) {
+				candidates = append(candidates, candidate{format: e.format, q: q, order: i})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return FormatPDF, nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].order < candidates[j].order
+	})
+	return candidates[0].format, nil
+}
+
+// PDFOptions are the document-wide settings of PDF reports.
+type PDFOptions struct {
+	// Brand adds the letterhead and footer when not nil.
+	Brand *Brand
+	// Encryption password protects the document when not nil.
+	Encryption *Encryption
+	// Stamp adds the verification QR code and serial to the first page when
+	// not nil.
+	Stamp *Stamp
+}
+
+// Render produces a report of data in the given format using tmpl.
+func Render(format Format, tmpl *Template, data any, opts PDFOptions) (Writer, error) {
+	if opts.Encryption != nil && format != FormatPDF && format != "" {
+		return nil, ErrEncryptionNotSupported
+	}
+	if opts.Stamp != nil && format != FormatPDF && format != "" {
+		return nil, fmt.Errorf("%w: issue stamps need a pdf", ErrUnsupportedFormat)
+	}
+	switch format {
+	case FormatPDF, "":
+		pdf := tmpl.NewPDF()
+		opts.Brand.Apply(pdf, tmpl.FontSet(), nil)
+		opts.Encryption.Apply(pdf)
+		pdf.AddPage()
+		opts.Stamp.Draw(pdf, tmpl.FontSet())
+		if err := tmpl.RenderPDF(pdf, data); err != nil {
+			return nil, err
+		}
+		return pdf, nil
+	case FormatCSV:
+		return newCSVWriter(tmpl, data)
+	case FormatXLSX:
+		return newXLSXWriter(tmpl, data)
+	case FormatHTML:
+		return newHTMLWriter(tmpl, data)
+	case FormatJSON:
+		return newJSONWriter(tmpl, data)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
@@ -0,0 +1,100 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		query, accept string
+		want          Format
+	}{
+		{"", "", FormatPDF},
+		{"xlsx", "application/json", FormatXLSX},
+		{"", "application/json", FormatJSON},
+		{"", "text/csv;q=0.5, application/json;q=0.9", FormatJSON},
+		{"", "text/html", FormatHTML},
+		{"", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatPDF},
+		{"", "image/png", FormatPDF},
+	}
+	for _, c := range cases {
+		got, err := NegotiateFormat(c.query, c.accept)
+		if err != nil {
+			t.Fatalf("query=%q accept=%q: unexpected error: %v", c.query, c.accept, err)
+		}
+		if got != c.want {
+			t.Errorf("query=%q accept=%q: expected %s, got %s", c.query, c.accept, c.want, got)
+		}
+	}
+
+	if _, err := NegotiateFormat("docx", ""); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+
+	// Restricted to some formats, Accept falls back to PDF while an explicit
+	// format outside them is rejected.
+	if got, err := NegotiateFormat("", "text/html", FormatPDF, FormatCSV); err != nil || got != FormatPDF {
+		t.Errorf("expected pdf for an unsupported Accept, got %s, %v", got, err)
+	}
+	if got, err := NegotiateFormat("", "application/json, text/csv;q=0.5", FormatPDF, FormatCSV); err != nil || got != FormatCSV {
+		t.Errorf("expected csv, got %s, %v", got, err)
+	}
+	if _, err := NegotiateFormat("html", "", FormatPDF, FormatCSV); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestRender_Formats(t *testing.T) {
+	tmpl, err := builtin.Lookup(KindStudent, DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(f Format) []byte {
+		t.Helper()
+		w, err := Render(f, tmpl, sampleStudent(), PDFOptions{})
+		if err != nil {
+			t.Fatalf("%s: render error: %v", f, err)
+		}
+		buf := new(bytes.Buffer)
+		if err := w.Output(buf); err != nil {
+			t.Fatalf("%s: output error: %v", f, err)
+		}
+		return buf.Bytes()
+	}
+
+	if out := render(FormatPDF); !bytes.HasPrefix(out, []byte("%PDF")) {
+		t.Error("pdf: missing PDF header")
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(render(FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if len(rows) != 22 || rows[2][1] != "Name:" || rows[2][2] != "Test Student" {
+		t.Errorf("csv: unexpected rows %v", rows[:3])
+	}
+
+	xlsx := render(FormatXLSX)
+	if _, err := zip.NewReader(bytes.NewReader(xlsx), int64(len(xlsx))); err != nil {
+		t.Errorf("xlsx: not a valid workbook: %v", err)
+	}
+
+	if out := string(render(FormatHTML)); !strings.Contains(out, "<td>Test Student</td>") {
+		t.Error("html: missing student name")
+	}
+
+	var doc jsonDocument
+	if err := json.Unmarshal(render(FormatJSON), &doc); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if doc.Template != DefaultTemplate || len(doc.Sections) != 1 || doc.Record == nil {
+		t.Errorf("json: unexpected document %+v", doc)
+	}
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Days are handled as UTC midnights, so that adding days and comparing them
+// is not affected by daylight saving time.
+//
+// The backend keeps days in DATE columns. Its REST endpoints send them as
+// midnight in the backend's time zone, which is assumed to be the server's,
+// so BackendDay reads them in time.Local. The dashboard endpoint sends the
+// database's wall clock without a zone instead, which models.DBTime reads as
+// UTC and Day takes as it is.
+
+var (
+	ErrInvalidPeriod = errors.New("invalid period")
+)
+
+// Day returns the calendar day of t, in t's own location.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BackendDay returns the calendar day of a date or timestamp sent by the
+// backend's REST endpoints.
+func BackendDay(t time.Time) time.Time {
+	return Day(t.In(time.Local))
+}
+
+// Period is an inclusive range of days.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// NewPeriod returns the period from from to to, which must not be earlier.
+func NewPeriod(from, to time.Time) (Period, error) {
+	if to.Before(from) {
+		return Period{}, fmt.Errorf("%w: from is after to", ErrInvalidPeriod)
+	}
+	return Period{From: from, To: to}, nil
+}
+
+// ParseDay reads the YYYY-MM-DD bound name of a period, returning def when
+// value is empty.
+func ParseDay(name, value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be YYYY-MM-DD", ErrInvalidPeriod, name)
+	}
+	return day, nil
+}
+
+// Contains reports whether the day of the backend date or timestamp t falls
+// in the period.
+func (p Period) Contains(t time.Time) bool {
+	day := BackendDay(t)
+	return !day.Before(p.From) && !day.After(p.To)
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPeriod(t *testing.T) {
+	def := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	from, err := ParseDay("from", "", def)
+	if err != nil || !from.Equal(def) {
+		t.Errorf("expected the default for an empty bound, got %v, %v", from, err)
+	}
+	to, err := ParseDay("to", "2024-03-31", def)
+	if err != nil || to.Format(time.DateOnly) != "2024-03-31" || to.Location() != time.UTC {
+		t.Errorf("expected 2024-03-31 as a UTC midnight, got %v, %v", to, err)
+	}
+	if _, err := ParseDay("to", "31/03/2024", def); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod, got %v", err)
+	}
+	if _, err := NewPeriod(to, from); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod for a reversed period, got %v", err)
+	}
+
+	p, _ := NewPeriod(from, to)
+	// Backend dates are local midnights, whatever the zone they arrive in.
+	for _, tc := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local).UTC(), true},
+		{time.Date(2024, 3, 31, 23, 59, 0, 0, time.Local), true},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local).UTC(), false},
+		{time.Date(2023, 12, 31, 12, 0, 0, 0, time.Local), false},
+	} {
+		if got := p.Contains(tc.t); got != tc.want {
+			t.Errorf("Contains(%v): expected %t", tc.t, tc.want)
+		}
+	}
+	if d := Day(time.Date(2024, 5, 6, 23, 0, 0, 0, time.UTC)); d.Format(time.DateOnly) != "2024-05-06" {
+		t.Errorf("expected the day in the value's own zone, got %v", d)
+	}
+}
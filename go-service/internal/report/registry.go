@@ -0,0 +1,149 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"goservice/internal/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+const (
+	KindStudent = "student"
+	KindStaff   = "staff"
+
+	// DefaultTemplate is used when a request does not select a template.
+	DefaultTemplate = "default"
+)
+
+// kinds maps template kinds to the model their fields bind to.
+var kinds = map[string]reflect.Type{
+	KindStudent: reflect.TypeOf(models.Student{}),
+	KindStaff:   reflect.TypeOf(models.Staff{}),
+}
+
+//go:embed templates/*.yaml
+var builtinFS embed.FS
+
+var builtin = mustLoadBuiltin()
+
+// Registry holds the validated templates available to the renderers, keyed
+// by kind and name.
+type Registry struct {
+	templates map[string]map[string]*Template
+}
+
+// LoadTemplates returns a registry with the built-in templates plus every
+// *.yaml, *.yml and *.json file in dir. Templates from dir override built-in
+// ones with the same kind and name. An empty dir only loads the built-ins.
+// PDF text is drawn with fonts, or the bundled font when fonts is nil.
+func LoadTemplates(dir string, fonts *FontSet) (*Registry, error) {
+	reg := builtin.clone(fonts)
+	if dir == "" {
+		return reg, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading template dir: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isTemplateFile(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseTemplate(e.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		t.fonts = fonts
+		reg.add(t)
+	}
+	return reg, nil
+}
+
+// Lookup returns the named template of the given kind, falling back to the
+// default template when name is empty. A nil registry serves the built-ins.
+func (r *Registry) Lookup(kind, name string) (*Template, error) {
+	if r == nil {
+		r = builtin
+	}
+	if name == "" {
+		name = DefaultTemplate
+	}
+	t, ok := r.templates[kind][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	return t, nil
+}
+
+// Names lists the template names registered for kind.
+func (r *Registry) Names(kind string) []string {
+	if r == nil {
+		r = builtin
+	}
+	names := make([]string, 0, len(r.templates[kind]))
+	for name := range r.templates[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) add(t *Template) {
+	if r.templates[t.Kind] == nil {
+		r.templates[t.Kind] = map[string]*Template{}
+	}
+	r.templates[t.Kind][t.Name] = t
+}
+
+// clone copies the registry with every template drawing with fonts.
+func (r *Registry) clone(fonts *FontSet) *Registry {
+	c := &Registry{templates: map[string]map[string]*Template{}}
+	for _, byName := range r.templates {
+		for _, t := range byName {
+			cp := *t
+			cp.fonts = fonts
+			c.add(&cp)
+		}
+	}
+	return c
+}
+
+func isTemplateFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func mustLoadBuiltin() *Registry {
+	reg := &Registry{templates: map[string]map[string]*Template{}}
+	err := fs.WalkDir(builtinFS, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := builtinFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		t, err := ParseTemplate(path, data)
+		if err != nil {
+			return err
+		}
+		reg.add(t)
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in report template: %v", err))
+	}
+	return reg
+}
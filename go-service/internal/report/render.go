@@ -0,0 +1,152 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const defaultDateLayout = "2006-01-02"
+
+// ResolvedField is a template field with its value formatted for display.
+type ResolvedField struct {
+	Label string `json:"label"`
+	Bind  string `json:"bind"`
+	Value string `json:"value"`
+}
+
+// ResolvedSection is a template section bound to a concrete record.
+type ResolvedSection struct {
+	Section
+	Values []ResolvedField
+}
+
+// Resolve binds every field of the template to data, which must be the model
+// of the template's kind or a pointer to it.
+func (t *Template) Resolve(data any) ([]ResolvedSection, error) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if model := kinds[t.Kind]; !v.IsValid() || v.Type() != model {
+		return nil, fmt.Errorf("template %s expects %s data, got %T", t.Name, t.Kind, data)
+	}
+
+	bindings := fieldIndex(v.Type())
+	sections := make([]ResolvedSection, 0, len(t.Sections))
+	for _, s := range t.Sections {
+		rs := ResolvedSection{Section: s, Values: make([]ResolvedField, 0, len(s.Fields))}
+		for _, f := range s.Fields {
+			rs.Values = append(rs.Values, ResolvedField{
+				Label: f.Label,
+				Bind:  f.Bind,
+				Value: formatValue(v.Field(bindings[f.Bind]), f.Format),
+			})
+		}
+		sections = append(sections, rs)
+	}
+	return sections, nil
+}
+
+// NewPDF creates an empty document with the template's page settings and
+// the Unicode fonts of its font set registered.
+func (t *Template) NewPDF() *gofpdf.Fpdf {
+	pdf := gofpdf.New(t.Page.Orientation, "mm", t.Page.Size, "")
+	t.FontSet().Register(pdf)
+	return pdf
+}
+
+// FontSet returns the Unicode font chain used to draw the template's text.
+func (t *Template) FontSet() *FontSet {
+	if t.fonts == nil {
+		return bundledFonts
+	}
+	return t.fonts
+}
+
+// RenderPDF writes the template layout for data starting at the current
+// position of pdf. Callers are responsible for adding the first page.
+func (t *Template) RenderPDF(pdf *gofpdf.Fpdf, data any) error {
+	sections, err := t.Resolve(data)
+	if err != nil {
+		return err
+	}
+
+	fonts := t.FontSet()
+	if t.Title != "" {
+		fonts.Cell(pdf, t.Fonts.Title, 40, 10, t.Title, "", 0, "", 0)
+		pdf.Ln(15)
+	}
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+	contentW := pageW - left - right
+
+	for i, s := range sections {
+		if s.PageBreak && i > 0 {
+			pdf.AddPage()
+		}
+		if s.Title != "" {
+			if i > 0 {
+				pdf.Ln(4)
+			}
+			fonts.Cell(pdf, t.Fonts.Heading, 0, s.LineHeight+2, s.Title, "B", 1, "L", 0)
+			pdf.Ln(2)
+		}
+
+		colW := contentW / float64(s.Columns)
+		labelW := s.LabelWidth
+		if labelW == 0 {
+			labelW = colW * 0.4
+		}
+		valueW := s.ValueWidth
+		if valueW == 0 {
+			valueW = colW - labelW
+		}
+
+		if s.Columns == 1 {
+			for _, f := range s.Values {
+				fonts.Cell(pdf, t.Fonts.Label, labelW, s.LineHeight, f.Label, "0", 0, "", 0)
+				fonts.MultiCell(pdf, t.Fonts.Value, valueW, s.LineHeight, f.Value, "L")
+			}
+			continue
+		}
+
+		for j, f := range s.Values {
+			fonts.Cell(pdf, t.Fonts.Label, labelW, s.LineHeight, f.Label, "0", 0, "", 0)
+			fonts.Cell(pdf, t.Fonts.Value, valueW, s.LineHeight, f.Value, "0", 0, "", 0)
+			if (j+1)%s.Columns == 0 || j == len(s.Values)-1 {
+				pdf.Ln(s.LineHeight)
+			}
+		}
+	}
+	return pdf.Error()
+}
+
+// formatValue renders a model property as text. Dates use format as a time
+// layout, booleans use a "Yes|No" pair and anything else a fmt verb.
+func formatValue(v reflect.Value, format string) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		if format == "" {
+			format = defaultDateLayout
+		}
+		return x.Format(format)
+	case bool:
+		yes, no := "Yes", "No"
+		if parts := strings.SplitN(format, "|", 2); len(parts) == 2 {
+			yes, no = parts[0], parts[1]
+		}
+		if x {
+			return yes
+		}
+		return no
+	}
+	if format != "" {
+		return fmt.Sprintf(format, v.Interface())
+	}
+	return fmt.Sprint(v.Interface())
+}
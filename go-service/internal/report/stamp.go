@@ -0,0 +1,80 @@
+package report
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	stampImageName = "issue-stamp"
+	stampSize      = 16.0
+	// stampModulePixels is the rendered size of one QR module. PNG keeps the
+	// modules sharp at any zoom.
+	stampModulePixels = 8
+)
+
+// Stamp identifies an issued document: its serial and the URL where it can
+// be verified.
+type Stamp struct {
+	Serial string
+	URL    string
+}
+
+// Draw puts the verification QR code and the serial in the top right corner
+// of the current page, alongside the letterhead. Content of unbranded pages
+// is moved below the code.
+func (s *Stamp) Draw(pdf *gofpdf.Fpdf, fonts *FontSet) {
+	if s == nil {
+		return
+	}
+	code, err := qr.Encode(s.URL, qr.M, qr.Auto)
+	if err != nil {
+		pdf.SetError(err)
+		return
+	}
+	size := code.Bounds().Dx() * stampModulePixels
+	data, err := BarcodePNG(code, size, size)
+	if err != nil {
+		pdf.SetError(err)
+		return
+	}
+
+	_, top, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+	x, y := pageW-right-stampSize, top
+	opts := gofpdf.ImageOptions{ImageType: "png"}
+	pdf.RegisterImageOptionsReader(stampImageName, opts, bytes.NewReader(data))
+	pdf.ImageOptions(stampImageName, x, y, stampSize, stampSize, false, opts, 0, s.URL)
+
+	// Cell moves the cursor, so the content position is restored afterwards.
+	cx, cy := pdf.GetXY()
+	pdf.SetXY(x-4, y+stampSize+0.5)
+	pdf.SetTextColor(90, 90, 90)
+	fonts.Cell(pdf, Font{Size: 6}, stampSize+4, 3, s.Serial, "", 0, "C", 0)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(cx, max(cy, y+stampSize+5))
+}
+
+// BarcodePNG scales code to width by height pixels and encodes it as a PNG
+// gofpdf can embed. The width of a 1D code must be a multiple of its
+// module count.
+func BarcodePNG(code barcode.Barcode, width, height int) ([]byte, error) {
+	code, err := barcode.Scale(code, width, height)
+	if err != nil {
+		return nil, err
+	}
+	// gofpdf only reads 8-bit PNGs while scaled codes are 16-bit gray.
+	img := image.NewGray(code.Bounds())
+	draw.Draw(img, img.Bounds(), code, image.Point{}, draw.Src)
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
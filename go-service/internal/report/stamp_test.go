@@ -0,0 +1,31 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRender_Stamped(t *testing.T) {
+	tmpl, _ := (*Registry)(nil).Lookup(KindStudent, DefaultTemplate)
+	stamp := &Stamp{Serial: "ABCDE-12345", URL: "https://school.example/api/v1/reports/verify/ABCDE-12345"}
+
+	w, err := Render(FormatPDF, tmpl, sampleStudent(), PDFOptions{Stamp: stamp})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := w.Output(buf); err != nil {
+		t.Fatalf("output error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/URI ("+stamp.URL+")")) {
+		t.Error("expected the QR code to link to the verification url")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Subtype /Image")) {
+		t.Error("expected the QR code image")
+	}
+
+	if _, err := Render(FormatJSON, tmpl, sampleStudent(), PDFOptions{Stamp: stamp}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
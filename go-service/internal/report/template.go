@@ -0,0 +1,241 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownTemplate = errors.New("unknown report template")
+)
+
+// Template is a declarative report layout. Templates are loaded from YAML or
+// JSON files and interpreted by the renderers in this package.
+type Template struct {
+	// Name identifies the template in ?template= and must be unique per kind.
+	Name string `json:"name" yaml:"name"`
+	// Kind is the data model the template binds to, e.g. "student".
+	Kind string `json:"kind" yaml:"kind"`
+	// Version is bumped whenever the layout changes so cached output can be
+	// invalidated.
+	Version  string    `json:"version" yaml:"version"`
+	Title    string    `json:"title" yaml:"title"`
+	Page     Page      `json:"page" yaml:"page"`
+	Fonts    Fonts     `json:"fonts" yaml:"fonts"`
+	Sections []Section `json:"sections" yaml:"sections"`
+
+	fonts *FontSet
+}
+
+// Page describes the paper format of the rendered document.
+type Page struct {
+	Orientation string `json:"orientation" yaml:"orientation"`
+	Size        string `json:"size" yaml:"size"`
+}
+
+// Fonts holds the font used for each text role of the layout. PDF output
+// draws text with the Unicode font chain, so only the style and size apply.
+type Fonts struct {
+	Title   Font `json:"title" yaml:"title"`
+	Heading Font `json:"heading" yaml:"heading"`
+	Label   Font `json:"label" yaml:"label"`
+	Value   Font `json:"value" yaml:"value"`
+}
+
+type Font struct {
+	Family string  `json:"family" yaml:"family"`
+	Style  string  `json:"style" yaml:"style"`
+	Size   float64 `json:"size" yaml:"size"`
+}
+
+// Section is a group of fields laid out in one or more label/value columns.
+type Section struct {
+	Title string `json:"title" yaml:"title"`
+	// Columns is the number of label/value pairs per row, defaults to 1.
+	Columns int `json:"columns" yaml:"columns"`
+	// LabelWidth and ValueWidth are in mm; when zero the available width is
+	// split evenly between the columns.
+	LabelWidth float64 `json:"labelWidth" yaml:"labelWidth"`
+	ValueWidth float64 `json:"valueWidth" yaml:"valueWidth"`
+	LineHeight float64 `json:"lineHeight" yaml:"lineHeight"`
+	// PageBreak starts the section on a new page.
+	PageBreak bool    `json:"pageBreak" yaml:"pageBreak"`
+	Fields    []Field `json:"fields" yaml:"fields"`
+}
+
+// Field binds a label to a property of the data model by its JSON name.
+type Field struct {
+	Label string `json:"label" yaml:"label"`
+	Bind  string `json:"bind" yaml:"bind"`
+	// Format is a time layout for dates, "Yes|No" style labels for booleans
+	// or a fmt verb for other values.
+	Format string `json:"format" yaml:"format"`
+}
+
+var (
+	pageSizes     = map[string]bool{"A3": true, "A4": true, "A5": true, "Letter": true, "Legal": true}
+	fontFamilies  = map[string]bool{"Arial": true, "Helvetica": true, "Times": true, "Courier": true}
+	fontStyles    = map[string]bool{"": true, "B": true, "I": true, "BI": true, "U": true, "BU": true, "IU": true, "BIU": true}
+	orientations  = map[string]bool{"P": true, "L": true}
+	defaultFont   = Font{Family: "Arial", Size: 12}
+	defaultLineHt = 8.0
+)
+
+// ParseTemplate decodes a template from YAML or JSON, chosen by the file
+// extension of name, and validates it. Unknown keys are rejected.
+func ParseTemplate(name string, data []byte) (*Template, error) {
+	var t Template
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("template %s: unsupported file type", name)
+	}
+
+	t.applyDefaults()
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("template %s: %v", name, err)
+	}
+	return &t, nil
+}
+
+func (t *Template) applyDefaults() {
+	if t.Page.Orientation == "" {
+		t.Page.Orientation = "P"
+	}
+	if t.Page.Size == "" {
+		t.Page.Size = "A4"
+	}
+	for _, f := range []*Font{&t.Fonts.Title, &t.Fonts.Heading, &t.Fonts.Label, &t.Fonts.Value} {
+		if f.Family == "" {
+			f.Family = defaultFont.Family
+		}
+		if f.Size == 0 {
+			f.Size = defaultFont.Size
+		}
+	}
+	for i := range t.Sections {
+		if t.Sections[i].Columns == 0 {
+			t.Sections[i].Columns = 1
+		}
+		if t.Sections[i].LineHeight == 0 {
+			t.Sections[i].LineHeight = defaultLineHt
+		}
+	}
+}
+
+// Validate checks the layout settings and that every field binds to an
+// existing property of the template's model kind.
+func (t *Template) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	model, ok := kinds[t.Kind]
+	if !ok {
+		return fmt.Errorf("unknown kind %q", t.Kind)
+	}
+	if !orientations[t.Page.Orientation] {
+		return fmt.Errorf("invalid page orientation %q", t.Page.Orientation)
+	}
+	if !pageSizes[t.Page.Size] {
+		return fmt.Errorf("invalid page size %q", t.Page.Size)
+	}
+	for role, f := range map[string]Font{"title": t.Fonts.Title, "heading": t.Fonts.Heading, "label": t.Fonts.Label, "value": t.Fonts.Value} {
+		if !fontFamilies[f.Family] {
+			return fmt.Errorf("%s font: unsupported family %q", role, f.Family)
+		}
+		if !fontStyles[f.Style] {
+			return fmt.Errorf("%s font: invalid style %q", role, f.Style)
+		}
+		if f.Size < 0 {
+			return fmt.Errorf("%s font: invalid size %v", role, f.Size)
+		}
+	}
+	if len(t.Sections) == 0 {
+		return errors.New("at least one section is required")
+	}
+
+	bindings := fieldIndex(model)
+	for i, s := range t.Sections {
+		if s.Columns < 1 {
+			return fmt.Errorf("section %d: columns must be positive", i+1)
+		}
+		if s.LabelWidth < 0 || s.ValueWidth < 0 || s.LineHeight < 0 {
+			return fmt.Errorf("section %d: widths must not be negative", i+1)
+		}
+		for _, f := range s.Fields {
+			idx, ok := bindings[f.Bind]
+			if !ok {
+				return fmt.Errorf("section %d: field %q binds to unknown property %q", i+1, f.Label, f.Bind)
+			}
+			if err := checkFormat(model.Field(idx).Type, f.Format); err != nil {
+				return fmt.Errorf("section %d: field %q: %v", i+1, f.Label, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFormat rejects a field format that formatValue cannot apply to a
+// property of type t: a boolean format without its "|" or a fmt verb that
+// does not suit the type. Time layouts accept any text.
+func checkFormat(t reflect.Type, format string) error {
+	if format == "" {
+		return nil
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return nil
+	case reflect.TypeOf(false):
+		if !strings.Contains(format, "|") {
+			return fmt.Errorf("boolean format %q must be a \"Yes|No\" pair", format)
+		}
+		return nil
+	}
+	sample := reflect.New(t).Elem()
+	if t.Kind() == reflect.Pointer {
+		sample = reflect.New(t.Elem())
+	}
+	if out := fmt.Sprintf(format, sample.Interface()); strings.Contains(out, "%!") {
+		return fmt.Errorf("format %q does not suit a %s value", format, t)
+	}
+	return nil
+}
+
+// fieldIndex maps the JSON names of a struct type's fields to their index.
+func fieldIndex(t reflect.Type) map[string]int {
+	idx := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		if name == "-" {
+			continue
+		}
+		idx[name] = i
+	}
+	return idx
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"goservice/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleStudent() *models.Student {
+	return &models.Student{
+		ID:            7,
+		Name:          "Test Student",
+		SystemAccess:  true,
+		DOB:           time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Class:         "10",
+		Section:       "A",
+		Roll:          3,
+		AdmissionDate: time.Date(2018, 6, 10, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestParseTemplate_YAMLAndJSON(t *testing.T) {
+	yamlSrc := `
+name: y
+kind: student
+sections:
+  - fields:
+      - { label: "Name", bind: name }
+`
+	jsonSrc := `{"name":"j","kind":"student","sections":[{"columns":2,"fields":[{"label":"Roll","bind":"roll"}]}]}`
+
+	for file, src := range map[string]string{"y.yaml": yamlSrc, "j.json": jsonSrc} {
+		tmpl, err := ParseTemplate(file, []byte(src))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", file, err)
+		}
+		if tmpl.Page.Size != "A4" || tmpl.Fonts.Value.Family != "Arial" || tmpl.Sections[0].LineHeight == 0 {
+			t.Errorf("%s: defaults not applied: %+v", file, tmpl)
+		}
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown binding": `{"name":"x","kind":"student","sections":[{"fields":[{"label":"A","bind":"nope"}]}]}`,
+		"unknown key":     `{"name":"x","kind":"student","colour":"red","sections":[{"fields":[]}]}`,
+		"unknown kind":    `{"name":"x","kind":"alien","sections":[{"fields":[]}]}`,
+		"bad font":        `{"name":"x","kind":"student","fonts":{"title":{"family":"Comic"}},"sections":[{"fields":[]}]}`,
+		"no sections":     `{"name":"x","kind":"student"}`,
+		"missing name":    `{"kind":"student","sections":[{"fields":[]}]}`,
+		"bad verb":        `{"name":"x","kind":"student","sections":[{"fields":[{"label":"Roll","bind":"roll","format":"%s"}]}]}`,
+		"extra verb":      `{"name":"x","kind":"student","sections":[{"fields":[{"label":"Roll","bind":"roll","format":"%d/%d"}]}]}`,
+		"bool format":     `{"name":"x","kind":"student","sections":[{"fields":[{"label":"A","bind":"systemAccess","format":"On"}]}]}`,
+	}
+	for name, src := range cases {
+		if _, err := ParseTemplate("t.json", []byte(src)); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	reg, err := LoadTemplates("../../templates", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{DefaultTemplate, "compact", "guardian"} {
+		if _, err := reg.Lookup(KindStudent, name); err != nil {
+			t.Errorf("expected template %q: %v", name, err)
+		}
+	}
+	if _, err := reg.Lookup(KindStudent, "missing"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("expected ErrUnknownTemplate, got %v", err)
+	}
+}
+
+func TestTemplate_Resolve(t *testing.T) {
+	tmpl, err := ParseTemplate("t.yaml", []byte(`
+name: t
+kind: student
+sections:
+  - fields:
+      - { label: "DOB", bind: dob, format: "02/01/2006" }
+      - { label: "Access", bind: systemAccess, format: "On|Off" }
+      - { label: "Roll", bind: roll, format: "%03d" }
+      - { label: "Admitted", bind: admissionDate }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sections, err := tmpl.Resolve(sampleStudent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"02/01/2000", "On", "003", "2018-06-10"}
+	for i, f := range sections[0].Values {
+		if f.Value != want[i] {
+			t.Errorf("field %s: expected %q, got %q", f.Label, want[i], f.Value)
+		}
+	}
+
+	if _, err := tmpl.Resolve(struct{}{}); err == nil {
+		t.Error("expected error for mismatched data")
+	}
+}
+
+func TestTemplate_RenderPDF(t *testing.T) {
+	reg, _ := LoadTemplates("", nil)
+	for _, name := range reg.Names(KindStudent) {
+		tmpl, _ := reg.Lookup(KindStudent, name)
+		pdf := tmpl.NewPDF()
+		pdf.AddPage()
+		if err := tmpl.RenderPDF(pdf, sampleStudent()); err != nil {
+			t.Fatalf("%s: render error: %v", name, err)
+		}
+		buf := new(bytes.Buffer)
+		if err := pdf.Output(buf); err != nil {
+			t.Fatalf("%s: output error: %v", name, err)
+		}
+		if !strings.HasPrefix(buf.String(), "%PDF") {
+			t.Errorf("%s: expected PDF output", name)
+		}
+	}
+}
+
+func TestTemplate_StaffDefault(t *testing.T) {
+	reg, err := LoadTemplates("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staff, err := reg.Lookup(KindStaff, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections, err := staff.Resolve(&models.Staff{Name: "Dana", Department: "Science", SystemAccess: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, s := range sections {
+		for _, f := range s.Values {
+			values[f.Label] = f.Value
+		}
+	}
+	if values["Department"] != "Science" || values["System Access"] != "Active" || values["Joined"] != "" {
+		t.Errorf("unexpected staff fields %v", values)
+	}
+	if _, err := staff.Resolve(sampleStudent()); err == nil {
+		t.Error("expected the staff template to reject student data")
+	}
+}
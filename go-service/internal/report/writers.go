@@ -0,0 +1,213 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// csvWriter emits one "section,label,value" row per template field.
+type csvWriter struct {
+	sections []ResolvedSection
+}
+
+func newCSVWriter(tmpl *Template, data any) (Writer, error) {
+	sections, err := tmpl.Resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	return &csvWriter{sections: sections}, nil
+}
+
+func (c *csvWriter) Output(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"section", "field", "value"}); err != nil {
+		return err
+	}
+	for _, s := range c.sections {
+		for _, f := range s.Values {
+			if err := cw.Write([]string{s.Title, f.Label, f.Value}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// xlsxWriter builds a native workbook with a single report sheet.
+type xlsxWriter struct {
+	title    string
+	sections []ResolvedSection
+}
+
+func newXLSXWriter(tmpl *Template, data any) (Writer, error) {
+	sections, err := tmpl.Resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{title: tmpl.Title, sections: sections}, nil
+}
+
+func (x *xlsxWriter) Output(w io.Writer) error {
+	const sheet = "Report"
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 16}})
+	if err != nil {
+		return err
+	}
+	headingStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 12}})
+	if err != nil {
+		return err
+	}
+	labelStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	row := 1
+	setRow := func(style int, values ...any) error {
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, cell, cell, style); err != nil {
+			return err
+		}
+		row++
+		return nil
+	}
+
+	if x.title != "" {
+		if err := setRow(titleStyle, x.title); err != nil {
+			return err
+		}
+		row++
+	}
+	for _, s := range x.sections {
+		if s.Title != "" {
+			if err := setRow(headingStyle, s.Title); err != nil {
+				return err
+			}
+		}
+		for _, fld := range s.Values {
+			if err := setRow(labelStyle, fld.Label, fld.Value); err != nil {
+				return err
+			}
+		}
+		row++
+	}
+
+	if err := f.SetColWidth(sheet, "A", "A", 28); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "B", "B", 60); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 2rem; color: #222; }
+h1 { font-size: 1.6rem; }
+h2 { font-size: 1.15rem; border-bottom: 1px solid #999; padding-bottom: .2rem; margin-top: 1.5rem; }
+table { border-collapse: collapse; width: 100%; }
+th { text-align: left; width: 35%; padding: .3rem .5rem .3rem 0; vertical-align: top; }
+td { padding: .3rem 0; }
+</style>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>{{end}}
+{{range .Sections}}
+{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+<table>
+{{range .Values}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// htmlWriter renders a standalone HTML document with inline styles.
+type htmlWriter struct {
+	Title    string
+	Sections []ResolvedSection
+}
+
+func newHTMLWriter(tmpl *Template, data any) (Writer, error) {
+	sections, err := tmpl.Resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	return &htmlWriter{Title: tmpl.Title, Sections: sections}, nil
+}
+
+func (h *htmlWriter) Output(w io.Writer) error {
+	return htmlReport.Execute(w, h)
+}
+
+// jsonDocument is the canonical JSON representation of a report: the
+// template identity, the resolved sections and the source record.
+type jsonDocument struct {
+	Template string        `json:"template"`
+	Version  string        `json:"version"`
+	Kind     string        `json:"kind"`
+	Title    string        `json:"title"`
+	Sections []jsonSection `json:"sections"`
+	Record   any           `json:"record"`
+}
+
+type jsonSection struct {
+	Title  string          `json:"title,omitempty"`
+	Fields []ResolvedField `json:"fields"`
+}
+
+type jsonWriter struct {
+	doc jsonDocument
+}
+
+func newJSONWriter(tmpl *Template, data any) (Writer, error) {
+	sections, err := tmpl.Resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := jsonDocument{
+		Template: tmpl.Name,
+		Version:  tmpl.Version,
+		Kind:     tmpl.Kind,
+		Title:    tmpl.Title,
+		Sections: make([]jsonSection, 0, len(sections)),
+		Record:   data,
+	}
+	for _, s := range sections {
+		doc.Sections = append(doc.Sections, jsonSection{Title: s.Title, Fields: s.Values})
+	}
+	return &jsonWriter{doc: doc}, nil
+}
+
+func (j *jsonWriter) Output(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(j.doc); err != nil {
+		return fmt.Errorf("encoding report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package roster
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves class rosters, mounted under /api/v1/reports/roster.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.Roster)
+	return r
+}
+
+// errorStatus maps roster errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrClassRequired), errors.Is(err, report.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNoStudents):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Roster reads the class, section, name and roll filters of the backend's
+// student list from the query string.
+func (h *Handler) Roster(w http.ResponseWriter, r *http.Request) {
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.StudentFilter{
+		Name:      strings.TrimSpace(q.Get("name")),
+		ClassName: strings.TrimSpace(q.Get("class")),
+		Section:   strings.TrimSpace(q.Get("section")),
+	}
+	if v := q.Get("roll"); v != "" {
+		if filter.Roll, err = strconv.Atoi(v); err != nil || filter.Roll < 1 {
+			response.Error(w, http.StatusBadRequest, fmt.Errorf("invalid roll %q", v))
+			return
+		}
+	}
+	w.Header().Add("Vary", "Accept")
+	format, err := report.NegotiateFormat(q.Get("format"), r.Header.Get("Accept"), report.FormatPDF, report.FormatXLSX)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateRoster(r.Context(), Options{Filter: filter, Format: format}, cookies)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+
+	name := "roster_class_" + filter.ClassName
+	if filter.Section != "" {
+		name += "_section_" + filter.Section
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format.Extension()}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,163 @@
+package roster
+
+import (
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"io"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	titleFont = report.Font{Style: "B", Size: 16}
+	textFont  = report.Font{Size: 10}
+	tableHead = report.Font{Style: "B", Size: 10}
+	tableFont = report.Font{Size: 10}
+	rowHeight = 7.0
+)
+
+// column is a column of the roster table, in both PDF and XLSX.
+type column struct {
+	title string
+	// width is in millimetres in the PDF and characters in the workbook.
+	width float64
+	align string
+	value func(int, *models.Student) string
+}
+
+var columns = []column{
+	{"No.", 12, "R", func(i int, _ *models.Student) string { return strconv.Itoa(i + 1) }},
+	{"Roll", 16, "R", func(_ int, st *models.Student) string { return strconv.Itoa(st.Roll) }},
+	{"Name", 70, "L", func(_ int, st *models.Student) string { return st.Name }},
+	{"Gender", 22, "L", func(_ int, st *models.Student) string { return st.Gender }},
+	{"Date of Birth", 30, "L", func(_ int, st *models.Student) string { return dob(st) }},
+	{"Guardian", 77, "L", func(_ int, st *models.Student) string { return guardian(st) }},
+	{"Phone", 50, "L", func(_ int, st *models.Student) string { return phone(st) }},
+}
+
+// renderPDF lays the roster out as a landscape table. gofpdf's automatic
+// page breaks are pre-empted so that every page starts with the header row.
+func renderPDF(r *Roster, fonts *report.FontSet, brand *report.Brand) *gofpdf.Fpdf {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	fonts.Register(pdf)
+	subject := r.Subject()
+	pdf.SetTitle("Class Roster - "+subject, true)
+	brand.Apply(pdf, fonts, func() string { return subject })
+	pdf.AddPage()
+
+	fonts.Cell(pdf, titleFont, 0, 10, "Class Roster", "", 1, "", 0)
+	fonts.Cell(pdf, textFont, 0, 6, subject+" - "+strconv.Itoa(len(r.Students))+" students", "", 1, "", 0)
+	pdf.Ln(4)
+
+	header := func() {
+		pdf.SetFillColor(230, 230, 230)
+		for _, c := range columns {
+			fonts.SetFont(pdf, tableHead, c.title)
+			pdf.CellFormat(c.width, rowHeight+1, c.title, "1", 0, c.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	header()
+	_, pageH := pdf.GetPageSize()
+	_, breakMargin := pdf.GetAutoPageBreak()
+	for i, st := range r.Students {
+		if pdf.GetY()+rowHeight > pageH-breakMargin {
+			pdf.AddPage()
+			header()
+		}
+		for _, c := range columns {
+			fonts.Cell(pdf, tableFont, c.width, rowHeight, fonts.Fit(pdf, tableFont, c.value(i, st), c.width-2), "1", 0, c.align, 0)
+		}
+		pdf.Ln(-1)
+	}
+	return pdf
+}
+
+// xlsxWriter builds a workbook with the roster table on one sheet. The
+// header row is frozen and repeated on every printed page.
+type xlsxWriter struct {
+	roster *Roster
+}
+
+func (x *xlsxWriter) Output(w io.Writer) error {
+	const (
+		sheet     = "Roster"
+		headerRow = 3
+	)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 16}})
+	if err != nil {
+		return err
+	}
+	headStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"E6E6E6"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetCellValue(sheet, "A1", "Class Roster - "+x.roster.Subject()); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A1", "A1", titleStyle); err != nil {
+		return err
+	}
+
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c.title
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetColWidth(sheet, name, name, c.width/2.5); err != nil {
+			return err
+		}
+	}
+	if err := f.SetSheetRow(sheet, "A"+strconv.Itoa(headerRow), &header); err != nil {
+		return err
+	}
+	last, err := excelize.CoordinatesToCellName(len(columns), headerRow)
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A"+strconv.Itoa(headerRow), last, headStyle); err != nil {
+		return err
+	}
+
+	for i, st := range x.roster.Students {
+		// Numbers stay numeric so the sheet can be sorted and summed.
+		row := []any{i + 1, st.Roll}
+		for _, c := range columns[2:] {
+			row = append(row, c.value(i, st))
+		}
+		if err := f.SetSheetRow(sheet, "A"+strconv.Itoa(headerRow+1+i), &row); err != nil {
+			return err
+		}
+	}
+
+	if err := f.SetPanes(sheet, &excelize.Panes{
+		Freeze: true, YSplit: headerRow, TopLeftCell: "A" + strconv.Itoa(headerRow+1), ActivePane: "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	orientation := "landscape"
+	if err := f.SetPageLayout(sheet, &excelize.PageLayoutOptions{Orientation: &orientation}); err != nil {
+		return err
+	}
+	if err := f.SetDefinedName(&excelize.DefinedName{
+		Name: "_xlnm.Print_Titles", RefersTo: sheet + "!$" + strconv.Itoa(headerRow) + ":$" + strconv.Itoa(headerRow), Scope: sheet,
+	}); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
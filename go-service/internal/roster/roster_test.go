@@ -0,0 +1,185 @@
+package roster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func sampleStudents() map[int]*models.Student {
+	dob := time.Date(2012, 3, 4, 0, 0, 0, 0, time.UTC)
+	return map[int]*models.Student{
+		1: {ID: 1, Name: "Cara", Class: "Grade 5", Section: "A", Roll: 3, Gender: "Female", DOB: dob, GuardianName: "Tom", RelationOfGuardian: "Uncle", GuardianPhone: "555-0101"},
+		2: {ID: 2, Name: "Abel", Class: "Grade 5", Section: "A", Roll: 1, Gender: "Male", DOB: dob, FatherName: "Sam", Phone: "555-0102"},
+		3: {ID: 3, Name: "Bea", Class: "Grade 5", Section: "B", Roll: 2, Gender: "Female", MotherName: "Ivy", MotherPhone: "555-0103"},
+		4: {ID: 4, Name: "Dov", Class: "Grade 6", Section: "A", Roll: 1, Gender: "Male"},
+	}
+}
+
+// fakeBackend lists every student whatever the filter, like a backend that
+// ignores filters.
+type fakeBackend struct {
+	client.IBackend
+	students map[int]*models.Student
+	filters  []models.StudentFilter
+	failID   int
+}
+
+func (f *fakeBackend) ListStudents(ctx context.Context, filter models.StudentFilter, cookies []*http.Cookie) ([]models.Student, error) {
+	f.filters = append(f.filters, filter)
+	if len(f.students) == 0 {
+		return nil, &client.StatusError{Resource: "students", Status: http.StatusNotFound}
+	}
+	var list []models.Student
+	for id, st := range f.students {
+		list = append(list, models.Student{ID: id, Name: st.Name})
+	}
+	return list, nil
+}
+
+func (f *fakeBackend) GetStudentByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Student, error) {
+	if id == f.failID {
+		return nil, errors.New("backend down")
+	}
+	return f.students[id], nil
+}
+
+func TestStudents(t *testing.T) {
+	backend := &fakeBackend{students: sampleStudents()}
+	s := NewService(backend, nil, nil, nil).(*service)
+
+	for _, tc := range []struct {
+		filter models.StudentFilter
+		want   string
+	}{
+		{models.StudentFilter{ClassName: "Grade 5"}, "[Abel Bea Cara]"},
+		{models.StudentFilter{ClassName: "grade 5", Section: "a"}, "[Abel Cara]"},
+		{models.StudentFilter{ClassName: "Grade 5", Roll: 3}, "[Cara]"},
+		{models.StudentFilter{ClassName: "Grade 5", Name: "bea"}, "[Bea]"},
+	} {
+		students, err := s.students(context.Background(), tc.filter, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, st := range students {
+			names = append(names, st.Name)
+		}
+		if got := fmt.Sprint(names); got != tc.want {
+			t.Errorf("%+v: expected %s sorted by roll, got %s", tc.filter, tc.want, got)
+		}
+	}
+	if got := backend.filters[1]; got.ClassName != "grade 5" || got.Section != "a" {
+		t.Errorf("expected the filter to be passed to the backend, got %+v", got)
+	}
+
+	if _, err := s.students(context.Background(), models.StudentFilter{ClassName: "Grade 9"}, nil); !errors.Is(err, ErrNoStudents) {
+		t.Errorf("expected ErrNoStudents for an empty class, got %v", err)
+	}
+	if _, err := NewService(&fakeBackend{}, nil, nil, nil).(*service).students(context.Background(), models.StudentFilter{ClassName: "Grade 5"}, nil); !errors.Is(err, ErrNoStudents) {
+		t.Errorf("expected ErrNoStudents for a backend 404, got %v", err)
+	}
+	backend.failID = 2
+	if _, err := s.students(context.Background(), models.StudentFilter{ClassName: "Grade 5"}, nil); err == nil || !strings.Contains(err.Error(), "backend down") {
+		t.Errorf("expected a failed lookup to fail the roster, got %v", err)
+	}
+}
+
+func TestGuardianAndPhone(t *testing.T) {
+	students := sampleStudents()
+	for id, want := range map[int][2]string{
+		1: {"Tom (Uncle)", "555-0101"},
+		2: {"Sam (Father)", "555-0102"},
+		3: {"Ivy (Mother)", "555-0103"},
+		4: {"", ""},
+	} {
+		if g, p := guardian(students[id]), phone(students[id]); g != want[0] || p != want[1] {
+			t.Errorf("student %d: expected %q, %q, got %q, %q", id, want[0], want[1], g, p)
+		}
+	}
+}
+
+func TestRenderPDF_RepeatsHeader(t *testing.T) {
+	r := &Roster{Filter: models.StudentFilter{ClassName: "Grade 5"}}
+	for i := range 60 {
+		r.Students = append(r.Students, &models.Student{ID: i, Roll: i + 1, Name: fmt.Sprintf("Student %d", i)})
+	}
+	fonts, _ := report.LoadFonts(nil)
+	pdf := renderPDF(r, fonts, nil)
+	if pdf.Err() {
+		t.Fatal(pdf.Error())
+	}
+	if pdf.PageNo() < 3 {
+		t.Errorf("expected 60 students to span several landscape pages, got %d", pdf.PageNo())
+	}
+	if w, _ := pdf.GetPageSize(); w < 290 {
+		t.Errorf("expected a landscape page, got width %v", w)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	routes := NewHandler(NewService(&fakeBackend{students: sampleStudents()}, nil, nil, nil)).Routes()
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"pdf", "/?class=Grade%205&section=A", http.StatusOK},
+		{"xlsx", "/?class=Grade%205&format=xlsx", http.StatusOK},
+		{"no class", "/?section=A", http.StatusBadRequest},
+		{"csv", "/?class=Grade%205&format=csv", http.StatusBadRequest},
+		{"bad roll", "/?class=Grade%205&roll=first", http.StatusBadRequest},
+		{"empty", "/?class=Grade%209", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/?class=Grade%205&format=xlsx", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="roster_class_Grade 5.xlsx"` {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Roster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3+3 {
+		t.Fatalf("expected a title, a blank, a header and 3 student rows, got %d", len(rows))
+	}
+	if got := strings.Join(rows[2], ","); got != "No.,Roll,Name,Gender,Date of Birth,Guardian,Phone" {
+		t.Errorf("unexpected header %q", got)
+	}
+	if got := strings.Join(rows[3], ","); got != "1,1,Abel,Male,2012-03-04,Sam (Father),555-0102" {
+		t.Errorf("unexpected first row %q", got)
+	}
+
+	// Accept naming a format the roster lacks falls back to the PDF.
+	req := testutil.AuthedRequest(http.MethodGet, "/?class=Grade%205", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("expected the PDF, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,188 @@
+// Package roster prints class roster sheets: one table row per student of a
+// class, sorted by roll number.
+package roster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KindRoster identifies rosters in signatures.
+const KindRoster = "roster"
+
+// workers bounds the concurrent student lookups of a roster.
+const workers = 4
+
+var (
+	ErrClassRequired = errors.New("class is required")
+	ErrNoStudents    = errors.New("no students found for the roster")
+)
+
+// Roster is the content of a roster sheet.
+type Roster struct {
+	Filter      models.StudentFilter `json:"filter"`
+	Students    []*models.Student    `json:"students"`
+	GeneratedAt time.Time            `json:"generatedAt"`
+}
+
+// Subject names the class and section of the roster.
+func (r *Roster) Subject() string {
+	subject := "Class " + r.Filter.ClassName
+	if r.Filter.Section != "" {
+		subject += " - Section " + r.Filter.Section
+	}
+	return subject
+}
+
+// Options are the per-request choices of a roster.
+type Options struct {
+	Filter models.StudentFilter
+	// Format is pdf or xlsx, empty means PDF.
+	Format report.Format
+}
+
+type Service interface {
+	GenerateRoster(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend  client.IBackend
+	fonts    *report.FontSet
+	branding *report.BrandStore
+	signer   *pdfsign.Signer
+}
+
+// NewService draws PDF text with fonts, or the bundled font when nil.
+func NewService(b client.IBackend, fonts *report.FontSet, branding *report.BrandStore, signer *pdfsign.Signer) Service {
+	if fonts == nil {
+		fonts, _ = report.LoadFonts(nil)
+	}
+	return &service{backend: b, fonts: fonts, branding: branding, signer: signer}
+}
+
+func (s *service) GenerateRoster(ctx context.Context, opts Options, authCookies []*http.Cookie) (report.Writer, error) {
+	if opts.Filter.ClassName == "" {
+		return nil, ErrClassRequired
+	}
+	if opts.Format != "" && opts.Format != report.FormatPDF && opts.Format != report.FormatXLSX {
+		return nil, fmt.Errorf("%w: rosters are pdf or xlsx", report.ErrUnsupportedFormat)
+	}
+
+	students, err := s.students(ctx, opts.Filter, authCookies)
+	if err != nil {
+		return nil, err
+	}
+	r := &Roster{Filter: opts.Filter, Students: students, GeneratedAt: time.Now()}
+
+	if opts.Format == report.FormatXLSX {
+		return &xlsxWriter{roster: r}, nil
+	}
+	pdf := renderPDF(r, s.fonts, s.branding.Current())
+	return s.signer.Wrap(pdf, pdfsign.Document{Kind: KindRoster, Name: r.Subject()}), nil
+}
+
+// students lists the students matching filter and loads their full records,
+// which the list does not carry. The records are checked against the filter
+// again so that a backend ignoring a filter cannot widen the roster.
+func (s *service) students(ctx context.Context, filter models.StudentFilter, authCookies []*http.Cookie) ([]*models.Student, error) {
+	list, err := s.backend.ListStudents(ctx, filter, authCookies)
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return nil, ErrNoStudents
+	} else if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		students []*models.Student
+		sem      = make(chan struct{}, workers)
+	)
+	for _, st := range list {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			student, err := s.backend.GetStudentByID(ctx, id, authCookies)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("student %d: %w", id, err)
+				}
+			case matches(student, filter):
+				students = append(students, student)
+			}
+		}(st.ID)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if len(students) == 0 {
+		return nil, ErrNoStudents
+	}
+
+	sort.Slice(students, func(i, j int) bool {
+		if students[i].Roll != students[j].Roll {
+			return students[i].Roll < students[j].Roll
+		}
+		return students[i].Name < students[j].Name
+	})
+	return students, nil
+}
+
+func matches(st *models.Student, f models.StudentFilter) bool {
+	return strings.EqualFold(st.Class, f.ClassName) &&
+		(f.Section == "" || strings.EqualFold(st.Section, f.Section)) &&
+		(f.Name == "" || strings.EqualFold(st.Name, f.Name)) &&
+		(f.Roll == 0 || st.Roll == f.Roll)
+}
+
+// guardian names the student's guardian, falling back to the father and
+// then the mother when no guardian is recorded.
+func guardian(st *models.Student) string {
+	switch {
+	case st.GuardianName != "" && st.RelationOfGuardian != "":
+		return st.GuardianName + " (" + st.RelationOfGuardian + ")"
+	case st.GuardianName != "":
+		return st.GuardianName
+	case st.FatherName != "":
+		return st.FatherName + " (Father)"
+	case st.MotherName != "":
+		return st.MotherName + " (Mother)"
+	}
+	return ""
+}
+
+// phone is the student's phone, or the guardian's for students without one.
+func phone(st *models.Student) string {
+	for _, p := range []string{st.Phone, st.GuardianPhone, st.FatherPhone, st.MotherPhone} {
+		if p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+func dob(st *models.Student) string {
+	if st.DOB.IsZero() {
+		return ""
+	}
+	return st.DOB.Format(time.DateOnly)
+}
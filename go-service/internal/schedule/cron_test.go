@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	from := time.Date(2026, 3, 31, 10, 7, 30, 0, loc) // a Tuesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 31, 10, 15, 0, 0, loc)},
+		{"0 6 1 4,9 *", time.Date(2026, 4, 1, 6, 0, 0, 0, loc)},
+		{"30 7 * * MON-FRI", time.Date(2026, 4, 1, 7, 30, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2026, 4, 5, 0, 0, 0, 0, loc)},
+		// Both day fields restricted: the 15th or any Friday.
+		{"0 9 15 * FRI", time.Date(2026, 4, 3, 9, 0, 0, 0, loc)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, loc)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, loc)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * FUNDAY"} {
+		if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("%q: expected ErrInvalidCron, got %v", expr, err)
+		}
+	}
+}
+
+func TestCron_NextAcrossDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	c, _ := ParseCron("30 2 * * *")
+	// 02:30 does not exist on 2026-03-08, so that day is skipped.
+	if got, want := c.Next(time.Date(2026, 3, 8, 1, 0, 0, 0, loc)), time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+	// Falling back repeats 01:xx; the search still terminates.
+	c, _ = ParseCron("15 1 * * *")
+	if got := c.Next(time.Date(2026, 11, 1, 1, 20, 0, 0, loc)); got.IsZero() {
+		t.Error("expected a next run after the fall back")
+	}
+}
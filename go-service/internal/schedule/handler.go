@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"errors"
+	"goservice/internal/response"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	scheduler *Scheduler
+}
+
+func NewHandler(s *Scheduler) *Handler {
+	return &Handler{scheduler: s}
+}
+
+// Routes serves the schedules and their run history, mounted under
+// /api/v1/admin/schedules behind the admin token.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.List)
+	r.Post("/{name}/runs", h.Trigger)
+	r.Get("/{name}/runs", h.Runs)
+	r.Get("/{name}/runs/{id}", h.Run)
+	r.Get("/{name}/runs/{id}/archive", h.Archive)
+	return r
+}
+
+// errorStatus maps scheduler errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownSchedule), errors.Is(err, ErrRunNotFound), errors.Is(err, ErrNoArchive):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRunInProgress):
+		return http.StatusConflict
+	case errors.Is(err, ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, h.scheduler.Schedules())
+}
+
+func (h *Handler) Trigger(w http.ResponseWriter, r *http.Request) {
+	run, err := h.scheduler.Trigger(chi.URLParam(r, "name"))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", BasePath+run.Schedule+"/runs/"+run.ID)
+	response.JSON(w, http.StatusAccepted, run)
+}
+
+func (h *Handler) Runs(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.scheduler.Runs(chi.URLParam(r, "name"))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, runs)
+}
+
+func (h *Handler) Run(w http.ResponseWriter, r *http.Request) {
+	run, err := h.scheduler.Run(chi.URLParam(r, "name"), chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusOK, run)
+}
+
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	f, run, err := h.scheduler.Archive(chi.URLParam(r, "name"), chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	defer f.Close()
+
+	// Whole-school archives can take longer to download than the server's
+	// global write timeout allows.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	name := filepath.Base(run.Archive)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": run.Schedule + "_" + name}))
+	http.ServeContent(w, r, name, *run.FinishedAt, f)
+}
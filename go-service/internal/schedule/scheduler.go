@@ -0,0 +1,503 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BasePath is where the schedule endpoints are mounted.
+const BasePath = "/api/v1/admin/schedules/"
+
+var (
+	ErrUnknownSchedule = errors.New("unknown schedule")
+	ErrRunNotFound     = errors.New("schedule run not found")
+	ErrRunInProgress   = errors.New("schedule is already running")
+	ErrNoArchive       = errors.New("schedule run has no archive")
+	ErrShuttingDown    = errors.New("scheduler is shutting down")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// errInterrupted is recorded for runs cut short by a shutdown or crash.
+const errInterrupted = "interrupted by a server restart"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Definition is one configured schedule. An empty class covers the whole
+// school, a section narrows a class.
+type Definition struct {
+	Name     string        `json:"name"`
+	Cron     string        `json:"cron"`
+	Class    string        `json:"class,omitempty"`
+	Section  string        `json:"section,omitempty"`
+	Template string        `json:"template,omitempty"`
+	Format   report.Format `json:"format,omitempty"`
+}
+
+// Trigger tells what started a run.
+type Trigger string
+
+const (
+	TriggerCron   Trigger = "cron"
+	TriggerManual Trigger = "manual"
+)
+
+// State is the outcome of a run.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	// StatePartial means the archive was written but some students failed.
+	StatePartial State = "partial"
+	StateFailed  State = "failed"
+)
+
+// Run is one execution of a schedule with its per-student results.
+type Run struct {
+	ID       string  `json:"id"`
+	Schedule string  `json:"schedule"`
+	Trigger  Trigger `json:"trigger"`
+	State    State   `json:"state"`
+	// Archive is the path of the ZIP file relative to the archive directory.
+	Archive    string                 `json:"archive,omitempty"`
+	Total      int                    `json:"total"`
+	Succeeded  int                    `json:"succeeded"`
+	Failed     int                    `json:"failed"`
+	Students   []student.ArchiveEntry `json:"students,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"startedAt"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+}
+
+// Status describes a schedule for listing.
+type Status struct {
+	Definition
+	NextRun *time.Time `json:"nextRun,omitempty"`
+	Running bool       `json:"running"`
+	LastRun *Run       `json:"lastRun,omitempty"`
+}
+
+// Options configure a Scheduler.
+type Options struct {
+	// ArchiveDir receives one ZIP archive per run, in a directory per
+	// schedule.
+	ArchiveDir string
+	// Store persists the run history. Without it the history is lost on
+	// restart.
+	Store *Store
+	// Username and Password are the service account used to fetch students.
+	Username string
+	Password string
+	// Location is the time zone of the cron expressions, local time when nil.
+	Location *time.Location
+}
+
+type scheduled struct {
+	def  Definition
+	cron *Cron
+	next time.Time
+}
+
+// Scheduler runs the configured schedules and records their runs.
+type Scheduler struct {
+	service student.Service
+	opts    Options
+	order   []*scheduled
+	byName  map[string]*scheduled
+	now     func() time.Time
+
+	ctx      context.Context
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+	loopDone chan struct{}
+	mu       sync.Mutex
+	runs     map[string]*Run
+	running  map[string]string
+	closed   bool
+}
+
+// New validates the schedules and reloads the run history. Runs the store
+// still records as running were cut short and are marked failed.
+func New(svc student.Service, defs []Definition, opts Options) (*Scheduler, error) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if len(defs) > 0 && opts.Username == "" {
+		return nil, fmt.Errorf("%w: scheduled reports need a service account", ErrInvalidSchedule)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	s := &Scheduler{
+		service: svc,
+		opts:    opts,
+		byName:  map[string]*scheduled{},
+		now:     time.Now,
+		ctx:     ctx,
+		stop:    stop,
+		runs:    map[string]*Run{},
+		running: map[string]string{},
+	}
+	for _, def := range defs {
+		if err := s.add(def); err != nil {
+			stop()
+			return nil, err
+		}
+	}
+	if err := s.load(); err != nil {
+		stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scheduler) add(def Definition) error {
+	if !validName.MatchString(def.Name) {
+		return fmt.Errorf("%w: name %q may only use letters, digits, - and _", ErrInvalidSchedule, def.Name)
+	}
+	if _, ok := s.byName[def.Name]; ok {
+		return fmt.Errorf("%w: duplicate name %q", ErrInvalidSchedule, def.Name)
+	}
+	if def.Section != "" && def.Class == "" {
+		return fmt.Errorf("%w %s: a section needs a class", ErrInvalidSchedule, def.Name)
+	}
+	cron, err := ParseCron(def.Cron)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", def.Name, err)
+	}
+	if def.Format != "" {
+		if def.Format, err = report.ParseFormat(string(def.Format)); err != nil {
+			return fmt.Errorf("schedule %s: %w", def.Name, err)
+		}
+	}
+	e := &scheduled{def: def, cron: cron}
+	s.order = append(s.order, e)
+	s.byName[def.Name] = e
+	return nil
+}
+
+func (s *Scheduler) load() error {
+	if s.opts.Store == nil {
+		return nil
+	}
+	runs, err := s.opts.Store.all()
+	if err != nil {
+		return err
+	}
+	for i := range runs {
+		run := &runs[i]
+		if run.State == StateRunning {
+			finished := s.now()
+			run.State, run.Error, run.FinishedAt = StateFailed, errInterrupted, &finished
+			s.save(run)
+		}
+		s.runs[run.ID] = run
+	}
+	return nil
+}
+
+// Start begins running the schedules at their times.
+func (s *Scheduler) Start() {
+	s.loopDone = make(chan struct{})
+	go s.loop()
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.loopDone)
+	if len(s.order) == 0 {
+		return
+	}
+
+	now := s.now().In(s.opts.Location)
+	for _, e := range s.order {
+		e.next = e.cron.Next(now)
+	}
+	for {
+		var next time.Time
+		for _, e := range s.order {
+			if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+				next = e.next
+			}
+		}
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := s.now().In(s.opts.Location)
+		for _, e := range s.order {
+			if e.next.IsZero() || e.next.After(now) {
+				continue
+			}
+			if _, err := s.start(e, TriggerCron); err != nil {
+				log.Printf("schedule %s skipped: %v", e.def.Name, err)
+			}
+			e.next = e.cron.Next(now)
+		}
+	}
+}
+
+// Shutdown stops the scheduler and interrupts running runs, waiting for
+// them to record their outcome until ctx ends.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		if s.loopDone != nil {
+			<-s.loopDone
+		}
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger starts a run of the named schedule now.
+func (s *Scheduler) Trigger(name string) (Run, error) {
+	e, ok := s.byName[name]
+	if !ok {
+		return Run{}, ErrUnknownSchedule
+	}
+	return s.start(e, TriggerManual)
+}
+
+func (s *Scheduler) start(e *scheduled, trigger Trigger) (Run, error) {
+	id, err := newID()
+	if err != nil {
+		return Run{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Run{}, ErrShuttingDown
+	}
+	if _, ok := s.running[e.def.Name]; ok {
+		return Run{}, ErrRunInProgress
+	}
+	run := &Run{ID: id, Schedule: e.def.Name, Trigger: trigger, State: StateRunning, StartedAt: s.now()}
+	s.runs[id] = run
+	s.running[e.def.Name] = id
+	s.save(run)
+
+	s.wg.Add(1)
+	go s.execute(e.def, run)
+	return snapshot(run), nil
+}
+
+func (s *Scheduler) execute(def Definition, run *Run) {
+	defer s.wg.Done()
+	err := s.generate(def, run)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := s.now()
+	run.FinishedAt = &finished
+	switch {
+	case s.ctx.Err() != nil:
+		run.State, run.Error = StateFailed, errInterrupted
+	case err != nil:
+		run.State, run.Error = StateFailed, err.Error()
+	case run.Failed > 0:
+		run.State = StatePartial
+	default:
+		run.State = StateSucceeded
+	}
+	delete(s.running, def.Name)
+	s.save(run)
+	log.Printf("schedule %s run %s %s: %d of %d reports", def.Name, run.ID, run.State, run.Succeeded, run.Total)
+}
+
+// generate logs in with the service account and writes the archive of the
+// run.
+func (s *Scheduler) generate(def Definition, run *Run) error {
+	cookies, err := s.service.Login(s.ctx, s.opts.Username, s.opts.Password)
+	if err != nil {
+		return fmt.Errorf("service account login: %v", err)
+	}
+
+	batch := student.Batch{Class: def.Class, Section: def.Section}
+	opts := student.ReportOptions{Template: def.Template, Format: def.Format}
+	rep, err := s.service.GenerateBatchReports(s.ctx, batch, opts, s.progress(run), cookies)
+	if err != nil {
+		return err
+	}
+
+	// The archive is written next to its final name and renamed once
+	// complete, so the archive directory never holds a partial file.
+	rel := filepath.Join(def.Name, run.StartedAt.In(s.opts.Location).Format("20060102-150405")+"_"+run.ID[:8]+".zip")
+	path := filepath.Join(s.opts.ArchiveDir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path+".part", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	err = rep.Output(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = s.ctx.Err()
+	}
+	if err != nil {
+		os.Remove(path + ".part")
+		return err
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	run.Archive = rel
+	s.mu.Unlock()
+	return nil
+}
+
+// progress records the outcome of every student of the run.
+func (s *Scheduler) progress(run *Run) student.BatchProgress {
+	return func(res student.ArchiveEntry, total int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		run.Total = total
+		switch {
+		case res.Error != "":
+			run.Failed++
+		case res.File != "":
+			run.Succeeded++
+		default:
+			// Not in the requested class or section.
+			return
+		}
+		run.Students = append(run.Students, res)
+	}
+}
+
+// Schedules lists the schedules with their next and last runs.
+func (s *Scheduler) Schedules() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := map[string]*Run{}
+	for _, run := range s.runs {
+		if l, ok := last[run.Schedule]; !ok || run.StartedAt.After(l.StartedAt) {
+			last[run.Schedule] = run
+		}
+	}
+
+	out := make([]Status, 0, len(s.order))
+	now := s.now().In(s.opts.Location)
+	for _, e := range s.order {
+		st := Status{Definition: e.def}
+		if next := e.cron.Next(now); !next.IsZero() {
+			st.NextRun = &next
+		}
+		_, st.Running = s.running[e.def.Name]
+		if run, ok := last[e.def.Name]; ok {
+			r := snapshot(run)
+			r.Students = nil
+			st.LastRun = &r
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// Runs returns the history of a schedule, newest first. Per-student results
+// are left out; Run has them.
+func (s *Scheduler) Runs(name string) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Run{}
+	for _, run := range s.runs {
+		if run.Schedule == name {
+			r := snapshot(run)
+			r.Students = nil
+			out = append(out, r)
+		}
+	}
+	if _, ok := s.byName[name]; !ok && len(out) == 0 {
+		return nil, ErrUnknownSchedule
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out, nil
+}
+
+// Run returns one run of the named schedule.
+func (s *Scheduler) Run(name, id string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok || run.Schedule != name {
+		return Run{}, ErrRunNotFound
+	}
+	return snapshot(run), nil
+}
+
+// Archive opens the archive written by a run.
+func (s *Scheduler) Archive(name, id string) (*os.File, Run, error) {
+	run, err := s.Run(name, id)
+	if err != nil {
+		return nil, Run{}, err
+	}
+	if run.Archive == "" {
+		return nil, Run{}, ErrNoArchive
+	}
+	f, err := os.Open(filepath.Join(s.opts.ArchiveDir, run.Archive))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Run{}, ErrNoArchive
+	}
+	return f, run, err
+}
+
+// save persists the run. The caller holds s.mu or owns run exclusively.
+func (s *Scheduler) save(run *Run) {
+	if s.opts.Store == nil {
+		return
+	}
+	if err := s.opts.Store.put(*run); err != nil {
+		log.Printf("schedule run %s not saved: %v", run.ID, err)
+	}
+}
+
+// snapshot copies the run so that callers can use it without the lock.
+func snapshot(run *Run) Run {
+	r := *run
+	r.Students = append([]student.ArchiveEntry(nil), run.Students...)
+	return r
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"goservice/internal/student"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type writerFunc func(w io.Writer) error
+
+func (f writerFunc) Output(w io.Writer) error { return f(w) }
+
+// fakeService logs in the service account and renders a fixed batch. The
+// remaining methods of student.Service are not called.
+type fakeService struct {
+	student.Service
+	login   func(username, password string) error
+	release chan struct{}
+	batches []student.Batch
+}
+
+func (f *fakeService) Login(_ context.Context, username, password string) ([]*http.Cookie, error) {
+	if f.login != nil {
+		if err := f.login(username, password); err != nil {
+			return nil, err
+		}
+	}
+	return []*http.Cookie{{Name: "token", Value: "service"}}, nil
+}
+
+func (f *fakeService) GenerateBatchReports(ctx context.Context, b student.Batch, _ student.ReportOptions, progress student.BatchProgress, cookies []*http.Cookie) (student.ReportWriter, error) {
+	f.batches = append(f.batches, b)
+	return writerFunc(func(w io.Writer) error {
+		if f.release != nil {
+			select {
+			case <-f.release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		progress(student.ArchiveEntry{StudentID: 1, Name: "Asha", File: "1_asha.pdf"}, 3)
+		progress(student.ArchiveEntry{StudentID: 2, Name: "Ravi", Error: "backend timeout"}, 3)
+		progress(student.ArchiveEntry{StudentID: 3, Name: "Elsewhere"}, 3)
+		zw := zip.NewWriter(w)
+		zw.Create("1_asha.pdf")
+		return zw.Close()
+	}), nil
+}
+
+func waitForRun(t *testing.T, s *Scheduler, name, id string) Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := s.Run(name, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.State != StateRunning {
+			return run
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not finish", id)
+	return Run{}
+}
+
+func TestNew_Validates(t *testing.T) {
+	opts := Options{Username: "svc"}
+	tests := []struct {
+		defs []Definition
+		opts Options
+	}{
+		{[]Definition{{Name: "term", Cron: "@daily"}}, Options{}},
+		{[]Definition{{Name: "term end", Cron: "@daily"}}, opts},
+		{[]Definition{{Name: "term", Cron: "@daily"}, {Name: "term", Cron: "@weekly"}}, opts},
+		{[]Definition{{Name: "term", Cron: "@daily", Section: "A"}}, opts},
+		{[]Definition{{Name: "term", Cron: "daily"}}, opts},
+		{[]Definition{{Name: "term", Cron: "@daily", Format: "docx"}}, opts},
+	}
+	for i, tt := range tests {
+		if _, err := New(&fakeService{}, tt.defs, tt.opts); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
+
+func TestScheduler_TriggerAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "runs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeService{release: make(chan struct{}), login: func(u, p string) error {
+		if u != "svc" || p != "secret" {
+			return errors.New("bad credentials")
+		}
+		return nil
+	}}
+	defs := []Definition{{Name: "class-10", Cron: "0 6 1 4 *", Class: "10"}}
+	s, err := New(svc, defs, Options{ArchiveDir: filepath.Join(dir, "archive"), Store: store, Username: "svc", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := s.Trigger("class-10")
+	if err != nil || run.State != StateRunning || run.Trigger != TriggerManual {
+		t.Fatalf("unexpected run %+v, %v", run, err)
+	}
+	if _, err := s.Trigger("class-10"); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("expected ErrRunInProgress, got %v", err)
+	}
+	if _, err := s.Trigger("nope"); !errors.Is(err, ErrUnknownSchedule) {
+		t.Errorf("expected ErrUnknownSchedule, got %v", err)
+	}
+	close(svc.release)
+
+	run = waitForRun(t, s, "class-10", run.ID)
+	if run.State != StatePartial || run.Total != 3 || run.Succeeded != 1 || run.Failed != 1 || len(run.Students) != 2 {
+		t.Errorf("unexpected run %+v", run)
+	}
+	if b := svc.batches[0]; b.Class != "10" || b.Section != "" || len(b.StudentIDs) != 0 {
+		t.Errorf("unexpected batch %+v", svc.batches[0])
+	}
+	f, _, err := s.Archive("class-10", run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := os.Stat(filepath.Join(dir, "archive", run.Archive)); err != nil || filepath.Dir(run.Archive) != "class-10" {
+		t.Errorf("expected the archive under the schedule directory, got %q, %v", run.Archive, err)
+	}
+
+	status := s.Schedules()
+	if len(status) != 1 || status[0].NextRun == nil || status[0].LastRun == nil || status[0].LastRun.ID != run.ID {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Trigger("class-10"); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown, got %v", err)
+	}
+
+	// A run that was still going when the process died is recorded as
+	// failed on the next start.
+	store.put(Run{ID: "stale", Schedule: "class-10", State: StateRunning, StartedAt: time.Now()})
+	s, err = New(svc, defs, Options{ArchiveDir: filepath.Join(dir, "archive"), Store: store, Username: "svc", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := s.Runs("class-10")
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("expected both runs in the history, got %+v, %v", runs, err)
+	}
+	if stale, _ := s.Run("class-10", "stale"); stale.State != StateFailed || stale.Error != errInterrupted {
+		t.Errorf("expected the stale run to be failed, got %+v", stale)
+	}
+	store.Close()
+}
+
+func TestScheduler_LoginFailure(t *testing.T) {
+	svc := &fakeService{login: func(string, string) error { return errors.New("bad credentials") }}
+	s, err := New(svc, []Definition{{Name: "school", Cron: "@yearly"}}, Options{ArchiveDir: t.TempDir(), Username: "svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.Trigger("school")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = waitForRun(t, s, "school", run.ID)
+	if run.State != StateFailed || run.Error == "" || run.Archive != "" {
+		t.Errorf("expected a failed run, got %+v", run)
+	}
+	if _, _, err := s.Archive("school", run.ID); !errors.Is(err, ErrNoArchive) {
+		t.Errorf("expected ErrNoArchive, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, err := New(&fakeService{}, []Definition{{Name: "school", Cron: "@yearly"}}, Options{ArchiveDir: t.TempDir(), Username: "svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Mount("/schedules", NewHandler(s).Routes())
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/schedules/school/runs")
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Location") == "" {
+		t.Fatalf("expected 202 with a Location, got %d", rec.Code)
+	}
+	runs, _ := s.Runs("school")
+	waitForRun(t, s, "school", runs[0].ID)
+
+	for path, want := range map[string]int{
+		"/schedules":                                        http.StatusOK,
+		"/schedules/school/runs":                            http.StatusOK,
+		"/schedules/school/runs/" + runs[0].ID:              http.StatusOK,
+		"/schedules/school/runs/" + runs[0].ID + "/archive": http.StatusOK,
+		"/schedules/other/runs":                             http.StatusNotFound,
+		"/schedules/school/runs/missing":                    http.StatusNotFound,
+	} {
+		if rec := do(http.MethodGet, path); rec.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+	if rec := do(http.MethodGet, "/schedules/school/runs/"+runs[0].ID+"/archive"); !bytes.HasPrefix(rec.Body.Bytes(), []byte("PK")) {
+		t.Error("expected the ZIP archive")
+	}
+}
@@ -0,0 +1,106 @@
+package staff
+
+import (
+	"errors"
+	"fmt"
+	"goservice/internal/report"
+	"goservice/internal/response"
+	"goservice/internal/student"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Routes serves staff profiles and reports, mounted under /api/v1/staffs.
+// Requests need the same backend session cookies as student routes.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/{id}", h.GetStaff)
+	r.Get("/{id}/report", h.GenerateReport)
+	return r
+}
+
+// reportErrorStatus maps report generation errors to HTTP status codes.
+func reportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, report.ErrUnknownTemplate), errors.Is(err, report.ErrUnsupportedFormat),
+		errors.Is(err, report.ErrPasswordRequired), errors.Is(err, report.ErrRequestPasswordDenied),
+		errors.Is(err, report.ErrEncryptionNotSupported):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) GetStaff(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	staff, err := h.service.GetStaff(r.Context(), id, cookies)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, staff)
+}
+
+func (h *Handler) GenerateReport(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := student.CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	opts, err := student.ParseReportOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateReport(r.Context(), id, opts, cookies)
+	if err != nil {
+		response.Error(w, reportErrorStatus(err), err)
+		return
+	}
+
+	disposition := "attachment"
+	if opts.Format == report.FormatHTML {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fmt.Sprintf("staff_%d_report.%s", id, opts.Format.Extension())}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,65 @@
+// Package staff serves staff profiles and their PDF reports.
+package staff
+
+import (
+	"context"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"net/http"
+)
+
+type Service interface {
+	GetStaff(ctx context.Context, id int, authCookies []*http.Cookie) (*models.Staff, error)
+	GenerateReport(ctx context.Context, id int, opts student.ReportOptions, authCookies []*http.Cookie) (report.Writer, error)
+}
+
+type service struct {
+	backend    client.IBackend
+	templates  *report.Registry
+	branding   *report.BrandStore
+	signer     *pdfsign.Signer
+	protection *report.Protection
+}
+
+func NewService(b client.IBackend, templates *report.Registry, branding *report.BrandStore, signer *pdfsign.Signer, protection *report.Protection) Service {
+	return &service{backend: b, templates: templates, branding: branding, signer: signer, protection: protection}
+}
+
+func (s *service) GetStaff(ctx context.Context, id int, authCookies []*http.Cookie) (*models.Staff, error) {
+	return s.backend.GetStaffByID(ctx, id, authCookies)
+}
+
+// GenerateReport renders the profile of one staff member with a staff
+// template, branded, encrypted under the "staff" policy and signed like
+// student reports. Staff reports carry no issue serial.
+func (s *service) GenerateReport(ctx context.Context, id int, opts student.ReportOptions, authCookies []*http.Cookie) (report.Writer, error) {
+	tmpl, err := s.templates.Lookup(report.KindStaff, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	staff, err := s.GetStaff(ctx, id, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := s.protection.Resolve(report.KindStaff, staff, opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := report.Render(opts.Format, tmpl, staff, report.PDFOptions{Brand: s.branding.Current(), Encryption: enc})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format != "" && opts.Format != report.FormatPDF {
+		return rep, nil
+	}
+	doc := pdfsign.Document{Kind: report.KindStaff, ID: staff.ID, Name: staff.Name}
+	if enc != nil {
+		doc.Password = enc.UserPassword
+	}
+	return s.signer.Wrap(rep, doc), nil
+}
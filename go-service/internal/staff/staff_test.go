@@ -0,0 +1,138 @@
+package staff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	client.IBackend
+	staff *models.Staff
+}
+
+func (f *fakeBackend) GetStaffByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Staff, error) {
+	if f.staff == nil || f.staff.ID != id {
+		return nil, errors.New("failed to get staff: Staff detail not found")
+	}
+	return f.staff, nil
+}
+
+func sampleStaff() *models.Staff {
+	return &models.Staff{
+		ID:           4,
+		Name:         "Dana Scully",
+		Email:        "dana@school.example",
+		SystemAccess: true,
+		RoleName:     "Teacher",
+		Department:   "Science",
+		ReporterName: "Walter Skinner",
+		JoinDate:     time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+		FatherName:   "William Scully",
+		MotherName:   "Margaret Scully",
+	}
+}
+
+func newTestService() Service {
+	return NewService(&fakeBackend{staff: sampleStaff()}, nil, nil, nil, nil)
+}
+
+func TestService_GenerateReport(t *testing.T) {
+	svc := newTestService()
+
+	rep, err := svc.GenerateReport(context.Background(), 4, student.ReportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := rep.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "%PDF") {
+		t.Error("expected a PDF")
+	}
+
+	rep, err = svc.GenerateReport(context.Background(), 4, student.ReportOptions{Format: report.FormatJSON}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := rep.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Kind     string `json:"kind"`
+		Sections []struct {
+			Title  string                 `json:"title"`
+			Fields []report.ResolvedField `json:"fields"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, s := range doc.Sections {
+		for _, f := range s.Fields {
+			values[f.Label] = f.Value
+		}
+	}
+	want := map[string]string{"Department": "Science", "Role": "Teacher", "Joined": "01 Apr 2019", "Reports To": "Walter Skinner", "Father": "William Scully"}
+	for label, v := range want {
+		if values[label] != v {
+			t.Errorf("%s: expected %q, got %q", label, v, values[label])
+		}
+	}
+	if doc.Kind != report.KindStaff {
+		t.Errorf("expected kind %q, got %q", report.KindStaff, doc.Kind)
+	}
+
+	if _, err := svc.GenerateReport(context.Background(), 4, student.ReportOptions{Template: "compact"}, nil); !errors.Is(err, report.ErrUnknownTemplate) {
+		t.Errorf("expected student-only templates to be unknown for staff, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	routes := NewHandler(newTestService()).Routes()
+
+	for _, tc := range []struct {
+		name        string
+		req         *http.Request
+		status      int
+		contentType string
+	}{
+		{"profile", testutil.AuthedRequest(http.MethodGet, "/4", nil), http.StatusOK, "application/json"},
+		{"report", testutil.AuthedRequest(http.MethodGet, "/4/report", nil), http.StatusOK, "application/pdf"},
+		{"html report", testutil.AuthedRequest(http.MethodGet, "/4/report?format=html", nil), http.StatusOK, "text/html; charset=utf-8"},
+		{"no session", httptest.NewRequest(http.MethodGet, "/4/report", nil), http.StatusUnauthorized, ""},
+		{"bad id", testutil.AuthedRequest(http.MethodGet, "/x/report", nil), http.StatusBadRequest, ""},
+		{"bad template", testutil.AuthedRequest(http.MethodGet, "/4/report?template=nope", nil), http.StatusBadRequest, ""},
+		{"unknown staff", testutil.AuthedRequest(http.MethodGet, "/5", nil), http.StatusInternalServerError, ""},
+	} {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, tc.req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body)
+			continue
+		}
+		if tc.contentType != "" && !strings.HasPrefix(rec.Header().Get("Content-Type"), tc.contentType) {
+			t.Errorf("%s: unexpected content type %q", tc.name, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, testutil.AuthedRequest(http.MethodGet, "/4/report", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=staff_4_report.pdf" {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+}
@@ -0,0 +1,216 @@
+package student
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/issuance"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveWorkers bounds how many student reports are fetched and rendered
+// concurrently while building a class archive.
+const archiveWorkers = 4
+
+// ManifestFileName is the name of the JSON summary written as the last entry
+// of every class archive.
+const ManifestFileName = "manifest.json"
+
+// ArchiveEntry describes the outcome for one student in a class archive.
+type ArchiveEntry struct {
+	StudentID int    `json:"studentId"`
+	Name      string `json:"name"`
+	File      string `json:"file,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ArchiveManifest is written into the archive so that per-student failures
+// are reported without aborting the whole download.
+type ArchiveManifest struct {
+	Class       string         `json:"class"`
+	Section     string         `json:"section"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Total       int            `json:"total"`
+	Succeeded   int            `json:"succeeded"`
+	Failed      int            `json:"failed"`
+	Entries     []ArchiveEntry `json:"entries"`
+}
+
+// classArchive streams a ZIP of per-student PDF reports. Reports are rendered
+// by a bounded pool of workers and written to the archive as they finish.
+type classArchive struct {
+	ctx        context.Context
+	backend    client.IBackend
+	cookies    []*http.Cookie
+	class      string
+	section    string
+	students   []models.Student
+	template   *report.Template
+	brand      *report.Brand
+	signer     *pdfsign.Signer
+	protection *report.Protection
+	issuer     *issuance.Issuer
+	opts       ReportOptions
+	progress   BatchProgress
+}
+
+type renderedReport struct {
+	entry ArchiveEntry
+	data  []byte
+	skip  bool
+}
+
+func (a *classArchive) Output(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+
+	jobs := make(chan models.Student)
+	results := make(chan renderedReport)
+
+	var wg sync.WaitGroup
+	for i := 0; i < archiveWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for st := range jobs {
+				select {
+				case results <- a.render(ctx, st):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, st := range a.students {
+			select {
+			case jobs <- st:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	manifest := ArchiveManifest{
+		Class:       a.class,
+		Section:     a.section,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	for res := range results {
+		if a.progress != nil {
+			a.progress(res.entry, len(a.students))
+		}
+		if res.skip {
+			continue
+		}
+		if res.entry.Error == "" {
+			if err := writeZipEntry(zw, res.entry.File, res.data); err != nil {
+				return err
+			}
+		}
+		manifest.Entries = append(manifest.Entries, res.entry)
+	}
+
+	if err := a.ctx.Err(); err != nil {
+		return err
+	}
+
+	manifest.Total = len(manifest.Entries)
+	for _, e := range manifest.Entries {
+		if e.Error != "" {
+			manifest.Failed++
+		} else {
+			manifest.Succeeded++
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipEntry(zw, ManifestFileName, data); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// render fetches the full student record and renders its PDF report. Errors
+// are recorded on the entry rather than returned.
+func (a *classArchive) render(ctx context.Context, st models.Student) renderedReport {
+	res := renderedReport{entry: ArchiveEntry{StudentID: st.ID, Name: st.Name}}
+
+	student, err := a.backend.GetStudentByID(ctx, st.ID, a.cookies)
+	if err != nil {
+		res.entry.Error = err.Error()
+		return res
+	}
+
+	// The list endpoint may not apply the class/section filters, so the
+	// detailed record is checked before rendering. Empty filters match all.
+	if !matchesFilter(student.Class, a.class) || !matchesFilter(student.Section, a.section) {
+		res.skip = true
+		return res
+	}
+
+	buf := new(bytes.Buffer)
+	rep, err := renderStudent(student, a.template, a.opts, a.brand, a.signer, a.protection, a.issuer)
+	if err == nil {
+		err = rep.Output(buf)
+	}
+	if err != nil {
+		res.entry.Error = err.Error()
+		return res
+	}
+
+	res.entry.Name = student.Name
+	res.entry.File = reportFileName(student, a.opts.Format)
+	res.data = buf.Bytes()
+	return res
+}
+
+func matchesFilter(value, filter string) bool {
+	return filter == "" || strings.EqualFold(value, filter)
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return zw.Flush()
+}
+
+func reportFileName(student *models.Student, format report.Format) string {
+	if format == "" {
+		format = report.FormatPDF
+	}
+	return fmt.Sprintf("student_%d_roll_%d_report.%s", student.ID, student.Roll, format.Extension())
+}
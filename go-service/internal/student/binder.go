@@ -0,0 +1,207 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	ErrNoStudents = errors.New("no students found for class and section")
+)
+
+const (
+	tocLineHeight  = 8.0
+	tocTitleHeight = 14.0
+)
+
+// tocLayout places the contents entries below the letterhead of the
+// reserved pages.
+type tocLayout struct {
+	top     float64
+	perPage int
+}
+
+// contentsLayout measures a freshly added page, whose header has already
+// moved the current position below the letterhead.
+func contentsLayout(pdf *gofpdf.Fpdf) tocLayout {
+	_, pageH := pdf.GetPageSize()
+	_, breakMargin := pdf.GetAutoPageBreak()
+	top := pdf.GetY()
+	perPage := int((pageH - breakMargin - top - tocTitleHeight) / tocLineHeight)
+	return tocLayout{top: top, perPage: max(perPage, 1)}
+}
+
+// binderEntry tracks where a student's report starts in the merged document.
+type binderEntry struct {
+	student *models.Student
+	page    int
+	link    int
+}
+
+// fetchClassStudents loads the full record of every listed student with a
+// bounded number of concurrent backend calls. Students outside the requested
+// class/section are dropped and failed lookups are returned separately.
+func fetchClassStudents(ctx context.Context, backend client.IBackend, cookies []*http.Cookie, class, section string, list []models.Student) ([]*models.Student, []ArchiveEntry) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		students []*models.Student
+		failed   []ArchiveEntry
+		sem      = make(chan struct{}, archiveWorkers)
+	)
+
+	for _, st := range list {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(st models.Student) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			student, err := backend.GetStudentByID(ctx, st.ID, cookies)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = append(failed, ArchiveEntry{StudentID: st.ID, Name: st.Name, Error: err.Error()})
+				return
+			}
+			if strings.EqualFold(student.Class, class) && strings.EqualFold(student.Section, section) {
+				students = append(students, student)
+			}
+		}(st)
+	}
+	wg.Wait()
+
+	sort.Slice(students, func(i, j int) bool {
+		if students[i].Roll != students[j].Roll {
+			return students[i].Roll < students[j].Roll
+		}
+		return students[i].ID < students[j].ID
+	})
+	sort.Slice(failed, func(i, j int) bool { return failed[i].StudentID < failed[j].StudentID })
+
+	return students, failed
+}
+
+// generateBinder renders every student into a single document with a cover
+// page, a table of contents with page numbers and one outline entry per
+// student. Each student starts on a new page.
+func generateBinder(tmpl *report.Template, pdfOpts report.PDFOptions, class, section string, students []*models.Student, failed []ArchiveEntry) *gofpdf.Fpdf {
+	pdf := tmpl.NewPDF()
+	fonts := tmpl.FontSet()
+	pdf.SetTitle(fmt.Sprintf("Class %s - Section %s Student Reports", class, section), true)
+
+	pdfOpts.Encryption.Apply(pdf)
+
+	var current string
+	if brand := pdfOpts.Brand; brand != nil {
+		brand.Apply(pdf, fonts, func() string { return current })
+	} else {
+		pdf.SetFooterFunc(func() {
+			if current == "" {
+				return
+			}
+			pdf.SetY(-15)
+			fonts.Cell(pdf, report.Font{Style: "I", Size: 8}, 0, 10, fmt.Sprintf("%s - Page %d", current, pdf.PageNo()), "", 0, "C", 0)
+		})
+	}
+
+	renderCover(pdf, fonts, pdfOpts.Stamp, class, section, len(students), failed)
+
+	// Reserve the contents pages up front so that student page numbers are
+	// known before the entries are written.
+	var toc tocLayout
+	firstTOCPage := pdf.PageNo() + 1
+	if len(students) > 0 {
+		pdf.AddPage()
+		fonts.Bookmark(pdf, "Contents", 0, 0)
+		toc = contentsLayout(pdf)
+		tocPages := (len(students) + toc.perPage - 1) / toc.perPage
+		for i := 1; i < tocPages; i++ {
+			pdf.AddPage()
+		}
+	}
+
+	entries := make([]binderEntry, 0, len(students))
+	for _, st := range students {
+		// The footer of the previous page is emitted by AddPage, so the
+		// name is switched only once the new page has started.
+		pdf.AddPage()
+		current = st.Name
+		link := pdf.AddLink()
+		pdf.SetLink(link, 0, -1)
+		fonts.Bookmark(pdf, fmt.Sprintf("%d. %s", st.Roll, st.Name), 0, 0)
+		entries = append(entries, binderEntry{student: st, page: pdf.PageNo(), link: link})
+		if err := tmpl.RenderPDF(pdf, st); err != nil {
+			pdf.SetError(err)
+			return pdf
+		}
+	}
+	lastPage := pdf.PageNo()
+
+	renderContents(pdf, fonts, firstTOCPage, toc, entries)
+	pdf.SetPage(lastPage)
+
+	return pdf
+}
+
+func renderCover(pdf *gofpdf.Fpdf, fonts *report.FontSet, stamp *report.Stamp, class, section string, total int, failed []ArchiveEntry) {
+	pdf.AddPage()
+	fonts.Bookmark(pdf, "Cover", 0, 0)
+	stamp.Draw(pdf, fonts)
+
+	pdf.SetY(80)
+	fonts.Cell(pdf, report.Font{Style: "B", Size: 24}, 0, 12, "Student Reports", "", 1, "C", 0)
+	fonts.Cell(pdf, report.Font{Size: 16}, 0, 10, fmt.Sprintf("Class %s - Section %s", class, section), "", 1, "C", 0)
+	pdf.Ln(10)
+	body := report.Font{Size: 12}
+	fonts.Cell(pdf, body, 0, 8, fmt.Sprintf("%d students", total), "", 1, "C", 0)
+	fonts.Cell(pdf, body, 0, 8, "Generated on "+time.Now().Format("2006-01-02"), "", 1, "C", 0)
+
+	if len(failed) > 0 {
+		pdf.Ln(10)
+		fonts.Cell(pdf, report.Font{Style: "B", Size: 12}, 0, 8, "Not included:", "", 1, "L", 0)
+		for _, f := range failed {
+			fonts.MultiCell(pdf, report.Font{Size: 10}, 0, 6, fmt.Sprintf("#%d %s: %s", f.StudentID, f.Name, f.Error), "L")
+		}
+	}
+}
+
+// renderContents goes back to the reserved contents pages and writes one
+// linked line per student with its starting page number.
+func renderContents(pdf *gofpdf.Fpdf, fonts *report.FontSet, firstPage int, toc tocLayout, entries []binderEntry) {
+	autoBreak, margin := pdf.GetAutoPageBreak()
+	pdf.SetAutoPageBreak(false, 0)
+	defer pdf.SetAutoPageBreak(autoBreak, margin)
+
+	left, _, right, _ := pdf.GetMargins()
+	pageW, _ := pdf.GetPageSize()
+	width := pageW - left - right
+
+	for i, e := range entries {
+		if i%toc.perPage == 0 {
+			pdf.SetPage(firstPage + i/toc.perPage)
+			pdf.SetXY(left, toc.top)
+			fonts.Cell(pdf, report.Font{Style: "B", Size: 16}, width, 10, "Contents", "", 0, "L", 0)
+			pdf.SetY(toc.top + tocTitleHeight)
+		}
+
+		y := pdf.GetY()
+		label := fmt.Sprintf("%d. %s", e.student.Roll, e.student.Name)
+		entry := report.Font{Size: 12}
+		fonts.Cell(pdf, entry, width-20, tocLineHeight, label, "", 0, "L", e.link)
+		fonts.Cell(pdf, entry, 20, tocLineHeight, fmt.Sprintf("%d", e.page), "", 1, "R", e.link)
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Line(left, y+tocLineHeight, left+width, y+tocLineHeight)
+	}
+}
@@ -0,0 +1,58 @@
+package student
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"io"
+)
+
+// TaggedReport is implemented by reports whose content is identified by an
+// entity tag, letting handlers answer conditional requests.
+type TaggedReport interface {
+	ReportWriter
+	ETag() string
+}
+
+// reportKey hashes everything a student report is rendered from: the
+// backend record, the template and its version, the format, the brand and
+// the encryption choice. Reports protected with a caller supplied password
+// get no key and are neither tagged nor cached.
+func reportKey(student *models.Student, tmpl *report.Template, opts ReportOptions, brand *report.Brand) (string, error) {
+	if opts.Encryption.Password != "" {
+		return "", nil
+	}
+	data, err := json.Marshal(student)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(data)
+	fmt.Fprintf(h, "\n%s\n%s\n%s\n%s\n%s\n%t", tmpl.Kind, tmpl.Name, tmpl.Version, opts.Format, brand.Version(), opts.Encryption.Encrypt)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// reportETag is a weak tag: re-rendering the same inputs yields an
+// equivalent report but not necessarily the same bytes, e.g. because of
+// signing times.
+func reportETag(key string) string {
+	return `W/"` + key[:32] + `"`
+}
+
+// cachedReport replays a report rendered earlier.
+type cachedReport struct {
+	data []byte
+	etag string
+}
+
+func (c *cachedReport) Output(w io.Writer) error {
+	_, err := w.Write(c.data)
+	return err
+}
+
+func (c *cachedReport) ETag() string {
+	return c.etag
+}
@@ -0,0 +1,107 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestService_GenerateReport_Cached(t *testing.T) {
+	st := &models.Student{ID: 1, Name: "Test Student", Class: "10", Section: "A"}
+	fetches := 0
+	svc := &service{
+		backend: fakeBackendClient(nil, func(context.Context, int, []*http.Cookie) (*models.Student, error) {
+			fetches++
+			cp := *st
+			return &cp, nil
+		}),
+		cache: report.NewCache(time.Minute, 1<<20),
+	}
+
+	render := func(opts ReportOptions) (string, []byte) {
+		t.Helper()
+		rep, err := svc.GenerateReport(context.Background(), 1, opts, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := rep.Output(&buf); err != nil {
+			t.Fatalf("report output error: %v", err)
+		}
+		tagged, ok := rep.(TaggedReport)
+		if !ok {
+			return "", buf.Bytes()
+		}
+		return tagged.ETag(), buf.Bytes()
+	}
+
+	etag, first := render(ReportOptions{})
+	if etag == "" {
+		t.Fatal("expected a tagged report")
+	}
+	again, second := render(ReportOptions{})
+	if again != etag || !bytes.Equal(first, second) {
+		t.Error("expected the cached report to be served again")
+	}
+	if fetches != 2 || svc.cache.Len() != 1 {
+		t.Errorf("expected the student to be fetched on every request and cached once, got %d fetches, %d entries", fetches, svc.cache.Len())
+	}
+
+	if html, _ := render(ReportOptions{Format: report.FormatHTML}); html == etag {
+		t.Error("expected the format to change the tag")
+	}
+	st.Name = "Renamed Student"
+	if renamed, _ := render(ReportOptions{}); renamed == etag {
+		t.Error("expected changed student data to change the tag")
+	}
+	if key, _ := reportKey(st, &report.Template{}, ReportOptions{Encryption: report.EncryptionRequest{Password: "secret"}}, nil); key != "" {
+		t.Error("expected reports with a caller password not to be cached")
+	}
+}
+
+func TestHandler_ConditionalGet(t *testing.T) {
+	st := &models.Student{ID: 7, Name: "Test Student"}
+	svc := &service{
+		backend: fakeBackendClient(nil, func(context.Context, int, []*http.Cookie) (*models.Student, error) {
+			return st, nil
+		}),
+		cache: report.NewCache(time.Minute, 1<<20),
+	}
+	r := chi.NewRouter()
+	r.Mount("/students", NewHandler(svc).Routes())
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := testutil.AuthedRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/students/7", "/students/7/report"} {
+		rec := get(path, "")
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", path, rec.Code, etag)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "private, no-cache" {
+			t.Errorf("%s: unexpected Cache-Control %q", path, cc)
+		}
+		if rec := get(path, `"other", `+etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 for a matching tag, got %d", path, rec.Code)
+		}
+		if rec := get(path, `"other"`); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for a stale tag, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package student
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"goservice/internal/client"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// reportMetrics counts student report requests, published at
+// /api/v1/admin/metrics:
+//
+//   - requests: GenerateReport calls with a known template
+//   - coalesced: requests that shared an identical request already in flight
+//   - cacheHits: renders avoided because the report was cached
+//   - renders: reports actually rendered
+var reportMetrics = expvar.NewMap("studentReports")
+
+// flightGroup lets concurrent identical report requests share one backend
+// fetch and render. The zero value is ready to use.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+	rep  *memoryReport
+	err  error
+}
+
+// do runs fn once for all callers asking for key at the same time. The work
+// is detached from the caller that started it, so one caller giving up does
+// not fail the others; each caller still stops waiting when its own context
+// ends. Callers joining a flight are counted as coalesced when they join.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*memoryReport, error)) (*memoryReport, error) {
+	g.mu.Lock()
+	f, ok := g.calls[key]
+	if ok {
+		reportMetrics.Add("coalesced", 1)
+	} else {
+		if g.calls == nil {
+			g.calls = map[string]*flight{}
+		}
+		f = &flight{done: make(chan struct{})}
+		g.calls[key] = f
+		go func() {
+			defer func() {
+				// The render runs outside the request goroutine, where the
+				// router's recoverer cannot catch a panic.
+				if v := recover(); v != nil {
+					f.err = fmt.Errorf("rendering report: %v", v)
+				}
+				g.mu.Lock()
+				delete(g.calls, key)
+				g.mu.Unlock()
+				close(f.done)
+			}()
+			f.rep, f.err = fn(context.WithoutCancel(ctx))
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.rep, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flightKey identifies a report request. It includes the caller's session
+// so that only callers with the same backend permissions share a result.
+func flightKey(id int, templateName string, opts ReportOptions, authCookies []*http.Cookie) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%t\n%s\n", id, templateName, opts.Format, opts.Encryption.Encrypt, opts.Encryption.Password)
+	for _, c := range authCookies {
+		if c.Name == client.AccesTokenName {
+			io.WriteString(h, c.Value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// memoryReport is a report rendered into memory so that it can be handed
+// to several callers.
+type memoryReport struct {
+	data []byte
+	// etag is empty for reports that are not tagged.
+	etag string
+}
+
+// writer returns the report as a TaggedReport when it has a tag.
+func (r *memoryReport) writer() ReportWriter {
+	if r.etag == "" {
+		return bufferedReport(r.data)
+	}
+	return &cachedReport{data: r.data, etag: r.etag}
+}
+
+// bufferedReport writes a report rendered earlier.
+type bufferedReport []byte
+
+func (b bufferedReport) Output(w io.Writer) error {
+	_, err := w.Write(b)
+	return err
+}
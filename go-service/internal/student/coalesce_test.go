@@ -0,0 +1,137 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goservice/internal/client"
+	"goservice/internal/models"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func coalescedCount() int {
+	v := reportMetrics.Get("coalesced")
+	if v == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(v.String())
+	return n
+}
+
+// waitForCoalesced blocks until the coalesced counter reached n.
+func waitForCoalesced(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if coalescedCount() >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d coalesced callers, got %d", n, coalescedCount())
+}
+
+func TestService_GenerateReport_Coalesced(t *testing.T) {
+	var fetches atomic.Int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	svc := &service{
+		backend: fakeBackendClient(nil, func(context.Context, int, []*http.Cookie) (*models.Student, error) {
+			if fetches.Add(1) == 1 {
+				close(entered)
+			}
+			<-release
+			return &models.Student{ID: 3, Name: "Test Student"}, nil
+		}),
+	}
+	cookies := []*http.Cookie{{Name: client.AccesTokenName, Value: "teacher"}}
+	before := coalescedCount()
+
+	const callers = 4
+	outputs := make([][]byte, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	call := func(i int) {
+		defer wg.Done()
+		rep, err := svc.GenerateReport(context.Background(), 3, ReportOptions{}, cookies)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		var buf bytes.Buffer
+		errs[i] = rep.Output(&buf)
+		outputs[i] = buf.Bytes()
+	}
+	wg.Add(callers)
+	go call(0)
+	<-entered
+	for i := 1; i < callers; i++ {
+		go call(i)
+	}
+	waitForCoalesced(t, before+callers-1)
+	close(release)
+	wg.Wait()
+
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if !bytes.Equal(outputs[i], outputs[0]) || len(outputs[i]) == 0 {
+			t.Errorf("caller %d got a different report", i)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected one backend fetch, got %d", n)
+	}
+	if after := coalescedCount(); after-before != callers-1 {
+		t.Errorf("expected %d coalesced calls, got %d", callers-1, after-before)
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	if flightKey(1, "", ReportOptions{}, []*http.Cookie{{Name: client.AccesTokenName, Value: "a"}}) ==
+		flightKey(1, "", ReportOptions{}, []*http.Cookie{{Name: client.AccesTokenName, Value: "b"}}) {
+		t.Error("expected callers with different sessions not to share a flight")
+	}
+
+	// A waiter that gives up does not cancel the shared work.
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.do(context.Background(), "k", func(ctx context.Context) (*memoryReport, error) {
+			<-release
+			return &memoryReport{data: []byte("ok")}, ctx.Err()
+		})
+		done <- err
+	}()
+	for {
+		g.mu.Lock()
+		started := g.calls["k"] != nil
+		g.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.do(ctx, "k", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled waiter to give up, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("expected the shared work to finish, got %v", err)
+	}
+
+	_, err := g.do(context.Background(), "panic", func(context.Context) (*memoryReport, error) {
+		panic("broken template")
+	})
+	if err == nil {
+		t.Error("expected a panic to be reported as an error")
+	}
+}
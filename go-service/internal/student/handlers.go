@@ -1,12 +1,23 @@
 package student
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"goservice/internal/client"
+	"goservice/internal/mail"
+	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
 	"goservice/internal/response"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -28,10 +39,22 @@ func (h *Handler) Routes() chi.Router {
 
 	r.Get("/{id}", h.GetStudent)
 	r.Get("/{id}/report", h.GenerateReport)
+	r.Post("/{id}/report/send", h.SendReport)
 	return r
 }
 
-func checkRequiredCookie(r *http.Request) ([]*http.Cookie, error) {
+// ClassRoutes serves the bulk report endpoints, mounted under
+// /api/v1/reports/classes.
+func (h *Handler) ClassRoutes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/{class}/sections/{section}", h.GenerateClassReport)
+	r.Get("/{class}/sections/{section}/binder", h.GenerateClassBinder)
+	return r
+}
+
+// CheckRequiredCookie returns the backend session cookies sent with r.
+func CheckRequiredCookie(r *http.Request) ([]*http.Cookie, error) {
 	var cookies []*http.Cookie
 	accessToken, err := r.Cookie(client.AccesTokenName)
 	if err != nil || accessToken.Value == "" {
@@ -50,6 +73,76 @@ func checkRequiredCookie(r *http.Request) ([]*http.Cookie, error) {
 	return cookies, nil
 }
 
+// ParseReportOptions reads the report choices from the query string and the
+// Accept header. Handlers using it answer with Vary: Accept.
+func ParseReportOptions(r *http.Request) (ReportOptions, error) {
+	format, err := report.NegotiateFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		return ReportOptions{}, err
+	}
+	return parseReportOptions(r, format)
+}
+
+// parseReportOptions reads the report choices other than the format.
+func parseReportOptions(r *http.Request, format report.Format) (ReportOptions, error) {
+	var err error
+	opts := ReportOptions{
+		Template: r.URL.Query().Get("template"),
+		Format:   format,
+	}
+	if v := r.URL.Query().Get("encrypt"); v != "" {
+		if opts.Encryption.Encrypt, err = strconv.ParseBool(v); err != nil {
+			return ReportOptions{}, fmt.Errorf("invalid encrypt value %q", v)
+		}
+	}
+	// Passwords are only accepted in a header to keep them out of URLs and
+	// access logs.
+	opts.Encryption.Password = r.Header.Get(pdfsign.PasswordHeader)
+	return opts, nil
+}
+
+// reportErrorStatus maps report generation errors to HTTP status codes.
+func reportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, report.ErrUnknownTemplate), errors.Is(err, report.ErrUnsupportedFormat),
+		errors.Is(err, report.ErrPasswordRequired), errors.Is(err, report.ErrRequestPasswordDenied),
+		errors.Is(err, report.ErrEncryptionNotSupported):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNoStudents):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// notModified sets the ETag of the response and reports whether the
+// client's If-None-Match already names it, in which case a 304 has been
+// written. Tags are compared weakly as RFC 9110 requires for GET.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	// Students and their reports are personal data, so shared caches must not keep them and
+	// clients have to revalidate.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// studentETag hashes the student as it is sent to the client.
+func studentETag(student *models.Student) (string, error) {
+	data, err := json.Marshal(student)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
 func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
@@ -58,7 +151,7 @@ func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cookies, err := checkRequiredCookie(r)
+	cookies, err := CheckRequiredCookie(r)
 	if err != nil {
 		response.Error(w, http.StatusUnauthorized, err)
 		return
@@ -70,6 +163,14 @@ func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag, err := studentETag(student)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	if notModified(w, r, etag) {
+		return
+	}
 	response.JSON(w, http.StatusOK, student)
 }
 
@@ -81,20 +182,191 @@ func (h *Handler) GenerateReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cookies, err := checkRequiredCookie(r)
+	cookies, err := CheckRequiredCookie(r)
 	if err != nil {
 		response.Error(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	pdf, err := h.service.GenerateReport(r.Context(), id, cookies)
+	w.Header().Add("Vary", "Accept")
+	opts, err := ParseReportOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep, err := h.service.GenerateReport(r.Context(), id, opts, cookies)
 	if err != nil {
+		response.Error(w, reportErrorStatus(err), err)
+		return
+	}
+	if tagged, ok := rep.(TaggedReport); ok && notModified(w, r, tagged.ETag()) {
+		return
+	}
+
+	disposition := "attachment"
+	if opts.Format == report.FormatHTML {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fmt.Sprintf("student_%d_report.%s", id, opts.Format.Extension())}))
+	w.WriteHeader(http.StatusOK)
+	if err := rep.Output(w); err != nil {
 		response.Error(w, http.StatusInternalServerError, err)
 		return
 	}
+}
+
+// SendRequest is the body of SendReport.
+type SendRequest struct {
+	// To lists the recipients, usually the guardians. It is required.
+	To []string `json:"to"`
+	// Format of the attachment, used when ?format= is not set.
+	Format string `json:"format,omitempty"`
+}
+
+// SendReport emails the report of a student. The report options are read
+// like those of GenerateReport, except that the format is only taken from
+// ?format= or the body and defaults to PDF: the Accept header is about the
+// JSON answer, not the attachment. The answer is the queued delivery.
+func (h *Handler) SendReport(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cookies, err := CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req SendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	format, name := report.FormatPDF, r.URL.Query().Get("format")
+	if name == "" {
+		name = req.Format
+	}
+	if name != "" {
+		if format, err = report.ParseFormat(name); err != nil {
+			response.Error(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	opts, err := parseReportOptions(r, format)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	delivery, err := h.service.SendReport(r.Context(), id, opts, req.To, cookies)
+	switch {
+	case err == nil:
+	case errors.Is(err, mail.ErrNoRecipient), errors.Is(err, mail.ErrInvalidAddress):
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, ErrMailDisabled), errors.Is(err, mail.ErrShuttingDown):
+		response.Error(w, http.StatusServiceUnavailable, err)
+		return
+	default:
+		response.Error(w, reportErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", mail.DeliveriesPath+delivery.ID)
+	response.JSON(w, http.StatusAccepted, delivery)
+}
+
+func (h *Handler) GenerateClassReport(w http.ResponseWriter, r *http.Request) {
+	class := chi.URLParam(r, "class")
+	section := chi.URLParam(r, "section")
+	if class == "" || section == "" {
+		response.Error(w, http.StatusBadRequest, errors.New("class and section are required"))
+		return
+	}
+
+	cookies, err := CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	opts, err := ParseReportOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	archive, err := h.service.GenerateClassReports(r.Context(), class, section, opts, cookies)
+	if err != nil {
+		response.Error(w, reportErrorStatus(err), err)
+		return
+	}
+
+	// The archive is streamed as reports finish and can outlive the server's
+	// global write timeout, so the deadline is lifted for this response.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("class_%s_section_%s_reports.zip", class, section)}))
+	w.WriteHeader(http.StatusOK)
+	if err := archive.Output(&flushWriter{w: w, rc: rc}); err != nil {
+		// Headers are already sent, the truncated archive signals the failure.
+		return
+	}
+}
+
+// flushWriter pushes every write to the client immediately so that streamed
+// archives reach the caller entry by entry.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	_ = f.rc.Flush()
+	return n, nil
+}
+
+func (h *Handler) GenerateClassBinder(w http.ResponseWriter, r *http.Request) {
+	class := chi.URLParam(r, "class")
+	section := chi.URLParam(r, "section")
+	if class == "" || section == "" {
+		response.Error(w, http.StatusBadRequest, errors.New("class and section are required"))
+		return
+	}
+
+	cookies, err := CheckRequiredCookie(r)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	opts, err := ParseReportOptions(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pdf, err := h.service.GenerateClassBinder(r.Context(), class, section, opts, cookies)
+	if err != nil {
+		response.Error(w, reportErrorStatus(err), err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=student_%d_report.pdf", id))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("class_%s_section_%s_binder.pdf", class, section)}))
 	w.WriteHeader(http.StatusOK)
 	if err := pdf.Output(w); err != nil {
 		response.Error(w, http.StatusInternalServerError, err)
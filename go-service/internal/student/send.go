@@ -0,0 +1,70 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"goservice/internal/mail"
+	"goservice/internal/models"
+	"goservice/internal/report"
+	"net/http"
+)
+
+var (
+	ErrMailDisabled = errors.New("sending reports by email is not configured")
+)
+
+// ReportMail is the data of the mail subject and body templates.
+type ReportMail struct {
+	Student  *models.Student
+	School   string
+	FileName string
+}
+
+// SendReport renders the report of one student and queues it for delivery
+// by email to the given recipients. The address on the student's record is
+// the student's own, so a guardian's address has to be supplied.
+func (s *service) SendReport(ctx context.Context, id int, opts ReportOptions, to []string, authCookies []*http.Cookie) (mail.Delivery, error) {
+	if s.outbox == nil {
+		return mail.Delivery{}, ErrMailDisabled
+	}
+	if len(to) == 0 {
+		return mail.Delivery{}, mail.ErrNoRecipient
+	}
+	tmpl, err := s.templates.Lookup(report.KindStudent, opts.Template)
+	if err != nil {
+		return mail.Delivery{}, err
+	}
+
+	student, err := s.GetStudent(ctx, id, authCookies)
+	if err != nil {
+		return mail.Delivery{}, err
+	}
+	brand := s.branding.Current()
+	format := opts.Format
+	if format == "" {
+		format = report.FormatPDF
+	}
+	data := ReportMail{Student: student, FileName: fmt.Sprintf("student_%d_report.%s", id, format.Extension())}
+	if brand != nil {
+		data.School = brand.SchoolName
+	}
+	// Recipients and templates are checked before the report is rendered
+	// and issued.
+	msg, err := s.outbox.Compose(data, to)
+	if err != nil {
+		return mail.Delivery{}, err
+	}
+
+	rep, err := renderStudent(student, tmpl, opts, brand, s.signer, s.protection, s.issuer)
+	if err != nil {
+		return mail.Delivery{}, err
+	}
+	var buf bytes.Buffer
+	if err := rep.Output(&buf); err != nil {
+		return mail.Delivery{}, err
+	}
+	msg.Attachments = []mail.Attachment{{Name: data.FileName, ContentType: format.ContentType(), Data: buf.Bytes()}}
+	return s.outbox.Send(msg, mail.Reference{Kind: report.KindStudent, StudentID: id})
+}
@@ -0,0 +1,124 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"goservice/internal/mail"
+	"goservice/internal/models"
+	"goservice/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// captureMailer records the messages it is asked to send.
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *captureMailer) Send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestService_SendReport(t *testing.T) {
+	if _, err := (&service{}).SendReport(context.Background(), 1, ReportOptions{}, nil, nil); !errors.Is(err, ErrMailDisabled) {
+		t.Fatalf("expected ErrMailDisabled, got %v", err)
+	}
+
+	mailer := &captureMailer{}
+	outbox, err := mail.NewOutbox(mailer, mail.Options{From: "reports@school.example", Subject: "Report of {{.Student.Name}}", Body: "File: {{.FileName}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Shutdown(context.Background())
+	st := &models.Student{ID: 9, Name: "Asha Rao", Email: "rao.family@example.com"}
+	svc := &service{
+		backend: fakeBackendClient(nil, func(context.Context, int, []*http.Cookie) (*models.Student, error) {
+			return st, nil
+		}),
+		outbox: outbox,
+	}
+
+	d, err := svc.SendReport(context.Background(), 9, ReportOptions{}, []string{"guardian@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.StudentID != 9 || len(d.To) != 1 || d.To[0] != "<guardian@example.com>" || d.Attachment != "student_9_report.pdf" {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, _ := outbox.Get(d.ID); got.State == mail.StateSent {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("report was not sent")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	msg := mailer.sent[0]
+	if msg.Subject != "Report of Asha Rao" || msg.Body != "File: student_9_report.pdf" {
+		t.Errorf("unexpected message %q / %q", msg.Subject, msg.Body)
+	}
+	if att := msg.Attachments[0]; att.ContentType != "application/pdf" || !bytes.HasPrefix(att.Data, []byte("%PDF")) {
+		t.Errorf("expected the PDF report attached, got %s", att.ContentType)
+	}
+
+	// The student's own address is never used in place of a guardian's.
+	if _, err := svc.SendReport(context.Background(), 9, ReportOptions{}, nil, nil); !errors.Is(err, mail.ErrNoRecipient) {
+		t.Errorf("expected ErrNoRecipient, got %v", err)
+	}
+
+	// The handler answers 202 with the delivery, or 400 for bad recipients.
+	r := chi.NewRouter()
+	r.Mount("/students", NewHandler(svc).Routes())
+	post := func(query, accept, body string) *httptest.ResponseRecorder {
+		req := testutil.AuthedRequest(http.MethodPost, "/students/9/report/send"+query, strings.NewReader(body))
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post("", "", `{"to":["teacher@school.example"]}`); rec.Code != http.StatusAccepted || !strings.HasPrefix(rec.Header().Get("Location"), mail.DeliveriesPath) {
+		t.Errorf("expected 202 with a Location, got %d", rec.Code)
+	}
+	if rec := post("", "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without any recipient, got %d", rec.Code)
+	}
+	if rec := post("", "", `{"to":["nobody"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid address, got %d", rec.Code)
+	}
+
+	// The format comes from ?format= or the body; Accept is about the answer.
+	attachment := func(rec *httptest.ResponseRecorder) string {
+		var resp struct {
+			Data mail.Delivery `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp.Data.Attachment
+	}
+	to := `{"to":["teacher@school.example"]}`
+	if got := attachment(post("", "text/csv", to)); got != "student_9_report.pdf" {
+		t.Errorf("expected the PDF whatever Accept says, got %q", got)
+	}
+	if got := attachment(post("?format=csv", "application/json", to)); got != "student_9_report.csv" {
+		t.Errorf("expected the CSV from ?format=, got %q", got)
+	}
+	if got := attachment(post("", "", `{"to":["teacher@school.example"],"format":"csv"}`)); got != "student_9_report.csv" {
+		t.Errorf("expected the CSV from the body, got %q", got)
+	}
+	if rec := post("?format=doc", "", to); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", rec.Code)
+	}
+}
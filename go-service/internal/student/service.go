@@ -1,32 +1,58 @@
 package student
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"goservice/internal/client"
+	"goservice/internal/issuance"
+	"goservice/internal/mail"
 	"goservice/internal/models"
+	"goservice/internal/pdfsign"
+	"goservice/internal/report"
 	"io"
 	"net/http"
-
-	"github.com/jung-kurt/gofpdf"
 )
 
 type Service interface {
 	GetStudent(ctx context.Context, id int, authCookies []*http.Cookie) (*models.Student, error)
-	GenerateReport(ctx context.Context, id int, authCookies []*http.Cookie) (ReportWriter, error)
+	GenerateReport(ctx context.Context, id int, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error)
+	GenerateClassReports(ctx context.Context, class, section string, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error)
+	GenerateClassBinder(ctx context.Context, class, section string, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error)
+	GenerateBatchReports(ctx context.Context, batch Batch, opts ReportOptions, progress BatchProgress, authCookies []*http.Cookie) (ReportWriter, error)
+	SendReport(ctx context.Context, id int, opts ReportOptions, to []string, authCookies []*http.Cookie) (mail.Delivery, error)
 	Login(ctx context.Context, username, password string) ([]*http.Cookie, error)
 }
 
+// ReportOptions are the per-request choices for report generation.
+type ReportOptions struct {
+	// Template selects a report layout by name, empty means the default.
+	Template string
+	// Format selects the output format, empty means PDF.
+	Format report.Format
+	// Encryption asks for a password protected PDF on top of the policy.
+	Encryption report.EncryptionRequest
+}
+
 type service struct {
-	backend client.IBackend
+	backend    client.IBackend
+	templates  *report.Registry
+	branding   *report.BrandStore
+	signer     *pdfsign.Signer
+	protection *report.Protection
+	issuer     *issuance.Issuer
+	cache      *report.Cache
+	outbox     *mail.Outbox
+	inflight   flightGroup
 }
 
 type ReportWriter interface {
 	Output(w io.Writer) error
 }
 
-func NewService(b client.IBackend) Service {
-	return &service{backend: b}
+func NewService(b client.IBackend, templates *report.Registry, branding *report.BrandStore, signer *pdfsign.Signer, protection *report.Protection, issuer *issuance.Issuer, cache *report.Cache, outbox *mail.Outbox) Service {
+	return &service{backend: b, templates: templates, branding: branding, signer: signer, protection: protection, issuer: issuer, cache: cache, outbox: outbox}
 }
 
 func (s *service) Login(ctx context.Context, username, password string) ([]*http.Cookie, error) {
@@ -37,62 +63,226 @@ func (s *service) GetStudent(ctx context.Context, id int, authCookies []*http.Co
 	return s.backend.GetStudentByID(ctx, id, authCookies)
 }
 
-func (s *service) GenerateReport(ctx context.Context, id int, authCookies []*http.Cookie) (ReportWriter, error) {
+// GenerateReport renders the report of one student. Identical requests in
+// flight at the same time share one backend fetch and render. Unless the
+// caller supplied a password the report is a TaggedReport, served from the
+// cache when the same inputs were rendered recently.
+func (s *service) GenerateReport(ctx context.Context, id int, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error) {
+	tmpl, err := s.templates.Lookup(report.KindStudent, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	reportMetrics.Add("requests", 1)
+	rep, err := s.inflight.do(ctx, flightKey(id, tmpl.Name, opts, authCookies), func(ctx context.Context) (*memoryReport, error) {
+		return s.renderReport(ctx, id, tmpl, opts, authCookies)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rep.writer(), nil
+}
+
+// renderReport fetches the student and renders its report into memory,
+// going through the report cache.
+func (s *service) renderReport(ctx context.Context, id int, tmpl *report.Template, opts ReportOptions, authCookies []*http.Cookie) (*memoryReport, error) {
 	student, err := s.GetStudent(ctx, id, authCookies)
 	if err != nil {
 		return nil, err
 	}
 
-	return generatePDF(student), nil
+	brand := s.branding.Current()
+	key, err := reportKey(student, tmpl, opts, brand)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		if data, ok := s.cache.Get(key); ok {
+			reportMetrics.Add("cacheHits", 1)
+			return &memoryReport{data: data, etag: reportETag(key)}, nil
+		}
+	}
+
+	rep, err := renderStudent(student, tmpl, opts, brand, s.signer, s.protection, s.issuer)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := rep.Output(&buf); err != nil {
+		return nil, err
+	}
+	reportMetrics.Add("renders", 1)
+	if key == "" {
+		return &memoryReport{data: buf.Bytes()}, nil
+	}
+	s.cache.Put(key, buf.Bytes(), issuance.SerialOf(rep))
+	return &memoryReport{data: buf.Bytes(), etag: reportETag(key)}, nil
 }
 
-func generatePDF(student *models.Student) *gofpdf.Fpdf {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
+// renderStudent renders one student report, encrypted, signed and issued as
+// configured.
+func renderStudent(student *models.Student, tmpl *report.Template, opts ReportOptions, brand *report.Brand, signer *pdfsign.Signer, protection *report.Protection, issuer *issuance.Issuer) (ReportWriter, error) {
+	enc, err := protection.Resolve(report.KindStudent, student, opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	pdfOpts := report.PDFOptions{Brand: brand, Encryption: enc}
+	if opts.Format == "" || opts.Format == report.FormatPDF {
+		if pdfOpts.Stamp, err = issuer.Stamp(); err != nil {
+			return nil, err
+		}
+	}
+	rep, err := report.Render(opts.Format, tmpl, student, pdfOpts)
+	if err != nil {
+		return nil, err
+	}
+	doc := pdfsign.Document{Kind: report.KindStudent, ID: student.ID, Name: student.Name}
+	if enc != nil {
+		doc.Password = enc.UserPassword
+	}
+	// The record hashes the signed document, so issuing wraps signing.
+	subject := issuance.Subject{Kind: report.KindStudent, StudentID: student.ID, Name: student.Name}
+	return issuer.Wrap(signPDF(signer, rep, opts.Format, doc), pdfOpts.Stamp, subject), nil
+}
 
-	// Set Title
-	pdf.SetFont("Arial", "B", 16)
-	pdf.Cell(40, 10, "Student Report")
-	pdf.Ln(15)
+// signPDF signs PDF reports when a signer is configured. Other formats are
+// returned unchanged.
+func signPDF(signer *pdfsign.Signer, w ReportWriter, format report.Format, doc pdfsign.Document) ReportWriter {
+	if format != "" && format != report.FormatPDF {
+		return w
+	}
+	return signer.Wrap(w, doc)
+}
 
-	// Set Font for content
-	pdf.SetFont("Arial", "", 12)
+// GenerateClassReports lists the students of a class section and returns a
+// writer that streams their reports as a ZIP archive. Per-student failures are
+// recorded in the archive manifest instead of failing the whole archive.
+func (s *service) GenerateClassReports(ctx context.Context, class, section string, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error) {
+	tmpl, err := s.templates.Lookup(report.KindStudent, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	students, err := s.listClass(ctx, class, section, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &classArchive{
+		ctx:        ctx,
+		backend:    s.backend,
+		cookies:    authCookies,
+		class:      class,
+		section:    section,
+		students:   students,
+		template:   tmpl,
+		brand:      s.branding.Current(),
+		signer:     s.signer,
+		protection: s.protection,
+		issuer:     s.issuer,
+		opts:       opts,
+	}, nil
+}
+
+// Batch selects the students of a bulk report: either explicit IDs, or the
+// students of a class, optionally narrowed to one section.
+type Batch struct {
+	StudentIDs []int
+	Class      string
+	Section    string
+}
 
-	// Add Student Data
-	addLine := func(label, value string) {
-		pdf.CellFormat(50, 8, label, "0", 0, "", false, 0, "")
-		pdf.CellFormat(100, 8, value, "0", 0, "", false, 0, "")
-		pdf.Ln(-1)
+// BatchProgress is called once per listed student after it has been handled,
+// with the number of listed students. Students skipped because they are not
+// in the requested class or section have neither a file nor an error.
+type BatchProgress func(entry ArchiveEntry, total int)
+
+// GenerateBatchReports streams the reports of a batch as a ZIP archive like
+// GenerateClassReports, reporting progress as students are handled.
+func (s *service) GenerateBatchReports(ctx context.Context, batch Batch, opts ReportOptions, progress BatchProgress, authCookies []*http.Cookie) (ReportWriter, error) {
+	tmpl, err := s.templates.Lookup(report.KindStudent, opts.Template)
+	if err != nil {
+		return nil, err
 	}
 
-	addLine("ID:", fmt.Sprintf("%d", student.ID))
-	addLine("Name:", student.Name)
-	addLine("Email:", student.Email)
-	addLine("System Access:", boolToString(student.SystemAccess))
-	addLine("Phone:", student.Phone)
-	addLine("Gender:", student.Gender)
-	addLine("DOB:", student.DOB.Format("2006-01-02"))
-	addLine("Class:", student.Class)
-	addLine("Section:", student.Section)
-	addLine("Roll:", fmt.Sprintf("%d", student.Roll))
-	addLine("Father Name:", student.FatherName)
-	addLine("Father Phone:", student.FatherPhone)
-	addLine("Mother Name:", student.MotherName)
-	addLine("Mother Phone:", student.MotherPhone)
-	addLine("Guardian Name:", student.GuardianName)
-	addLine("Guardian Phone:", student.GuardianPhone)
-	addLine("Relation Of Guardian:", student.RelationOfGuardian)
-	addLine("Current Address:", student.CurrentAddress)
-	addLine("Permanent Address:", student.PermanentAddress)
-	addLine("Admission Date:", student.AdmissionDate.Format("2006-01-02"))
-	addLine("Reporter Name:", student.ReporterName)
+	var students []models.Student
+	if len(batch.StudentIDs) > 0 {
+		for _, id := range batch.StudentIDs {
+			students = append(students, models.Student{ID: id})
+		}
+	} else {
+		students, err = s.listClass(ctx, batch.Class, batch.Section, authCookies)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return pdf
+	return &classArchive{
+		ctx:        ctx,
+		backend:    s.backend,
+		cookies:    authCookies,
+		class:      batch.Class,
+		section:    batch.Section,
+		students:   students,
+		template:   tmpl,
+		brand:      s.branding.Current(),
+		signer:     s.signer,
+		protection: s.protection,
+		issuer:     s.issuer,
+		opts:       opts,
+		progress:   progress,
+	}, nil
+}
+
+// GenerateClassBinder renders every student of a class section into one
+// merged PDF with a cover page, table of contents and bookmarks.
+func (s *service) GenerateClassBinder(ctx context.Context, class, section string, opts ReportOptions, authCookies []*http.Cookie) (ReportWriter, error) {
+	if opts.Format != "" && opts.Format != report.FormatPDF {
+		return nil, fmt.Errorf("%w: binders are only available as pdf", report.ErrUnsupportedFormat)
+	}
+	tmpl, err := s.templates.Lookup(report.KindStudent, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.listClass(ctx, class, section, authCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	students, failed := fetchClassStudents(ctx, s.backend, authCookies, class, section, list)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(students) == 0 && len(failed) == 0 {
+		return nil, ErrNoStudents
+	}
+
+	// Password rules for binders are evaluated against the class and section.
+	enc, err := s.protection.Resolve(report.KindClass, struct{ Class, Section string }{class, section}, opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := s.issuer.Stamp()
+	if err != nil {
+		return nil, err
+	}
+	binder := generateBinder(tmpl, report.PDFOptions{Brand: s.branding.Current(), Encryption: enc, Stamp: stamp}, class, section, students, failed)
+	doc := pdfsign.Document{Kind: report.KindClass, Name: fmt.Sprintf("Class %s - Section %s", class, section)}
+	if enc != nil {
+		doc.Password = enc.UserPassword
+	}
+	subject := issuance.Subject{Kind: report.KindClass, Name: doc.Name}
+	return s.issuer.Wrap(signPDF(s.signer, binder, report.FormatPDF, doc), stamp, subject), nil
 }
 
-func boolToString(b bool) string {
-	if b {
-		return "Yes"
+// listClass lists the students of a class section. The backend answers 404
+// when there are none, which is returned as ErrNoStudents.
+func (s *service) listClass(ctx context.Context, class, section string, authCookies []*http.Cookie) ([]models.Student, error) {
+	list, err := s.backend.ListStudents(ctx, models.StudentFilter{ClassName: class, Section: section}, authCookies)
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		return nil, ErrNoStudents
 	}
-	return "No"
+	return list, err
 }
@@ -1,20 +1,34 @@
 package student
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"goservice/internal/client"
+	"goservice/internal/issuance"
 	"goservice/internal/models"
+	"goservice/internal/report"
 	"io"
 	"net/http"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
+
+	"github.com/jung-kurt/gofpdf"
 )
 
 // --- Mock BackendClient ---
 type mockBackendClient struct {
+	client.IBackend
 	loginFn        func(ctx context.Context, username, password string) ([]*http.Cookie, error)
 	getStudentByID func(ctx context.Context, id int, cookies []*http.Cookie) (*models.Student, error)
+	listStudents   func(ctx context.Context, filter models.StudentFilter, cookies []*http.Cookie) ([]models.Student, error)
 }
 
 func (m *mockBackendClient) Login(ctx context.Context, username, password string) ([]*http.Cookie, error) {
@@ -23,6 +37,9 @@ func (m *mockBackendClient) Login(ctx context.Context, username, password string
 func (m *mockBackendClient) GetStudentByID(ctx context.Context, id int, cookies []*http.Cookie) (*models.Student, error) {
 	return m.getStudentByID(ctx, id, cookies)
 }
+func (m *mockBackendClient) ListStudents(ctx context.Context, filter models.StudentFilter, cookies []*http.Cookie) ([]models.Student, error) {
+	return m.listStudents(ctx, filter, cookies)
+}
 
 // --- Fakes for client.BackendClient interface ---
 func fakeBackendClient(loginFn func(context.Context, string, string) ([]*http.Cookie, error),
@@ -135,7 +152,7 @@ func TestService_GenerateReport(t *testing.T) {
 			},
 		),
 	}
-	rep, err := svc.GenerateReport(context.Background(), 1, nil)
+	rep, err := svc.GenerateReport(context.Background(), 1, ReportOptions{}, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -148,3 +165,265 @@ func TestService_GenerateReport(t *testing.T) {
 		t.Errorf("expected some report output, got 0 bytes")
 	}
 }
+
+func TestService_GenerateClassReports(t *testing.T) {
+	students := map[int]*models.Student{
+		1: {ID: 1, Name: "First", Class: "10", Section: "A", Roll: 1},
+		2: {ID: 2, Name: "Second", Class: "10", Section: "A", Roll: 2},
+		3: {ID: 3, Name: "Other", Class: "9", Section: "B", Roll: 3},
+	}
+	mock := fakeBackendClient(
+		nil,
+		func(_ context.Context, id int, _ []*http.Cookie) (*models.Student, error) {
+			if st, ok := students[id]; ok {
+				return st, nil
+			}
+			return nil, errors.New("not found")
+		},
+	)
+	mock.listStudents = func(_ context.Context, filter models.StudentFilter, _ []*http.Cookie) ([]models.Student, error) {
+		if filter.ClassName != "10" || filter.Section != "A" {
+			t.Errorf("unexpected filter: %+v", filter)
+		}
+		return []models.Student{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4, Name: "Missing"}}, nil
+	}
+	svc := &service{backend: mock}
+
+	rep, err := svc.GenerateClassReports(context.Background(), "10", "A", ReportOptions{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := rep.Output(buf); err != nil {
+		t.Fatalf("archive output error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"student_1_roll_1_report.pdf", "student_2_roll_2_report.pdf", ManifestFileName} {
+		if files[name] == nil {
+			t.Errorf("expected %s in archive", name)
+		}
+	}
+	if len(files) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(files))
+	}
+
+	rc, err := files[ManifestFileName].Open()
+	if err != nil {
+		t.Fatalf("open manifest: %v", err)
+	}
+	defer rc.Close()
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if manifest.Succeeded != 2 || manifest.Failed != 1 {
+		t.Errorf("expected 2 succeeded and 1 failed, got %+v", manifest)
+	}
+}
+
+func TestService_GenerateClassReports_ListError(t *testing.T) {
+	mock := fakeBackendClient(nil, nil)
+	mock.listStudents = func(context.Context, models.StudentFilter, []*http.Cookie) ([]models.Student, error) {
+		return nil, errors.New("backend down")
+	}
+	svc := &service{backend: mock}
+
+	if _, err := svc.GenerateClassReports(context.Background(), "10", "A", ReportOptions{}, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestService_GenerateClassReports_NoStudents(t *testing.T) {
+	// The backend answers a class without students with 404.
+	mock := fakeBackendClient(nil, nil)
+	mock.listStudents = func(context.Context, models.StudentFilter, []*http.Cookie) ([]models.Student, error) {
+		return nil, &client.StatusError{Resource: "students", Status: http.StatusNotFound, Body: "Students not found"}
+	}
+	svc := &service{backend: mock}
+
+	_, err := svc.GenerateClassReports(context.Background(), "10", "A", ReportOptions{}, nil)
+	if !errors.Is(err, ErrNoStudents) || reportErrorStatus(err) != http.StatusNotFound {
+		t.Fatalf("expected ErrNoStudents, got %v", err)
+	}
+	if _, err := svc.GenerateClassBinder(context.Background(), "10", "A", ReportOptions{}, nil); !errors.Is(err, ErrNoStudents) {
+		t.Fatalf("expected ErrNoStudents, got %v", err)
+	}
+}
+
+func TestService_GenerateClassBinder(t *testing.T) {
+	mock := fakeBackendClient(
+		nil,
+		func(_ context.Context, id int, _ []*http.Cookie) (*models.Student, error) {
+			if id > 40 {
+				return nil, errors.New("not found")
+			}
+			return &models.Student{ID: id, Name: fmt.Sprintf("Student %d", id), Class: "10", Section: "A", Roll: 41 - id}, nil
+		},
+	)
+	mock.listStudents = func(context.Context, models.StudentFilter, []*http.Cookie) ([]models.Student, error) {
+		list := make([]models.Student, 0, 41)
+		for id := 1; id <= 41; id++ {
+			list = append(list, models.Student{ID: id})
+		}
+		return list, nil
+	}
+	brand, err := report.LoadBrand(report.Branding{SchoolName: "Springfield Public School", ConfidentialityNotice: "Confidential"})
+	if err != nil {
+		t.Fatalf("unexpected branding error: %v", err)
+	}
+	svc := &service{backend: mock, branding: report.NewBrandStore(brand)}
+
+	rep, err := svc.GenerateClassBinder(context.Background(), "10", "A", ReportOptions{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pdf, ok := rep.(*gofpdf.Fpdf)
+	if !ok {
+		t.Fatalf("expected *gofpdf.Fpdf, got %T", rep)
+	}
+	// cover + 2 contents pages + one page per student
+	if got, want := pdf.PageCount(), 1+2+40; got != want {
+		t.Errorf("expected %d pages, got %d", want, got)
+	}
+	buf := new(bytes.Buffer)
+	if err := rep.Output(buf); err != nil {
+		t.Fatalf("binder output error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Outlines")) {
+		t.Error("expected document outline in binder")
+	}
+}
+
+func TestContentsLayout_BelowLetterhead(t *testing.T) {
+	brand, err := report.LoadBrand(report.Branding{SchoolName: "Springfield Public School", Address: "742 Evergreen Terrace"})
+	if err != nil {
+		t.Fatalf("unexpected branding error: %v", err)
+	}
+	tmpl, _ := report.LoadTemplates("", nil)
+	student, _ := tmpl.Lookup(report.KindStudent, "")
+	pdf := student.NewPDF()
+	brand.Apply(pdf, student.FontSet(), nil)
+	pdf.AddPage()
+
+	toc := contentsLayout(pdf)
+	if toc.top <= 30 {
+		t.Errorf("expected contents to start below the letterhead, got y=%.1f", toc.top)
+	}
+	_, pageH := pdf.GetPageSize()
+	_, breakMargin := pdf.GetAutoPageBreak()
+	if bottom := toc.top + tocTitleHeight + float64(toc.perPage)*tocLineHeight; bottom > pageH-breakMargin {
+		t.Errorf("contents run into the footer: %.1f > %.1f", bottom, pageH-breakMargin)
+	}
+}
+
+func TestService_GenerateClassBinder_Empty(t *testing.T) {
+	mock := fakeBackendClient(nil, nil)
+	mock.listStudents = func(context.Context, models.StudentFilter, []*http.Cookie) ([]models.Student, error) {
+		return nil, nil
+	}
+	svc := &service{backend: mock}
+
+	if _, err := svc.GenerateClassBinder(context.Background(), "10", "A", ReportOptions{}, nil); !errors.Is(err, ErrNoStudents) {
+		t.Fatalf("expected ErrNoStudents, got %v", err)
+	}
+}
+
+func TestService_GenerateReport_UnknownTemplate(t *testing.T) {
+	svc := &service{
+		backend: fakeBackendClient(nil, func(context.Context, int, []*http.Cookie) (*models.Student, error) {
+			t.Error("backend should not be called for an unknown template")
+			return nil, nil
+		}),
+	}
+	if _, err := svc.GenerateReport(context.Background(), 1, ReportOptions{Template: "missing"}, nil); !errors.Is(err, report.ErrUnknownTemplate) {
+		t.Fatalf("expected ErrUnknownTemplate, got %v", err)
+	}
+}
+
+func TestService_GenerateReport_Issued(t *testing.T) {
+	store, err := issuance.OpenStore(filepath.Join(t.TempDir(), "issued.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	svc := &service{
+		backend: fakeBackendClient(nil, func(_ context.Context, id int, _ []*http.Cookie) (*models.Student, error) {
+			return &models.Student{ID: id, Name: "Test Student"}, nil
+		}),
+		issuer: issuance.NewIssuer(store, "https://school.example", "School"),
+	}
+
+	rep, err := svc.GenerateReport(context.Background(), 7, ReportOptions{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := rep.Output(buf); err != nil {
+		t.Fatalf("unexpected output error: %v", err)
+	}
+	m := regexp.MustCompile(`/URI \(https://school\.example/api/v1/reports/verify/([0-9A-Z-]+)\)`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("expected a verification link in the report")
+	}
+	rec, err := store.Get(string(m[1]))
+	if err != nil {
+		t.Fatalf("expected the report to be recorded: %v", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if rec.StudentID != 7 || rec.ContentHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	// Only PDFs carry a serial.
+	if _, err := svc.GenerateReport(context.Background(), 7, ReportOptions{Format: report.FormatCSV}, nil); err != nil {
+		t.Errorf("unexpected csv error: %v", err)
+	}
+}
+
+func TestService_GenerateBatchReports(t *testing.T) {
+	mock := fakeBackendClient(nil, func(_ context.Context, id int, _ []*http.Cookie) (*models.Student, error) {
+		if id == 3 {
+			return nil, errors.New("not found")
+		}
+		return &models.Student{ID: id, Name: fmt.Sprintf("Student %d", id), Class: "10", Section: "B"}, nil
+	})
+	svc := &service{backend: mock}
+
+	var done, failed int
+	progress := func(e ArchiveEntry, total int) {
+		if total != 3 {
+			t.Errorf("expected 3 listed students, got %d", total)
+		}
+		done++
+		if e.Error != "" {
+			failed++
+		}
+	}
+	rep, err := svc.GenerateBatchReports(context.Background(), Batch{StudentIDs: []int{1, 2, 3}}, ReportOptions{}, progress, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := rep.Output(buf); err != nil {
+		t.Fatalf("unexpected output error: %v", err)
+	}
+	if done != 3 || failed != 1 {
+		t.Errorf("expected 3 handled and 1 failed, got %d and %d", done, failed)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Explicit lists are not filtered by class, two reports and the manifest.
+	if len(zr.File) != 3 {
+		t.Errorf("expected 3 archive entries, got %d", len(zr.File))
+	}
+}
@@ -0,0 +1,19 @@
+// Package testutil holds helpers shared by the handler tests.
+package testutil
+
+import (
+	"goservice/internal/client"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+// AuthedRequest returns a test request carrying the session cookies every
+// report handler requires.
+func AuthedRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	for _, name := range []string{client.AccesTokenName, client.RefreshTokenName, client.CSFRTokenName} {
+		req.AddCookie(&http.Cookie{Name: name, Value: "token"})
+	}
+	return req
+}
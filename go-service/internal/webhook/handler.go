@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"goservice/internal/response"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	dispatcher *Dispatcher
+}
+
+func NewHandler(d *Dispatcher) *Handler {
+	return &Handler{dispatcher: d}
+}
+
+// Routes serves endpoint registration and the dead-letter list, mounted
+// under /api/v1/admin/webhooks behind the admin token.
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.List)
+	r.Post("/", h.Register)
+	r.Delete("/{id}", h.Remove)
+	r.Get("/dead-letters", h.DeadLetters)
+	r.Post("/dead-letters/{id}/replay", h.Replay)
+	return r
+}
+
+// RegisterRequest is the body of an endpoint registration.
+type RegisterRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// errorStatus maps dispatcher errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidEndpoint):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrEndpointNotFound), errors.Is(err, ErrDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, h.dispatcher.Endpoints())
+}
+
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	ep, err := h.dispatcher.Register(req.URL, req.Events, req.Secret)
+	if err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, ep)
+}
+
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) {
+	if err := h.dispatcher.Remove(chi.URLParam(r, "id")); err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, h.dispatcher.DeadLetters())
+}
+
+func (h *Handler) Replay(w http.ResponseWriter, r *http.Request) {
+	if err := h.dispatcher.Replay(chi.URLParam(r, "id")); err != nil {
+		response.Error(w, errorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
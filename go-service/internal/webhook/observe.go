@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"goservice/internal/report"
+	"goservice/internal/student"
+	"io"
+	"net/http"
+)
+
+// Report scopes of a ReportEvent.
+const (
+	ScopeStudent = "student"
+	ScopeClass   = "class"
+	ScopeBinder  = "binder"
+	ScopeBatch   = "batch"
+)
+
+// ReportEvent is the data of report.generated and report.failed events. It
+// identifies the report without any of its content.
+type ReportEvent struct {
+	Scope     string `json:"scope"`
+	StudentID int    `json:"studentId,omitempty"`
+	Class     string `json:"class,omitempty"`
+	Section   string `json:"section,omitempty"`
+	Students  int    `json:"students,omitempty"`
+	Template  string `json:"template"`
+	Format    string `json:"format"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Observe wraps svc so that every report it renders publishes
+// report.generated once written, or report.failed when it cannot be
+// produced or written.
+func Observe(svc student.Service, d *Dispatcher) student.Service {
+	if d == nil {
+		return svc
+	}
+	return &observed{Service: svc, dispatcher: d}
+}
+
+type observed struct {
+	student.Service
+	dispatcher *Dispatcher
+}
+
+func (o *observed) GenerateReport(ctx context.Context, id int, opts student.ReportOptions, authCookies []*http.Cookie) (student.ReportWriter, error) {
+	rw, err := o.Service.GenerateReport(ctx, id, opts, authCookies)
+	return o.watch(rw, err, ReportEvent{Scope: ScopeStudent, StudentID: id}, opts)
+}
+
+func (o *observed) GenerateClassReports(ctx context.Context, class, section string, opts student.ReportOptions, authCookies []*http.Cookie) (student.ReportWriter, error) {
+	rw, err := o.Service.GenerateClassReports(ctx, class, section, opts, authCookies)
+	return o.watch(rw, err, ReportEvent{Scope: ScopeClass, Class: class, Section: section}, opts)
+}
+
+func (o *observed) GenerateClassBinder(ctx context.Context, class, section string, opts student.ReportOptions, authCookies []*http.Cookie) (student.ReportWriter, error) {
+	rw, err := o.Service.GenerateClassBinder(ctx, class, section, opts, authCookies)
+	return o.watch(rw, err, ReportEvent{Scope: ScopeBinder, Class: class, Section: section}, opts)
+}
+
+func (o *observed) GenerateBatchReports(ctx context.Context, batch student.Batch, opts student.ReportOptions, progress student.BatchProgress, authCookies []*http.Cookie) (student.ReportWriter, error) {
+	rw, err := o.Service.GenerateBatchReports(ctx, batch, opts, progress, authCookies)
+	ev := ReportEvent{Scope: ScopeBatch, Class: batch.Class, Section: batch.Section, Students: len(batch.StudentIDs)}
+	return o.watch(rw, err, ev, opts)
+}
+
+// watch publishes report.failed for err, or wraps rw to publish the outcome
+// of its Output. Writers that carry an ETag keep it.
+func (o *observed) watch(rw student.ReportWriter, err error, ev ReportEvent, opts student.ReportOptions) (student.ReportWriter, error) {
+	ev.Template = opts.Template
+	if ev.Template == "" {
+		ev.Template = report.DefaultTemplate
+	}
+	ev.Format = string(opts.Format)
+	if ev.Format == "" {
+		ev.Format = string(report.FormatPDF)
+	}
+	if err != nil {
+		ev.Error = err.Error()
+		o.dispatcher.Publish(EventReportFailed, ev)
+		return nil, err
+	}
+	w := &watchedReport{ReportWriter: rw, dispatcher: o.dispatcher, event: ev}
+	if tagged, ok := rw.(student.TaggedReport); ok {
+		return &watchedTaggedReport{watchedReport: w, etag: tagged.ETag()}, nil
+	}
+	return w, nil
+}
+
+type watchedReport struct {
+	student.ReportWriter
+	dispatcher *Dispatcher
+	event      ReportEvent
+}
+
+func (r *watchedReport) Output(w io.Writer) error {
+	err := r.ReportWriter.Output(w)
+	ev := r.event
+	if err != nil {
+		ev.Error = err.Error()
+		r.dispatcher.Publish(EventReportFailed, ev)
+		return err
+	}
+	r.dispatcher.Publish(EventReportGenerated, ev)
+	return nil
+}
+
+type watchedTaggedReport struct {
+	*watchedReport
+	etag string
+}
+
+func (r *watchedTaggedReport) ETag() string { return r.etag }
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	endpointsBucket   = []byte("webhook_endpoints")
+	deadLettersBucket = []byte("webhook_dead_letters")
+)
+
+// Store persists endpoints, with their secrets, and dead letters in a bbolt
+// database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens or creates the webhook database at path.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("webhook store: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("webhook store %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{endpointsBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) put(bucket []byte, id string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(id), data)
+	})
+}
+
+func (s *Store) delete(bucket []byte, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(id))
+	})
+}
+
+func (s *Store) endpoints() ([]Endpoint, error) {
+	var out []Endpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(endpointsBucket).ForEach(func(_, v []byte) error {
+			var ep Endpoint
+			if err := json.Unmarshal(v, &ep); err != nil {
+				return err
+			}
+			out = append(out, ep)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (s *Store) deadLetters() ([]DeadLetter, error) {
+	var out []DeadLetter
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(_, v []byte) error {
+			var dl DeadLetter
+			if err := json.Unmarshal(v, &dl); err != nil {
+				return err
+			}
+			out = append(out, dl)
+			return nil
+		})
+	})
+	return out, err
+}
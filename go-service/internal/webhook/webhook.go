@@ -0,0 +1,426 @@
+// Package webhook notifies registered HTTP endpoints of report events.
+// Deliveries are signed with HMAC-SHA256, retried with exponential backoff
+// and moved to a dead-letter list when they keep failing.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	EventReportGenerated = "report.generated"
+	EventReportFailed    = "report.failed"
+	EventJobCompleted    = "job.completed"
+)
+
+// EventTypes lists the events endpoints can subscribe to.
+var EventTypes = []string{EventReportGenerated, EventReportFailed, EventJobCompleted}
+
+// Request headers of a delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the endpoint's secret.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-ID"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	defaultMaxAttempts = 6
+	defaultBackoff     = 10 * time.Second
+	defaultTimeout     = 10 * time.Second
+	// maxConcurrent bounds the deliveries in flight.
+	maxConcurrent = 8
+)
+
+var (
+	ErrInvalidEndpoint    = errors.New("invalid webhook endpoint")
+	ErrEndpointNotFound   = errors.New("webhook endpoint not found")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrShuttingDown       = errors.New("webhooks are shutting down")
+)
+
+// Endpoint is a registered receiver. The secret is only returned when the
+// endpoint is registered.
+type Endpoint struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// DeadLetter is a delivery that failed every attempt. It can be replayed.
+type DeadLetter struct {
+	ID         string    `json:"id"`
+	EndpointID string    `json:"endpointId"`
+	URL        string    `json:"url"`
+	Event      Event     `json:"event"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error"`
+	FailedAt   time.Time `json:"failedAt"`
+}
+
+// Options configure a Dispatcher.
+type Options struct {
+	// Store keeps endpoints and dead letters across restarts.
+	Store *Store
+	// MaxAttempts bounds the tries per delivery and Backoff is the wait
+	// before the first retry, doubled after each one. Non-positive values
+	// use defaults.
+	MaxAttempts int
+	Backoff     time.Duration
+	// Timeout bounds one delivery request.
+	Timeout time.Duration
+}
+
+// Dispatcher delivers events to the endpoints subscribed to them.
+type Dispatcher struct {
+	store       *Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	sem         chan struct{}
+
+	ctx         context.Context
+	stop        context.CancelFunc
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	endpoints   map[string]*Endpoint
+	deadLetters map[string]*DeadLetter
+	closed      bool
+}
+
+// NewDispatcher reloads the endpoints and dead letters of the store.
+func NewDispatcher(opts Options) (*Dispatcher, error) {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store:       opts.Store,
+		client:      &http.Client{Timeout: opts.Timeout},
+		maxAttempts: opts.MaxAttempts,
+		backoff:     opts.Backoff,
+		sem:         make(chan struct{}, maxConcurrent),
+		ctx:         ctx,
+		stop:        stop,
+		endpoints:   map[string]*Endpoint{},
+		deadLetters: map[string]*DeadLetter{},
+	}
+	if d.store != nil {
+		endpoints, err := d.store.endpoints()
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		for i := range endpoints {
+			d.endpoints[endpoints[i].ID] = &endpoints[i]
+		}
+		deadLetters, err := d.store.deadLetters()
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		for i := range deadLetters {
+			d.deadLetters[deadLetters[i].ID] = &deadLetters[i]
+		}
+	}
+	return d, nil
+}
+
+// Register adds an endpoint for the given events, all of them when empty.
+// Without a secret one is generated.
+func (d *Dispatcher) Register(rawURL string, events []string, secret string) (Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Endpoint{}, fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidEndpoint)
+	}
+	if len(events) == 0 {
+		events = EventTypes
+	}
+	for _, ev := range events {
+		if !slices.Contains(EventTypes, ev) {
+			return Endpoint{}, fmt.Errorf("%w: unknown event %q", ErrInvalidEndpoint, ev)
+		}
+	}
+	if secret == "" {
+		if secret, err = newID(); err != nil {
+			return Endpoint{}, err
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return Endpoint{}, err
+	}
+	ep := &Endpoint{ID: id, URL: u.String(), Events: slices.Clone(events), Secret: secret, CreatedAt: time.Now().UTC()}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.store != nil {
+		if err := d.store.put(endpointsBucket, id, ep); err != nil {
+			return Endpoint{}, err
+		}
+	}
+	d.endpoints[id] = ep
+	return *ep, nil
+}
+
+// Endpoints lists the endpoints without their secrets.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Endpoint, 0, len(d.endpoints))
+	for _, ep := range d.endpoints {
+		c := *ep
+		c.Secret = ""
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Remove deletes an endpoint. Its dead letters are kept.
+func (d *Dispatcher) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.endpoints[id]; !ok {
+		return ErrEndpointNotFound
+	}
+	if d.store != nil {
+		if err := d.store.delete(endpointsBucket, id); err != nil {
+			return err
+		}
+	}
+	delete(d.endpoints, id)
+	return nil
+}
+
+// Publish sends an event to every endpoint subscribed to typ. It does not
+// wait for the deliveries. A nil Dispatcher publishes nothing.
+func (d *Dispatcher) Publish(typ string, data any) {
+	if d == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("webhook event %s not sent: %v", typ, err)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		log.Printf("webhook event %s not sent: %v", typ, err)
+		return
+	}
+	ev := Event{ID: id, Type: typ, CreatedAt: time.Now().UTC(), Data: payload}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	for _, ep := range d.endpoints {
+		if slices.Contains(ep.Events, typ) {
+			d.wg.Add(1)
+			go d.deliver(*ep, ev)
+		}
+	}
+}
+
+// DeadLetters lists failed deliveries, newest first.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DeadLetter, 0, len(d.deadLetters))
+	for _, dl := range d.deadLetters {
+		out = append(out, *dl)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FailedAt.After(out[j].FailedAt) })
+	return out
+}
+
+// Replay removes a dead letter and delivers its event again, to the
+// endpoint's current URL and secret. A delivery that fails again becomes a
+// new dead letter.
+func (d *Dispatcher) Replay(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrShuttingDown
+	}
+	dl, ok := d.deadLetters[id]
+	if !ok {
+		return ErrDeadLetterNotFound
+	}
+	ep, ok := d.endpoints[dl.EndpointID]
+	if !ok {
+		return ErrEndpointNotFound
+	}
+	if d.store != nil {
+		if err := d.store.delete(deadLettersBucket, id); err != nil {
+			return err
+		}
+	}
+	delete(d.deadLetters, id)
+	d.wg.Add(1)
+	go d.deliver(*ep, dl.Event)
+	return nil
+}
+
+// Shutdown stops retrying; deliveries still waiting for a retry become dead
+// letters. It waits for requests in flight until ctx ends.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+	d.stop()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) deliver(ep Endpoint, ev Event) {
+	defer d.wg.Done()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("webhook event %s not sent: %v", ev.ID, err)
+		return
+	}
+
+	attempts := 0
+	for {
+		attempts++
+		retry, err := d.attempt(ep, ev, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempts >= d.maxAttempts || d.ctx.Err() != nil {
+			d.deadLetter(ep, ev, attempts, err)
+			return
+		}
+		timer := time.NewTimer(d.backoff << (attempts - 1))
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.deadLetter(ep, ev, attempts, fmt.Errorf("%v; retry interrupted by shutdown", err))
+			return
+		}
+	}
+}
+
+// attempt posts the event once and reports whether a failure is worth
+// retrying: network errors, 408, 429 and 5xx answers are.
+func (d *Dispatcher) attempt(ep Endpoint, ev Event, body []byte) (bool, error) {
+	select {
+	case d.sem <- struct{}{}:
+		defer func() { <-d.sem }()
+	case <-d.ctx.Done():
+		return false, d.ctx.Err()
+	}
+
+	// A request in flight is left to finish within the client timeout
+	// rather than cut short by Shutdown.
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-service-webhooks")
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderID, ev.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(ep.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("endpoint answered %s", resp.Status)
+}
+
+func (d *Dispatcher) deadLetter(ep Endpoint, ev Event, attempts int, err error) {
+	id, idErr := newID()
+	if idErr != nil {
+		log.Printf("webhook event %s to %s lost: %v", ev.ID, ep.URL, err)
+		return
+	}
+	dl := &DeadLetter{ID: id, EndpointID: ep.ID, URL: ep.URL, Event: ev, Attempts: attempts, Error: err.Error(), FailedAt: time.Now().UTC()}
+	log.Printf("webhook event %s to %s failed after %d attempts: %v", ev.ID, ep.URL, attempts, err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.store != nil {
+		if err := d.store.put(deadLettersBucket, id, dl); err != nil {
+			log.Printf("webhook dead letter %s not saved: %v", id, err)
+		}
+	}
+	d.deadLetters[id] = dl
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with
+// secret, as sent in the X-Webhook-Signature header after "sha256=".
+// Receivers should compare it in constant time and reject old timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"goservice/internal/student"
+)
+
+// receiver records the deliveries it accepts and answers with the status
+// returned by status.
+type receiver struct {
+	mu     sync.Mutex
+	calls  int
+	events []Event
+	bodies [][]byte
+	heads  []http.Header
+	status func(call int) int
+	got    chan struct{}
+}
+
+func newReceiver(t *testing.T, status func(call int) int) (*receiver, *httptest.Server) {
+	rc := &receiver{status: status, got: make(chan struct{}, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.calls++
+		code := rc.status(rc.calls)
+		if code < 300 {
+			var ev Event
+			json.Unmarshal(body, &ev)
+			rc.events = append(rc.events, ev)
+			rc.bodies = append(rc.bodies, body)
+			rc.heads = append(rc.heads, r.Header.Clone())
+		}
+		rc.mu.Unlock()
+		w.WriteHeader(code)
+		rc.got <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return rc, srv
+}
+
+func ok(int) int { return http.StatusNoContent }
+
+func (rc *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-rc.got:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a delivery")
+		}
+	}
+}
+
+func newTestDispatcher(t *testing.T, store *Store) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(Options{Store: store, MaxAttempts: 3, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Shutdown(context.Background()) })
+	return d
+}
+
+func TestDispatcher_SignsDeliveries(t *testing.T) {
+	rc, srv := newReceiver(t, ok)
+	d := newTestDispatcher(t, nil)
+	ep, err := d.Register(srv.URL, []string{EventReportGenerated}, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Secret != "s3cret" {
+		t.Errorf("expected the secret to be returned on registration, got %q", ep.Secret)
+	}
+
+	d.Publish(EventJobCompleted, map[string]string{"id": "ignored"})
+	d.Publish(EventReportGenerated, ReportEvent{Scope: ScopeStudent, StudentID: 7})
+	rc.wait(t, 1)
+	d.Shutdown(context.Background())
+
+	if len(rc.events) != 1 || rc.events[0].Type != EventReportGenerated {
+		t.Fatalf("expected only the subscribed event, got %+v", rc.events)
+	}
+	h := rc.heads[0]
+	if h.Get(HeaderEvent) != EventReportGenerated || h.Get(HeaderID) != rc.events[0].ID {
+		t.Errorf("unexpected event headers %v", h)
+	}
+	want := "sha256=" + Sign("s3cret", h.Get(HeaderTimestamp), rc.bodies[0])
+	if h.Get(HeaderSignature) != want {
+		t.Errorf("signature %q, want %q", h.Get(HeaderSignature), want)
+	}
+	var data ReportEvent
+	json.Unmarshal(rc.events[0].Data, &data)
+	if data.StudentID != 7 {
+		t.Errorf("unexpected event data %s", rc.events[0].Data)
+	}
+	for _, listed := range d.Endpoints() {
+		if listed.Secret != "" {
+			t.Error("expected listed endpoints to hide their secret")
+		}
+	}
+}
+
+func TestDispatcher_RetriesTransientFailures(t *testing.T) {
+	rc, srv := newReceiver(t, func(call int) int {
+		if call == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	d := newTestDispatcher(t, nil)
+	if _, err := d.Register(srv.URL, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	d.Publish(EventReportFailed, ReportEvent{Scope: ScopeClass, Class: "10"})
+	rc.wait(t, 2)
+	d.Shutdown(context.Background())
+
+	if len(rc.events) != 1 {
+		t.Errorf("expected one accepted delivery, got %d", len(rc.events))
+	}
+	if n := len(d.DeadLetters()); n != 0 {
+		t.Errorf("expected no dead letters, got %d", n)
+	}
+}
+
+func TestDispatcher_DeadLetterAndReplay(t *testing.T) {
+	failing := true
+	var mu sync.Mutex
+	rc, srv := newReceiver(t, func(int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	store, err := OpenStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	d := newTestDispatcher(t, store)
+	if _, err := d.Register(srv.URL, []string{EventJobCompleted}, ""); err != nil {
+		t.Fatal(err)
+	}
+	d.Publish(EventJobCompleted, map[string]string{"id": "job-1"})
+	rc.wait(t, 3)
+	d.Shutdown(context.Background())
+
+	letters := d.DeadLetters()
+	if len(letters) != 1 || letters[0].Attempts != 3 {
+		t.Fatalf("expected one dead letter after 3 attempts, got %+v", letters)
+	}
+
+	// The dead letter and endpoint survive a restart and can be replayed.
+	d = newTestDispatcher(t, store)
+	if len(d.DeadLetters()) != 1 || len(d.Endpoints()) != 1 {
+		t.Fatal("expected the endpoint and dead letter to be reloaded")
+	}
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if err := d.Replay(letters[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Replay(letters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("expected ErrDeadLetterNotFound on a second replay, got %v", err)
+	}
+	rc.wait(t, 1)
+	d.Shutdown(context.Background())
+
+	if len(rc.events) != 1 || rc.events[0].ID != letters[0].Event.ID {
+		t.Errorf("expected the original event to be replayed, got %+v", rc.events)
+	}
+	if n := len(d.DeadLetters()); n != 0 {
+		t.Errorf("expected the dead letter to be removed, got %d", n)
+	}
+}
+
+func TestDispatcher_PermanentFailureIsNotRetried(t *testing.T) {
+	rc, srv := newReceiver(t, func(int) int { return http.StatusGone })
+	d := newTestDispatcher(t, nil)
+	if _, err := d.Register(srv.URL, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	d.Publish(EventReportGenerated, ReportEvent{Scope: ScopeStudent})
+	rc.wait(t, 1)
+	d.Shutdown(context.Background())
+
+	if rc.calls != 1 {
+		t.Errorf("expected a single attempt, got %d", rc.calls)
+	}
+	if letters := d.DeadLetters(); len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Errorf("expected one dead letter, got %+v", letters)
+	}
+}
+
+func TestDispatcher_Register(t *testing.T) {
+	d := newTestDispatcher(t, nil)
+	for _, tc := range []struct {
+		url    string
+		events []string
+	}{
+		{"ftp://example.com/hook", nil},
+		{"/relative", nil},
+		{"https://example.com/hook", []string{"report.deleted"}},
+	} {
+		if _, err := d.Register(tc.url, tc.events, ""); !errors.Is(err, ErrInvalidEndpoint) {
+			t.Errorf("Register(%q, %v): expected ErrInvalidEndpoint, got %v", tc.url, tc.events, err)
+		}
+	}
+	ep, err := d.Register("https://example.com/hook", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ep.Events) != len(EventTypes) || ep.Secret == "" {
+		t.Errorf("expected all events and a generated secret, got %+v", ep)
+	}
+	if err := d.Remove(ep.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ep.ID); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("expected ErrEndpointNotFound, got %v", err)
+	}
+}
+
+type fakeService struct {
+	student.Service
+	err error
+}
+
+type taggedWriter struct{ err error }
+
+func (w taggedWriter) Output(out io.Writer) error { return w.err }
+func (w taggedWriter) ETag() string               { return `W/"tag"` }
+
+func (f fakeService) GenerateReport(ctx context.Context, id int, opts student.ReportOptions, cookies []*http.Cookie) (student.ReportWriter, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return taggedWriter{}, nil
+}
+
+func TestObserve(t *testing.T) {
+	rc, srv := newReceiver(t, ok)
+	d := newTestDispatcher(t, nil)
+	if _, err := d.Register(srv.URL, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := Observe(fakeService{}, d)
+	rw, err := svc.GenerateReport(context.Background(), 3, student.ReportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagged, ok := rw.(student.TaggedReport); !ok || tagged.ETag() != `W/"tag"` {
+		t.Error("expected the wrapped report to keep its ETag")
+	}
+	if err := rw.Output(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	rc.wait(t, 1)
+
+	svc = Observe(fakeService{err: errors.New("backend down")}, d)
+	if _, err := svc.GenerateReport(context.Background(), 4, student.ReportOptions{}, nil); err == nil {
+		t.Fatal("expected the error to be passed on")
+	}
+	rc.wait(t, 1)
+	d.Shutdown(context.Background())
+
+	types := map[string]ReportEvent{}
+	for _, ev := range rc.events {
+		var data ReportEvent
+		json.Unmarshal(ev.Data, &data)
+		types[ev.Type] = data
+	}
+	if got := types[EventReportGenerated]; got.StudentID != 3 || got.Format != "pdf" || got.Template != "default" {
+		t.Errorf("unexpected report.generated data %+v", got)
+	}
+	if got := types[EventReportFailed]; got.StudentID != 4 || got.Error != "backend down" {
+		t.Errorf("unexpected report.failed data %+v", got)
+	}
+}